@@ -0,0 +1,92 @@
+package billing
+
+import (
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+)
+
+// Calculator prices an invoice from the order lines it bills. Guests is the
+// party size the service charges are checked against; Discounts are the
+// discounts applied to the order.
+type Calculator struct {
+	Tax_rates       []models.TaxRate
+	Service_charges []models.ServiceCharge
+	Discounts       []models.Discount
+	Guests          int
+}
+
+// Calculate fills in the lines, the discounts, the per-rate tax breakdown,
+// the service charges and the totals of the invoice. Discounts come off the
+// line prices before taxes. Service charges are a percentage of what the
+// lines cost the guest, taxes included.
+func (calc Calculator) Calculate(invoice *models.Invoice, orderLines []models.OrderLine) {
+	calc.price(invoice, orderLines, calc.allocateDiscounts(orderLines))
+}
+
+// price is Calculate with the discounts already allocated to the lines, which
+// lets a bill carry its share of discounts allocated over the whole order.
+func (calc Calculator) price(invoice *models.Invoice, orderLines []models.OrderLine, parts lineDiscounts) {
+	invoice.Lines = []models.InvoiceLine{}
+	invoice.Subtotal = money.Zero()
+	invoice.Tax_total = money.Zero()
+	invoice.Discount_total = money.Zero()
+	invoice.Total = money.Zero()
+
+	breakdown := newTaxBreakdown()
+	discounts := newDiscountTotals()
+
+	for _, orderLine := range orderLines {
+		line := models.InvoiceLine{
+			Order_item_id: orderLine.Order_item_id,
+			Food_id:       orderLine.Food_id,
+			Food_name:     orderLine.Food_name,
+			Quantity:      orderLine.Quantity,
+			Variant:       orderLine.Variant,
+			Count:         orderLine.Count,
+			Seat:          orderLine.Seat,
+			Modifiers:     orderLine.Modifiers,
+			Unit_price:    orderLine.Unit_price,
+			Discount:      money.Zero(),
+			Amount:        orderLine.Amount,
+		}
+
+		for _, part := range parts[orderLine.Order_item_id] {
+			line.Discount = line.Discount.Add(part.Amount)
+			discounts.add(part)
+		}
+		line.Amount = line.Amount.Sub(line.Discount)
+
+		applyTaxes(&line, calc.ratesFor(orderLine.Food_id, orderLine.Menu_category))
+		breakdown.add(line)
+
+		invoice.Discount_total = invoice.Discount_total.Add(line.Discount)
+		invoice.Subtotal = invoice.Subtotal.Add(line.Net_amount)
+		invoice.Tax_total = invoice.Tax_total.Add(line.Tax_amount)
+		invoice.Total = invoice.Total.Add(line.Amount)
+		invoice.Lines = append(invoice.Lines, line)
+	}
+
+	invoice.Tax_breakdown = breakdown.summaries()
+	invoice.Discounts = discounts.discounts()
+
+	invoice.Service_charges = []models.InvoiceCharge{}
+	invoice.Service_total = money.Zero()
+	base := invoice.Total
+
+	for _, charge := range calc.Service_charges {
+		if !charge.AppliesTo(calc.Guests) {
+			continue
+		}
+
+		amount := base.Percent(*charge.Rate)
+		invoice.Service_charges = append(invoice.Service_charges, models.InvoiceCharge{
+			Service_charge_id: charge.Service_charge_id,
+			Name:              *charge.Name,
+			Rate:              *charge.Rate,
+			Amount:            amount,
+		})
+		invoice.Service_total = invoice.Service_total.Add(amount)
+	}
+
+	invoice.Total = invoice.Total.Add(invoice.Service_total)
+}
@@ -0,0 +1,201 @@
+package billing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func taxRate(id string, rate float64, inclusive bool) models.TaxRate {
+	return models.TaxRate{
+		Tax_rate_id: id,
+		Name:        ptr(id),
+		Rate:        ptr(rate),
+		Inclusive:   ptr(inclusive),
+	}
+}
+
+func orderLine(orderItemId, foodId, category string, amount int64) models.OrderLine {
+	return models.OrderLine{
+		Order_item_id: orderItemId,
+		Food_id:       foodId,
+		Menu_category: category,
+		Count:         1,
+		Unit_price:    money.New(amount),
+		Amount:        money.New(amount),
+	}
+}
+
+func checkMoney(t *testing.T, what string, got money.Money, want int64) {
+	t.Helper()
+	if got.Minor != want {
+		t.Errorf("%s = %s, want %s", what, got, money.New(want))
+	}
+}
+
+func TestCalculateTaxes(t *testing.T) {
+	food := taxRate("food", 10, false)
+	food.Food_id = ptr("f2")
+	inactive := taxRate("inactive", 5, false)
+	inactive.Category = ptr("MAINS")
+	inactive.Active = ptr(false)
+
+	calc := Calculator{Tax_rates: []models.TaxRate{taxRate("vat", 20, true), food, inactive}}
+
+	var invoice models.Invoice
+	calc.Calculate(&invoice, []models.OrderLine{
+		orderLine("i1", "f1", "MAINS", 1200),
+		orderLine("i2", "f2", "MAINS", 1000),
+	})
+
+	if len(invoice.Lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(invoice.Lines))
+	}
+
+	// the inclusive rate is carved out of the price
+	inclusive := invoice.Lines[0]
+	checkMoney(t, "inclusive net", inclusive.Net_amount, 1000)
+	checkMoney(t, "inclusive tax", inclusive.Tax_amount, 200)
+	checkMoney(t, "inclusive amount", inclusive.Amount, 1200)
+	if len(inclusive.Taxes) != 1 || inclusive.Taxes[0].Tax_rate_id != "vat" {
+		t.Errorf("inclusive line is taxed at %+v, want the default rate only", inclusive.Taxes)
+	}
+
+	// the food's own rate beats the default and is added on top
+	exclusive := invoice.Lines[1]
+	checkMoney(t, "exclusive net", exclusive.Net_amount, 1000)
+	checkMoney(t, "exclusive tax", exclusive.Tax_amount, 100)
+	checkMoney(t, "exclusive amount", exclusive.Amount, 1100)
+	if len(exclusive.Taxes) != 1 || exclusive.Taxes[0].Tax_rate_id != "food" {
+		t.Errorf("exclusive line is taxed at %+v, want the food rate only", exclusive.Taxes)
+	}
+
+	checkMoney(t, "subtotal", invoice.Subtotal, 2000)
+	checkMoney(t, "tax total", invoice.Tax_total, 300)
+	checkMoney(t, "total", invoice.Total, 2300)
+
+	if len(invoice.Tax_breakdown) != 2 {
+		t.Fatalf("got %d tax summaries, want 2", len(invoice.Tax_breakdown))
+	}
+	checkMoney(t, "vat taxable", invoice.Tax_breakdown[0].Taxable_amount, 1000)
+	checkMoney(t, "vat tax", invoice.Tax_breakdown[0].Tax_amount, 200)
+	checkMoney(t, "food taxable", invoice.Tax_breakdown[1].Taxable_amount, 1000)
+	checkMoney(t, "food tax", invoice.Tax_breakdown[1].Tax_amount, 100)
+}
+
+func TestRatesFor(t *testing.T) {
+	byFood := taxRate("food", 10, false)
+	byFood.Food_id = ptr("f1")
+	byCategory := taxRate("category", 7, false)
+	byCategory.Category = ptr("DRINKS")
+	stacked := taxRate("stacked", 3, false)
+	stacked.Category = ptr("DRINKS")
+
+	calc := Calculator{Tax_rates: []models.TaxRate{taxRate("default", 20, true), byFood, byCategory, stacked}}
+
+	tests := []struct {
+		name     string
+		foodId   string
+		category string
+		want     []string
+	}{
+		{"food beats category", "f1", "DRINKS", []string{"food"}},
+		{"category beats default", "f2", "DRINKS", []string{"category", "stacked"}},
+		{"default", "f2", "MAINS", []string{"default"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rates := calc.ratesFor(test.foodId, test.category)
+			if len(rates) != len(test.want) {
+				t.Fatalf("got %d rates, want %v", len(rates), test.want)
+			}
+			for i, rate := range rates {
+				if rate.Tax_rate_id != test.want[i] {
+					t.Errorf("rate %d = %s, want %s", i, rate.Tax_rate_id, test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCalculateServiceCharges(t *testing.T) {
+	calc := Calculator{
+		Tax_rates: []models.TaxRate{taxRate("vat", 15, false)},
+		Service_charges: []models.ServiceCharge{
+			{Service_charge_id: "large", Name: ptr("Large party"), Rate: ptr(12.5), Min_guests: ptr(4)},
+			{Service_charge_id: "huge", Name: ptr("Huge party"), Rate: ptr(5.0), Min_guests: ptr(8)},
+			{Service_charge_id: "off", Name: ptr("Off"), Rate: ptr(10.0), Active: ptr(false)},
+		},
+		Guests: 4,
+	}
+
+	var invoice models.Invoice
+	calc.Calculate(&invoice, []models.OrderLine{orderLine("i1", "f1", "MAINS", 2000)})
+
+	// 12.5% of 23.00, taxes included, rounds up from 2.875
+	if len(invoice.Service_charges) != 1 || invoice.Service_charges[0].Service_charge_id != "large" {
+		t.Fatalf("got service charges %+v, want the large party charge only", invoice.Service_charges)
+	}
+	checkMoney(t, "service charge", invoice.Service_charges[0].Amount, 288)
+	checkMoney(t, "service total", invoice.Service_total, 288)
+	checkMoney(t, "tax total", invoice.Tax_total, 300)
+	checkMoney(t, "total", invoice.Total, 2588)
+}
+
+func TestCalculateDiscounts(t *testing.T) {
+	calc := Calculator{
+		Discounts: []models.Discount{
+			{Discount_id: "item", Order_item_id: "i1", Kind: models.DiscountFixed, Amount: money.New(200)},
+			{Discount_id: "bill", Kind: models.DiscountPercent, Percent: 10},
+			{Discount_id: "removed", Kind: models.DiscountPercent, Percent: 50, Removed_at: ptr(time.Now())},
+		},
+	}
+
+	var invoice models.Invoice
+	calc.Calculate(&invoice, []models.OrderLine{
+		orderLine("i1", "f1", "MAINS", 1000),
+		orderLine("i2", "f2", "MAINS", 2000),
+	})
+
+	// the bill discount is 10% of what is left after the item discount,
+	// 28.00, shared 8:20 between the lines
+	checkMoney(t, "first line discount", invoice.Lines[0].Discount, 280)
+	checkMoney(t, "first line amount", invoice.Lines[0].Amount, 720)
+	checkMoney(t, "second line discount", invoice.Lines[1].Discount, 200)
+	checkMoney(t, "second line amount", invoice.Lines[1].Amount, 1800)
+	checkMoney(t, "discount total", invoice.Discount_total, 480)
+	checkMoney(t, "total", invoice.Total, 2520)
+
+	if len(invoice.Discounts) != 2 {
+		t.Fatalf("got %d discounts, want 2", len(invoice.Discounts))
+	}
+	checkMoney(t, "item discount", invoice.Discounts[0].Amount, 200)
+	checkMoney(t, "bill discount", invoice.Discounts[1].Amount, 280)
+}
+
+func TestCalculateDiscountsNeverGoBelowZero(t *testing.T) {
+	calc := Calculator{
+		Discounts: []models.Discount{
+			{Discount_id: "comp", Order_item_id: "i1", Kind: models.DiscountFixed, Amount: money.New(1500)},
+			{Discount_id: "void", Order_item_id: "i2", Type: models.DiscountVoid},
+		},
+	}
+
+	var invoice models.Invoice
+	calc.Calculate(&invoice, []models.OrderLine{
+		orderLine("i1", "f1", "MAINS", 1000),
+		orderLine("i2", "f2", "MAINS", 2000),
+	})
+
+	checkMoney(t, "comped line", invoice.Lines[0].Amount, 0)
+	checkMoney(t, "voided line", invoice.Lines[1].Amount, 0)
+	checkMoney(t, "discount total", invoice.Discount_total, 3000)
+	checkMoney(t, "total", invoice.Total, 0)
+}
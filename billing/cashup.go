@@ -0,0 +1,99 @@
+package billing
+
+import (
+	"time"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+)
+
+// Till is what went through a drawer, or through every drawer of a business
+// day: the floats, cash movements, payments, refunds and the invoices the
+// payments settled. Counted is the cash counted at closing, if it was.
+type Till struct {
+	Opening_float money.Money
+	Movements     []models.CashMovement
+	Payments      []models.Payment
+	Credit_notes  []models.CreditNote
+	Invoices      []models.Invoice
+	Counted_cash  *money.Money
+}
+
+// Report tallies the till into a cash report of the given kind over
+// [from, to). Only captured payments count. Refunds are counted by the
+// credit notes issued for them, at the time they were given.
+func (till Till) Report(kind string, from, to time.Time) models.CashReport {
+	report := models.CashReport{
+		Kind:           kind,
+		From:           from,
+		To:             to,
+		Opening_float:  till.Opening_float,
+		Cash_sales:     money.Zero(),
+		Cash_tips:      money.Zero(),
+		Cash_refunds:   money.Zero(),
+		Cash_in:        money.Zero(),
+		Cash_out:       money.Zero(),
+		Paid_out:       money.Zero(),
+		Card_sales:     money.Zero(),
+		Card_tips:      money.Zero(),
+		Card_refunds:   money.Zero(),
+		Refund_total:   money.Zero(),
+		Discount_total: money.Zero(),
+		Tip_total:      money.Zero(),
+		Counted_cash:   till.Counted_cash,
+	}
+
+	for _, payment := range till.Payments {
+		if !payment.IsCaptured() {
+			continue
+		}
+		report.Payment_count++
+		report.Tip_total = report.Tip_total.Add(payment.Tip)
+
+		if *payment.Method == models.PaymentCash {
+			report.Cash_sales = report.Cash_sales.Add(payment.Amount)
+			report.Cash_tips = report.Cash_tips.Add(payment.Tip)
+		} else {
+			report.Card_sales = report.Card_sales.Add(payment.Amount)
+			report.Card_tips = report.Card_tips.Add(payment.Tip)
+		}
+	}
+
+	for _, creditNote := range till.Credit_notes {
+		report.Refund_count++
+		report.Refund_total = report.Refund_total.Add(creditNote.Total)
+
+		switch creditNote.Method {
+		case models.PaymentCash:
+			report.Cash_refunds = report.Cash_refunds.Add(creditNote.Total)
+		case models.PaymentCard:
+			report.Card_refunds = report.Card_refunds.Add(creditNote.Total)
+		}
+	}
+
+	for _, movement := range till.Movements {
+		switch movement.Type {
+		case models.CashIn:
+			report.Cash_in = report.Cash_in.Add(movement.Amount)
+		case models.CashOut:
+			report.Cash_out = report.Cash_out.Add(movement.Amount)
+		case models.PaidOut:
+			report.Paid_out = report.Paid_out.Add(movement.Amount)
+		}
+	}
+
+	for _, invoice := range till.Invoices {
+		if !invoice.IsVoided() {
+			report.Discount_total = report.Discount_total.Add(invoice.Discount_total)
+		}
+	}
+
+	report.Expected_cash = money.Sum(report.Opening_float, report.Cash_sales, report.Cash_tips, report.Cash_in).
+		Sub(money.Sum(report.Cash_refunds, report.Cash_out, report.Paid_out))
+	if till.Counted_cash != nil {
+		overShort := till.Counted_cash.Sub(report.Expected_cash)
+		report.Over_short = &overShort
+	}
+
+	return report
+}
@@ -0,0 +1,64 @@
+package billing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+)
+
+func TestTillReport(t *testing.T) {
+	cashSale := payment(models.PaymentCash, models.PaymentCaptured, 5000, 0)
+	cashSale.Tip = money.New(500)
+	cardSale := payment(models.PaymentCard, models.PaymentCaptured, 3000, 0)
+	cardSale.Tip = money.New(300)
+
+	voided := models.Invoice{Payment_status: ptr(models.InvoiceVoided), Discount_total: money.New(900)}
+	counted := money.New(14000)
+	from := time.Date(2026, 10, 17, 6, 0, 0, 0, time.UTC)
+
+	till := Till{
+		Opening_float: money.New(10000),
+		Movements: []models.CashMovement{
+			{Type: models.CashIn, Amount: money.New(2000)},
+			{Type: models.CashOut, Amount: money.New(1500)},
+			{Type: models.PaidOut, Amount: money.New(500), Reason: "milk"},
+		},
+		Payments: []models.Payment{
+			cashSale,
+			cardSale,
+			payment(models.PaymentCash, models.PaymentFailed, 4000, 0),
+		},
+		Credit_notes: []models.CreditNote{{Method: models.PaymentCash, Total: money.New(1000)}},
+		Invoices:     []models.Invoice{{Discount_total: money.New(200)}, voided},
+		Counted_cash: &counted,
+	}
+
+	report := till.Report(models.ReportZ, from, from.Add(24*time.Hour))
+
+	checkMoney(t, "cash sales", report.Cash_sales, 5000)
+	checkMoney(t, "cash tips", report.Cash_tips, 500)
+	checkMoney(t, "card sales", report.Card_sales, 3000)
+	checkMoney(t, "card tips", report.Card_tips, 300)
+	checkMoney(t, "tips", report.Tip_total, 800)
+	checkMoney(t, "cash refunds", report.Cash_refunds, 1000)
+	checkMoney(t, "refunds", report.Refund_total, 1000)
+	checkMoney(t, "discounts", report.Discount_total, 200)
+	if report.Payment_count != 2 || report.Refund_count != 1 {
+		t.Errorf("report counts %d payment(s) and %d refund(s), want 2 and 1", report.Payment_count, report.Refund_count)
+	}
+
+	// 100.00 float + 50.00 sales + 5.00 tips + 20.00 in
+	// - 10.00 refunded - 15.00 out - 5.00 paid out
+	checkMoney(t, "expected cash", report.Expected_cash, 14500)
+	if report.Over_short == nil {
+		t.Fatal("a counted till has no over/short")
+	}
+	checkMoney(t, "over/short", *report.Over_short, -500)
+
+	till.Counted_cash = nil
+	if report := till.Report(models.ReportX, from, from.Add(time.Hour)); report.Over_short != nil {
+		t.Errorf("an uncounted till is %s over/short, want none", report.Over_short)
+	}
+}
@@ -0,0 +1,133 @@
+package billing
+
+import (
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+)
+
+// lineDiscounts maps an order item to the parts of the discounts it bears.
+type lineDiscounts map[string][]models.InvoiceDiscount
+
+// allocateDiscounts works out what every active discount takes off each
+// line, before taxes. Item discounts come first, in the order they were
+// applied, and never take a line below zero. Bill discounts then apply to
+// what is left and are spread over the lines in proportion, so each line
+// is taxed on what the guest actually pays for it.
+func (calc Calculator) allocateDiscounts(orderLines []models.OrderLine) lineDiscounts {
+	parts := lineDiscounts{}
+	remaining := map[string]money.Money{}
+	for _, line := range orderLines {
+		remaining[line.Order_item_id] = line.Amount
+	}
+
+	for _, discount := range calc.Discounts {
+		if !discount.IsActive() || discount.Scope() != models.ScopeItem {
+			continue
+		}
+
+		left, ok := remaining[discount.Order_item_id]
+		if !ok {
+			continue
+		}
+
+		var gross money.Money
+		for _, line := range orderLines {
+			if line.Order_item_id == discount.Order_item_id {
+				gross = line.Amount
+			}
+		}
+
+		amount := capAt(discountAmount(discount, gross), left)
+		remaining[discount.Order_item_id] = left.Sub(amount)
+		parts[discount.Order_item_id] = append(parts[discount.Order_item_id], discountPart(discount, amount))
+	}
+
+	for _, discount := range calc.Discounts {
+		if !discount.IsActive() || discount.Scope() != models.ScopeBill {
+			continue
+		}
+
+		weights := make([]int64, len(orderLines))
+		base := money.Zero()
+		for i, line := range orderLines {
+			weights[i] = remaining[line.Order_item_id].Minor
+			base = base.Add(remaining[line.Order_item_id])
+		}
+		if base.Minor <= 0 {
+			continue
+		}
+
+		amounts := capAt(discountAmount(discount, base), base).Allocate(weights)
+		for i, line := range orderLines {
+			if amounts[i].IsZero() {
+				continue
+			}
+			remaining[line.Order_item_id] = remaining[line.Order_item_id].Sub(amounts[i])
+			parts[line.Order_item_id] = append(parts[line.Order_item_id], discountPart(discount, amounts[i]))
+		}
+	}
+
+	return parts
+}
+
+// discountAmount is what the discount takes off base before any cap.
+func discountAmount(discount models.Discount, base money.Money) money.Money {
+	switch {
+	case discount.Type == models.DiscountVoid:
+		return base
+	case discount.Kind == models.DiscountPercent:
+		return base.Percent(discount.Percent)
+	default:
+		return discount.Amount
+	}
+}
+
+func capAt(amount, limit money.Money) money.Money {
+	if amount.Minor > limit.Minor {
+		return limit
+	}
+	if amount.IsNegative() {
+		return money.Zero()
+	}
+	return amount
+}
+
+func discountPart(discount models.Discount, amount money.Money) models.InvoiceDiscount {
+	return models.InvoiceDiscount{
+		Discount_id:   discount.Discount_id,
+		Type:          discount.Type,
+		Name:          discount.Name,
+		Order_item_id: discount.Order_item_id,
+		Amount:        amount,
+	}
+}
+
+// discountTotals adds up the parts of every discount over the billed lines
+// in the order the discounts were met.
+type discountTotals struct {
+	order []string
+	byId  map[string]*models.InvoiceDiscount
+}
+
+func newDiscountTotals() *discountTotals {
+	return &discountTotals{byId: map[string]*models.InvoiceDiscount{}}
+}
+
+func (t *discountTotals) add(part models.InvoiceDiscount) {
+	total, ok := t.byId[part.Discount_id]
+	if !ok {
+		total = &part
+		t.byId[part.Discount_id] = total
+		t.order = append(t.order, part.Discount_id)
+		return
+	}
+	total.Amount = total.Amount.Add(part.Amount)
+}
+
+func (t *discountTotals) discounts() []models.InvoiceDiscount {
+	discounts := []models.InvoiceDiscount{}
+	for _, id := range t.order {
+		discounts = append(discounts, *t.byId[id])
+	}
+	return discounts
+}
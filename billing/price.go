@@ -0,0 +1,111 @@
+package billing
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+)
+
+var (
+	ErrUnknownVariant   = errors.New("unknown variant")
+	ErrInvalidModifiers = errors.New("invalid modifiers")
+)
+
+// UnitPrice prices food ordered in a size as a variant, "" being the food
+// ordered plainly. A variant priced for the size wins over one priced for
+// every size. Food without variants costs its Price in every size.
+func UnitPrice(food models.Food, variant, size string) (money.Money, error) {
+	var anySize *money.Money
+	named := false
+
+	for _, foodVariant := range food.Variants {
+		if foodVariant.Name != variant {
+			continue
+		}
+		named = true
+
+		if foodVariant.Size == size {
+			return *foodVariant.Price, nil
+		}
+		if foodVariant.Size == "" {
+			anySize = foodVariant.Price
+		}
+	}
+
+	if anySize != nil {
+		return *anySize, nil
+	}
+	if named {
+		return money.Money{}, fmt.Errorf("%w: %s is not offered in size %s", ErrUnknownVariant, variantName(variant), size)
+	}
+	if variant != "" {
+		return money.Money{}, fmt.Errorf("%w: %s is not a variant of this food", ErrUnknownVariant, variant)
+	}
+	return *food.Price, nil
+}
+
+func variantName(variant string) string {
+	if variant == "" {
+		return "the plain food"
+	}
+	return variant
+}
+
+// ChooseModifiers checks the modifiers chosen for food against its groups:
+// each has to exist and be picked once, and each group picked from within
+// its limits, groups that need a pick included. It returns the modifiers
+// priced and with the allergens the food has them with, and what they add
+// up to.
+func ChooseModifiers(food models.Food, chosen []models.OrderModifier) ([]models.OrderModifier, money.Money, error) {
+	priced := []models.OrderModifier{}
+	total := money.Zero()
+	picked := map[string]int{}
+	seen := map[[2]string]bool{}
+
+	for _, choice := range chosen {
+		key := [2]string{choice.Group, choice.Name}
+		if seen[key] {
+			return nil, total, fmt.Errorf("%w: %s is chosen more than once", ErrInvalidModifiers, choice.Name)
+		}
+		seen[key] = true
+
+		modifier, ok := findModifier(food, choice.Group, choice.Name)
+		if !ok {
+			return nil, total, fmt.Errorf("%w: %s is not in the %s group of this food", ErrInvalidModifiers, choice.Name, choice.Group)
+		}
+		picked[choice.Group]++
+
+		choice.Price_delta = modifier.Price_delta
+		choice.Allergens = modifier.Allergens
+		priced = append(priced, choice)
+		total = total.Add(modifier.Price_delta)
+	}
+
+	for _, group := range food.Modifier_groups {
+		count := picked[group.Name]
+		if count < group.Min {
+			return nil, total, fmt.Errorf("%w: choose at least %d from %s", ErrInvalidModifiers, group.Min, group.Name)
+		}
+		if group.Max > 0 && count > group.Max {
+			return nil, total, fmt.Errorf("%w: choose at most %d from %s", ErrInvalidModifiers, group.Max, group.Name)
+		}
+	}
+
+	return priced, total, nil
+}
+
+func findModifier(food models.Food, group, name string) (models.Modifier, bool) {
+	for _, modifierGroup := range food.Modifier_groups {
+		if modifierGroup.Name != group {
+			continue
+		}
+		for _, modifier := range modifierGroup.Modifiers {
+			if modifier.Name == name {
+				return modifier, true
+			}
+		}
+	}
+	return models.Modifier{}, false
+}
@@ -0,0 +1,90 @@
+package billing
+
+import (
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+)
+
+// Settle derives the paid, refunded and credited amounts, the outstanding
+// balance, the payment status and the payment method of the invoice from
+// its payments and credit notes, which are the source of truth.
+//
+// Invoices settled before payments were recorded have neither; they keep
+// their status, PENDING if they were stored without one. Voided invoices
+// stay voided and overdue invoices stay overdue until nothing is left to
+// pay.
+func Settle(invoice *models.Invoice, payments []models.Payment, creditNotes []models.CreditNote) {
+	if invoice.Payment_status == nil {
+		pending := models.InvoicePending
+		invoice.Payment_status = &pending
+	}
+
+	paid := money.Zero()
+	refunded := money.Zero()
+	credited := money.Zero()
+	tips := money.Zero()
+	methods := map[string]bool{}
+	lastFailed := false
+
+	for _, payment := range payments {
+		if payment.IsCaptured() {
+			paid = paid.Add(payment.Amount)
+			refunded = refunded.Add(payment.Refunded)
+			tips = tips.Add(payment.Tip)
+			methods[*payment.Method] = true
+		}
+		lastFailed = payment.Status == models.PaymentFailed
+	}
+
+	for _, creditNote := range creditNotes {
+		credited = credited.Add(creditNote.Total)
+	}
+
+	invoice.Amount_paid = paid
+	invoice.Amount_refunded = refunded
+	invoice.Amount_credited = credited
+	invoice.Tip_total = tips
+
+	if len(payments) == 0 && len(creditNotes) == 0 {
+		invoice.Balance = invoice.Total
+		if *invoice.Payment_status == models.InvoicePaid {
+			invoice.Balance = money.Zero()
+		}
+		return
+	}
+
+	due := invoice.Total.Sub(credited)
+	netPaid := paid.Sub(refunded)
+	invoice.Balance = due.Sub(netPaid)
+
+	var status string
+	switch {
+	case invoice.IsVoided():
+		status = models.InvoiceVoided
+	case due.Minor <= 0 && !credited.IsZero():
+		status = models.InvoiceRefunded
+	case invoice.Balance.Minor <= 0:
+		status = models.InvoicePaid
+	case invoice.IsOverdue():
+		status = models.InvoiceOverdue
+	case netPaid.Minor > 0:
+		status = models.InvoicePartiallyPaid
+	case lastFailed:
+		status = models.InvoiceFailed
+	default:
+		status = models.InvoicePending
+	}
+	invoice.Payment_status = &status
+
+	switch len(methods) {
+	case 0:
+	case 1:
+		for method := range methods {
+			method := method
+			invoice.Payment_method = &method
+		}
+	default:
+		mixed := models.PaymentMixed
+		invoice.Payment_method = &mixed
+	}
+}
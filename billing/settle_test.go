@@ -0,0 +1,164 @@
+package billing
+
+import (
+	"testing"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+)
+
+func payment(method, status string, amount, refunded int64) models.Payment {
+	return models.Payment{
+		Method:   ptr(method),
+		Status:   status,
+		Amount:   money.New(amount),
+		Refunded: money.New(refunded),
+		Tip:      money.Zero(),
+	}
+}
+
+func TestSettle(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      *string
+		payments    []models.Payment
+		creditNotes []models.CreditNote
+		wantStatus  string
+		wantMethod  string
+		wantBalance int64
+	}{
+		{
+			name:        "stored without a status",
+			wantStatus:  models.InvoicePending,
+			wantBalance: 1000,
+		},
+		{
+			name:        "paid before payments were recorded",
+			status:      ptr(models.InvoicePaid),
+			wantStatus:  models.InvoicePaid,
+			wantBalance: 0,
+		},
+		{
+			name:        "partially paid",
+			status:      ptr(models.InvoicePending),
+			payments:    []models.Payment{payment(models.PaymentCash, models.PaymentCaptured, 400, 0)},
+			wantStatus:  models.InvoicePartiallyPaid,
+			wantMethod:  models.PaymentCash,
+			wantBalance: 600,
+		},
+		{
+			name:   "paid with two methods",
+			status: ptr(models.InvoicePending),
+			payments: []models.Payment{
+				payment(models.PaymentCash, models.PaymentCaptured, 400, 0),
+				payment(models.PaymentCard, models.PaymentCaptured, 600, 0),
+			},
+			wantStatus:  models.InvoicePaid,
+			wantMethod:  models.PaymentMixed,
+			wantBalance: 0,
+		},
+		{
+			name:   "failed attempts do not count",
+			status: ptr(models.InvoicePending),
+			payments: []models.Payment{
+				payment(models.PaymentCard, models.PaymentCaptured, 300, 0),
+				payment(models.PaymentCard, models.PaymentFailed, 700, 0),
+			},
+			wantStatus:  models.InvoicePartiallyPaid,
+			wantMethod:  models.PaymentCard,
+			wantBalance: 700,
+		},
+		{
+			name:        "last attempt failed",
+			status:      ptr(models.InvoicePending),
+			payments:    []models.Payment{payment(models.PaymentCard, models.PaymentFailed, 1000, 0)},
+			wantStatus:  models.InvoiceFailed,
+			wantBalance: 1000,
+		},
+		{
+			name:        "refund without a credit note is owed again",
+			status:      ptr(models.InvoicePaid),
+			payments:    []models.Payment{payment(models.PaymentCard, models.PaymentPartiallyRefunded, 1000, 250)},
+			wantStatus:  models.InvoicePartiallyPaid,
+			wantMethod:  models.PaymentCard,
+			wantBalance: 250,
+		},
+		{
+			name:        "refunded and credited in full",
+			status:      ptr(models.InvoicePaid),
+			payments:    []models.Payment{payment(models.PaymentCard, models.PaymentRefunded, 1000, 1000)},
+			creditNotes: []models.CreditNote{{Total: money.New(1000)}},
+			wantStatus:  models.InvoiceRefunded,
+			wantMethod:  models.PaymentCard,
+			wantBalance: 0,
+		},
+		{
+			name:        "refunded and credited in part",
+			status:      ptr(models.InvoicePaid),
+			payments:    []models.Payment{payment(models.PaymentCard, models.PaymentPartiallyRefunded, 1000, 250)},
+			creditNotes: []models.CreditNote{{Total: money.New(250)}},
+			wantStatus:  models.InvoicePaid,
+			wantMethod:  models.PaymentCard,
+			wantBalance: 0,
+		},
+		{
+			name:        "voided stays voided",
+			status:      ptr(models.InvoiceVoided),
+			payments:    []models.Payment{payment(models.PaymentCash, models.PaymentCaptured, 400, 0)},
+			wantStatus:  models.InvoiceVoided,
+			wantMethod:  models.PaymentCash,
+			wantBalance: 600,
+		},
+		{
+			name:        "overdue stays overdue until paid",
+			status:      ptr(models.InvoiceOverdue),
+			payments:    []models.Payment{payment(models.PaymentCash, models.PaymentCaptured, 400, 0)},
+			wantStatus:  models.InvoiceOverdue,
+			wantMethod:  models.PaymentCash,
+			wantBalance: 600,
+		},
+		{
+			name:        "overdue paid in full",
+			status:      ptr(models.InvoiceOverdue),
+			payments:    []models.Payment{payment(models.PaymentCash, models.PaymentCaptured, 1000, 0)},
+			wantStatus:  models.InvoicePaid,
+			wantMethod:  models.PaymentCash,
+			wantBalance: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			invoice := models.Invoice{Payment_status: test.status, Total: money.New(1000)}
+			Settle(&invoice, test.payments, test.creditNotes)
+
+			if invoice.Payment_status == nil || *invoice.Payment_status != test.wantStatus {
+				t.Errorf("status = %v, want %s", invoice.Payment_status, test.wantStatus)
+			}
+			checkMoney(t, "balance", invoice.Balance, test.wantBalance)
+
+			method := ""
+			if invoice.Payment_method != nil {
+				method = *invoice.Payment_method
+			}
+			if method != test.wantMethod {
+				t.Errorf("method = %q, want %q", method, test.wantMethod)
+			}
+		})
+	}
+}
+
+func TestSettleTotals(t *testing.T) {
+	tipped := payment(models.PaymentCard, models.PaymentPartiallyRefunded, 1000, 300)
+	tipped.Tip = money.New(150)
+
+	invoice := models.Invoice{Payment_status: ptr(models.InvoicePending), Total: money.New(1000)}
+	Settle(&invoice, []models.Payment{tipped}, []models.CreditNote{{Total: money.New(100)}})
+
+	checkMoney(t, "paid", invoice.Amount_paid, 1000)
+	checkMoney(t, "refunded", invoice.Amount_refunded, 300)
+	checkMoney(t, "credited", invoice.Amount_credited, 100)
+	checkMoney(t, "tips", invoice.Tip_total, 150)
+	// 9.00 is due after the credit note and 7.00 was kept
+	checkMoney(t, "balance", invoice.Balance, 200)
+}
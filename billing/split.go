@@ -0,0 +1,202 @@
+package billing
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+)
+
+// ErrInvalidSplit is returned when a split request does not add up to the
+// order it splits.
+var ErrInvalidSplit = errors.New("invalid split")
+
+// SplitByItems prices one sub-invoice per bill, where a bill lists the order
+// items it pays for. Every billed order item has to be on exactly one bill.
+func (calc Calculator) SplitByItems(orderLines []models.OrderLine, bills [][]string) ([]models.Invoice, error) {
+	if len(bills) < 2 {
+		return nil, fmt.Errorf("%w: at least two bills are required", ErrInvalidSplit)
+	}
+
+	linesById := map[string]models.OrderLine{}
+	for _, line := range orderLines {
+		linesById[line.Order_item_id] = line
+	}
+
+	// bill discounts are shared over the whole order, not granted per bill
+	parts := calc.allocateDiscounts(orderLines)
+	assigned := map[string]bool{}
+	invoices := []models.Invoice{}
+
+	for i, bill := range bills {
+		if len(bill) == 0 {
+			return nil, fmt.Errorf("%w: bill %d has no items", ErrInvalidSplit, i+1)
+		}
+
+		billLines := []models.OrderLine{}
+		for _, orderItemId := range bill {
+			line, ok := linesById[orderItemId]
+			if !ok {
+				return nil, fmt.Errorf("%w: order item %s is not billed on this order", ErrInvalidSplit, orderItemId)
+			}
+			if assigned[orderItemId] {
+				return nil, fmt.Errorf("%w: order item %s is on more than one bill", ErrInvalidSplit, orderItemId)
+			}
+			assigned[orderItemId] = true
+			billLines = append(billLines, line)
+		}
+
+		var invoice models.Invoice
+		calc.price(&invoice, billLines, parts)
+		invoices = append(invoices, invoice)
+	}
+
+	for _, line := range orderLines {
+		if !assigned[line.Order_item_id] {
+			return nil, fmt.Errorf("%w: order item %s is not on any bill", ErrInvalidSplit, line.Order_item_id)
+		}
+	}
+
+	return invoices, nil
+}
+
+// SplitBySeat prices one sub-invoice per seat, in seat order. Every billed
+// order item has to be taken by a seat.
+func (calc Calculator) SplitBySeat(orderLines []models.OrderLine) ([]models.Invoice, error) {
+	billsBySeat := map[int][]string{}
+	seats := []int{}
+	for _, line := range orderLines {
+		if line.Seat == 0 {
+			return nil, fmt.Errorf("%w: order item %s is not taken by a seat", ErrInvalidSplit, line.Order_item_id)
+		}
+		if _, ok := billsBySeat[line.Seat]; !ok {
+			seats = append(seats, line.Seat)
+		}
+		billsBySeat[line.Seat] = append(billsBySeat[line.Seat], line.Order_item_id)
+	}
+	slices.Sort(seats)
+
+	if len(seats) < 2 {
+		return nil, fmt.Errorf("%w: the order is taken by fewer than two seats", ErrInvalidSplit)
+	}
+
+	bills := make([][]string, len(seats))
+	for i, seat := range seats {
+		bills[i] = billsBySeat[seat]
+	}
+	return calc.SplitByItems(orderLines, bills)
+}
+
+// SplitEvenly shares the priced invoice between guests. Cents that do not
+// divide evenly go to the first bills.
+func SplitEvenly(whole models.Invoice, guests int) ([]models.Invoice, error) {
+	if guests < 2 {
+		return nil, fmt.Errorf("%w: at least two guests are required", ErrInvalidSplit)
+	}
+
+	weights := make([]int64, guests)
+	for i := range weights {
+		weights[i] = 1
+	}
+
+	return splitByShares(whole, whole.Total.Allocate(weights)), nil
+}
+
+// SplitByAmounts splits the priced invoice into the given amounts, which have
+// to add up to its total.
+func SplitByAmounts(whole models.Invoice, amounts []money.Money) ([]models.Invoice, error) {
+	if len(amounts) < 2 {
+		return nil, fmt.Errorf("%w: at least two amounts are required", ErrInvalidSplit)
+	}
+
+	sum := money.Sum(amounts...)
+	for _, amount := range amounts {
+		if amount.IsNegative() || amount.IsZero() {
+			return nil, fmt.Errorf("%w: every amount must be positive", ErrInvalidSplit)
+		}
+	}
+
+	if sum.Minor != whole.Total.Minor {
+		return nil, fmt.Errorf("%w: amounts add up to %s but the order total is %s", ErrInvalidSplit, sum, whole.Total)
+	}
+
+	return splitByShares(whole, amounts), nil
+}
+
+// splitByShares divides the totals, the discounts and the tax breakdown of
+// whole in proportion to shares. Each sub-invoice's tax and service charge
+// are the sums of its breakdowns, so every column still adds up to the whole
+// invoice.
+func splitByShares(whole models.Invoice, shares []money.Money) []models.Invoice {
+	invoices := make([]models.Invoice, len(shares))
+	weights := make([]int64, len(shares))
+
+	for i, share := range shares {
+		weights[i] = share.Minor
+		invoices[i].Lines = []models.InvoiceLine{}
+		invoices[i].Discounts = []models.InvoiceDiscount{}
+		invoices[i].Discount_total = money.Zero()
+		invoices[i].Tax_breakdown = []models.TaxSummary{}
+		invoices[i].Tax_total = money.Zero()
+		invoices[i].Service_charges = []models.InvoiceCharge{}
+		invoices[i].Service_total = money.Zero()
+	}
+
+	for _, charge := range whole.Service_charges {
+		amounts := charge.Amount.Allocate(weights)
+
+		for i := range invoices {
+			part := charge
+			part.Amount = amounts[i]
+			invoices[i].Service_charges = append(invoices[i].Service_charges, part)
+			invoices[i].Service_total = invoices[i].Service_total.Add(amounts[i])
+		}
+	}
+
+	for _, discount := range whole.Discounts {
+		amounts := discount.Amount.Allocate(weights)
+
+		for i := range invoices {
+			part := discount
+			part.Amount = amounts[i]
+			invoices[i].Discounts = append(invoices[i].Discounts, part)
+			invoices[i].Discount_total = invoices[i].Discount_total.Add(amounts[i])
+		}
+	}
+
+	for _, summary := range whole.Tax_breakdown {
+		taxable := summary.Taxable_amount.Allocate(weights)
+		tax := summary.Tax_amount.Allocate(weights)
+
+		for i := range invoices {
+			part := summary
+			part.Taxable_amount = taxable[i]
+			part.Tax_amount = tax[i]
+			invoices[i].Tax_breakdown = append(invoices[i].Tax_breakdown, part)
+			invoices[i].Tax_total = invoices[i].Tax_total.Add(tax[i])
+		}
+	}
+
+	for i, share := range shares {
+		invoices[i].Total = share
+		invoices[i].Subtotal = share.Sub(invoices[i].Tax_total).Sub(invoices[i].Service_total)
+	}
+
+	return invoices
+}
+
+// Portion returns the share of whole worth amount, with its subtotal, tax and
+// tax breakdown in proportion. Credit notes use it to credit part of an
+// invoice.
+func Portion(whole models.Invoice, amount money.Money) (models.Invoice, error) {
+	if amount.IsNegative() || amount.IsZero() || amount.Minor > whole.Total.Minor {
+		return models.Invoice{}, fmt.Errorf("%w: %s is not a portion of %s", ErrInvalidSplit, amount, whole.Total)
+	}
+
+	if amount.Minor == whole.Total.Minor {
+		return splitByShares(whole, []money.Money{amount})[0], nil
+	}
+	return splitByShares(whole, []money.Money{amount, whole.Total.Sub(amount)})[0], nil
+}
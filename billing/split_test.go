@@ -0,0 +1,217 @@
+package billing
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+)
+
+// pricedInvoice is 20.00 of food taxed at an exclusive 10% with a 12.5%
+// service charge and a 5% bill discount.
+func pricedInvoice() models.Invoice {
+	calc := Calculator{
+		Tax_rates:       []models.TaxRate{taxRate("vat", 10, false)},
+		Service_charges: []models.ServiceCharge{{Service_charge_id: "service", Name: ptr("Service"), Rate: ptr(12.5)}},
+		Discounts:       []models.Discount{{Discount_id: "bill", Kind: models.DiscountPercent, Percent: 5}},
+	}
+
+	var invoice models.Invoice
+	calc.Calculate(&invoice, []models.OrderLine{
+		orderLine("i1", "f1", "MAINS", 1250),
+		orderLine("i2", "f2", "MAINS", 750),
+	})
+	return invoice
+}
+
+// checkColumns checks that every column of the parts adds up to the whole.
+func checkColumns(t *testing.T, whole models.Invoice, parts []models.Invoice) {
+	t.Helper()
+
+	var subtotal, tax, service, discount, total money.Money
+	for _, part := range parts {
+		subtotal = subtotal.Add(part.Subtotal)
+		tax = tax.Add(part.Tax_total)
+		service = service.Add(part.Service_total)
+		discount = discount.Add(part.Discount_total)
+		total = total.Add(part.Total)
+
+		if got := part.Subtotal.Add(part.Tax_total).Add(part.Service_total); got.Minor != part.Total.Minor {
+			t.Errorf("part adds up to %s, but its total is %s", got, part.Total)
+		}
+	}
+
+	checkMoney(t, "subtotals", subtotal, whole.Subtotal.Minor)
+	checkMoney(t, "taxes", tax, whole.Tax_total.Minor)
+	checkMoney(t, "service charges", service, whole.Service_total.Minor)
+	checkMoney(t, "discounts", discount, whole.Discount_total.Minor)
+	checkMoney(t, "totals", total, whole.Total.Minor)
+
+	for i, summary := range whole.Tax_breakdown {
+		var taxable, amount money.Money
+		for _, part := range parts {
+			taxable = taxable.Add(part.Tax_breakdown[i].Taxable_amount)
+			amount = amount.Add(part.Tax_breakdown[i].Tax_amount)
+		}
+		checkMoney(t, summary.Name+" taxable", taxable, summary.Taxable_amount.Minor)
+		checkMoney(t, summary.Name+" tax", amount, summary.Tax_amount.Minor)
+	}
+}
+
+func TestSplitEvenly(t *testing.T) {
+	whole := pricedInvoice()
+	// 19.00 after the discount, 1.90 tax, 2.61 service charge
+	checkMoney(t, "whole total", whole.Total, 2351)
+
+	parts, err := SplitEvenly(whole, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 3 {
+		t.Fatalf("got %d parts, want 3", len(parts))
+	}
+
+	for i, want := range []int64{784, 784, 783} {
+		checkMoney(t, "part total", parts[i].Total, want)
+	}
+	checkColumns(t, whole, parts)
+
+	if _, err := SplitEvenly(whole, 1); !errors.Is(err, ErrInvalidSplit) {
+		t.Errorf("splitting for one guest returned %v, want ErrInvalidSplit", err)
+	}
+}
+
+func TestSplitByAmounts(t *testing.T) {
+	whole := pricedInvoice()
+
+	parts, err := SplitByAmounts(whole, []money.Money{money.New(1000), money.New(1351)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMoney(t, "first part", parts[0].Total, 1000)
+	checkMoney(t, "second part", parts[1].Total, 1351)
+	checkColumns(t, whole, parts)
+
+	invalid := map[string][]money.Money{
+		"one amount":       {whole.Total},
+		"short":            {money.New(1000), money.New(1000)},
+		"over":             {money.New(2000), money.New(1000)},
+		"zero amount":      {money.New(0), whole.Total},
+		"negative amount":  {money.New(-100), money.New(2451)},
+		"no amounts given": nil,
+	}
+	for name, amounts := range invalid {
+		if _, err := SplitByAmounts(whole, amounts); !errors.Is(err, ErrInvalidSplit) {
+			t.Errorf("%s: got %v, want ErrInvalidSplit", name, err)
+		}
+	}
+}
+
+func TestSplitByItems(t *testing.T) {
+	calc := Calculator{
+		Tax_rates: []models.TaxRate{taxRate("vat", 10, false)},
+		Discounts: []models.Discount{{Discount_id: "bill", Kind: models.DiscountFixed, Amount: money.New(300)}},
+	}
+	lines := []models.OrderLine{
+		orderLine("i1", "f1", "MAINS", 1000),
+		orderLine("i2", "f2", "MAINS", 2000),
+		orderLine("i3", "f3", "MAINS", 3000),
+	}
+
+	bills, err := calc.SplitByItems(lines, [][]string{{"i1", "i3"}, {"i2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var whole models.Invoice
+	calc.Calculate(&whole, lines)
+	checkColumns(t, whole, bills)
+
+	// the 3.00 bill discount is shared over the whole order, 2.00 to the
+	// first bill and 1.00 to the second, not granted to each bill
+	checkMoney(t, "first bill discount", bills[0].Discount_total, 200)
+	checkMoney(t, "second bill discount", bills[1].Discount_total, 100)
+	checkMoney(t, "first bill total", bills[0].Total, 4180)
+	checkMoney(t, "second bill total", bills[1].Total, 2090)
+
+	invalid := map[string][][]string{
+		"one bill":      {{"i1", "i2", "i3"}},
+		"empty bill":    {{"i1", "i2", "i3"}, {}},
+		"unknown item":  {{"i1", "i2"}, {"i3", "i4"}},
+		"item twice":    {{"i1", "i2"}, {"i2", "i3"}},
+		"item left out": {{"i1"}, {"i2"}},
+	}
+	for name, bills := range invalid {
+		if _, err := calc.SplitByItems(lines, bills); !errors.Is(err, ErrInvalidSplit) {
+			t.Errorf("%s: got %v, want ErrInvalidSplit", name, err)
+		}
+	}
+}
+
+func TestSplitBySeat(t *testing.T) {
+	calc := Calculator{Tax_rates: []models.TaxRate{taxRate("vat", 10, false)}}
+	seated := func(orderItemId string, seat int, amount int64) models.OrderLine {
+		line := orderLine(orderItemId, "f"+orderItemId, "MAINS", amount)
+		line.Seat = seat
+		return line
+	}
+	lines := []models.OrderLine{
+		seated("i1", 3, 1000),
+		seated("i2", 1, 2000),
+		seated("i3", 3, 500),
+	}
+
+	bills, err := calc.SplitBySeat(lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bills) != 2 {
+		t.Fatalf("got %d bills, want one per seat", len(bills))
+	}
+
+	var whole models.Invoice
+	calc.Calculate(&whole, lines)
+	checkColumns(t, whole, bills)
+
+	// bills follow the seat numbers, not the order the items were taken in
+	checkMoney(t, "seat 1 total", bills[0].Total, 2200)
+	checkMoney(t, "seat 3 total", bills[1].Total, 1650)
+
+	invalid := map[string][]models.OrderLine{
+		"one seat": {seated("i1", 2, 1000), seated("i2", 2, 2000)},
+		"no seat":  {seated("i1", 1, 1000), seated("i2", 0, 2000), seated("i3", 2, 500)},
+		"no items": {},
+	}
+	for name, lines := range invalid {
+		if _, err := calc.SplitBySeat(lines); !errors.Is(err, ErrInvalidSplit) {
+			t.Errorf("%s: got %v, want ErrInvalidSplit", name, err)
+		}
+	}
+}
+
+func TestPortion(t *testing.T) {
+	whole := pricedInvoice()
+
+	part, err := Portion(whole, money.New(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMoney(t, "portion total", part.Total, 1000)
+	if got := part.Subtotal.Add(part.Tax_total).Add(part.Service_total); got.Minor != 1000 {
+		t.Errorf("portion adds up to %s, want 10.00", got)
+	}
+
+	all, err := Portion(whole, whole.Total)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMoney(t, "full portion subtotal", all.Subtotal, whole.Subtotal.Minor)
+	checkMoney(t, "full portion tax", all.Tax_total, whole.Tax_total.Minor)
+
+	for _, amount := range []int64{0, -100, whole.Total.Minor + 1} {
+		if _, err := Portion(whole, money.New(amount)); !errors.Is(err, ErrInvalidSplit) {
+			t.Errorf("a portion of %s returned %v, want ErrInvalidSplit", money.New(amount), err)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package billing
+
+import (
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+)
+
+// ratesFor picks the active rates of the most specific level that matches:
+// the food item itself, then its menu category, then the default rates.
+func (calc Calculator) ratesFor(foodId, category string) []models.TaxRate {
+	var byFood, byCategory, defaults []models.TaxRate
+
+	for _, rate := range calc.Tax_rates {
+		if !rate.IsActive() {
+			continue
+		}
+
+		switch {
+		case rate.Food_id != nil && *rate.Food_id != "":
+			if *rate.Food_id == foodId {
+				byFood = append(byFood, rate)
+			}
+		case rate.Category != nil && *rate.Category != "":
+			if *rate.Category == category {
+				byCategory = append(byCategory, rate)
+			}
+		default:
+			defaults = append(defaults, rate)
+		}
+	}
+
+	if len(byFood) > 0 {
+		return byFood
+	}
+	if len(byCategory) > 0 {
+		return byCategory
+	}
+	return defaults
+}
+
+// applyTaxes splits the line amount into net and tax. Inclusive rates are
+// carved out of the price; exclusive rates are charged on the net amount and
+// added to the line. Each tax is rounded to the minor unit and the net
+// absorbs the rounding, so net plus taxes always equals what the guest pays.
+func applyTaxes(line *models.InvoiceLine, rates []models.TaxRate) {
+	gross := line.Amount
+
+	var inclusiveRate float64
+	for _, rate := range rates {
+		if *rate.Inclusive {
+			inclusiveRate += *rate.Rate
+		}
+	}
+
+	net := gross.Div(1 + inclusiveRate/100)
+
+	line.Taxes = []models.LineTax{}
+	inclusiveTax := money.Zero()
+	exclusiveTax := money.Zero()
+
+	for _, rate := range rates {
+		if !*rate.Inclusive {
+			continue
+		}
+		amount := net.Percent(*rate.Rate)
+		inclusiveTax = inclusiveTax.Add(amount)
+		line.Taxes = append(line.Taxes, lineTax(rate, amount))
+	}
+
+	net = gross.Sub(inclusiveTax)
+
+	for _, rate := range rates {
+		if *rate.Inclusive {
+			continue
+		}
+		amount := net.Percent(*rate.Rate)
+		exclusiveTax = exclusiveTax.Add(amount)
+		line.Taxes = append(line.Taxes, lineTax(rate, amount))
+	}
+
+	line.Net_amount = net
+	line.Tax_amount = inclusiveTax.Add(exclusiveTax)
+	line.Amount = gross.Add(exclusiveTax)
+}
+
+func lineTax(rate models.TaxRate, amount money.Money) models.LineTax {
+	return models.LineTax{
+		Tax_rate_id: rate.Tax_rate_id,
+		Name:        *rate.Name,
+		Rate:        *rate.Rate,
+		Inclusive:   *rate.Inclusive,
+		Amount:      amount,
+	}
+}
+
+// taxBreakdown totals every rate over the invoice in the order the rates
+// were first met.
+type taxBreakdown struct {
+	order []string
+	byId  map[string]*models.TaxSummary
+}
+
+func newTaxBreakdown() *taxBreakdown {
+	return &taxBreakdown{byId: map[string]*models.TaxSummary{}}
+}
+
+func (b *taxBreakdown) add(line models.InvoiceLine) {
+	for _, tax := range line.Taxes {
+		summary, ok := b.byId[tax.Tax_rate_id]
+		if !ok {
+			summary = &models.TaxSummary{
+				Tax_rate_id:    tax.Tax_rate_id,
+				Name:           tax.Name,
+				Rate:           tax.Rate,
+				Inclusive:      tax.Inclusive,
+				Taxable_amount: money.Zero(),
+				Tax_amount:     money.Zero(),
+			}
+			b.byId[tax.Tax_rate_id] = summary
+			b.order = append(b.order, tax.Tax_rate_id)
+		}
+
+		summary.Taxable_amount = summary.Taxable_amount.Add(line.Net_amount)
+		summary.Tax_amount = summary.Tax_amount.Add(tax.Amount)
+	}
+}
+
+func (b *taxBreakdown) summaries() []models.TaxSummary {
+	summaries := []models.TaxSummary{}
+	for _, id := range b.order {
+		summaries = append(summaries, *b.byId[id])
+	}
+	return summaries
+}
@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/billing"
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+	"github.com/tokha04/go-restautant-management/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var businessDayStore repository.BusinessDayStore
+
+// restaurantTimezone is where business days, report dates and menu hours
+// are reckoned in.
+var restaurantTimezone = time.Local
+
+// UseTimezone sets the restaurant's time zone; the server's own is used
+// otherwise.
+func UseTimezone(loc *time.Location) {
+	restaurantTimezone = loc
+}
+
+// businessDate names the business day t falls on, in the restaurant's local
+// time.
+func businessDate(t time.Time) string {
+	return t.In(restaurantTimezone).Format("2006-01-02")
+}
+
+// businessDayRange parses a YYYY-MM-DD business date into the [from, to)
+// range it covers.
+func businessDayRange(date string) (time.Time, time.Time, error) {
+	from, err := time.ParseInLocation("2006-01-02", date, restaurantTimezone)
+	if err != nil {
+		return from, from, fmt.Errorf("business date must be a YYYY-MM-DD date")
+	}
+	return from, from.AddDate(0, 0, 1), nil
+}
+
+// checkDayOpen refuses changes that fall on a closed business day: editing
+// an invoice billed on it, or recording anything while it is today.
+func checkDayOpen(c context.Context, t time.Time) (int, gin.H) {
+	date := businessDate(t)
+
+	_, err := businessDayStore.Get(c, date)
+	if err == nil {
+		return http.StatusConflict, gin.H{"error": "business day " + date + " is closed"}
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return http.StatusInternalServerError, gin.H{"error": "error occured while looking up the business day"}
+	}
+	return 0, nil
+}
+
+// GetBusinessDays lists the closed business days, latest first.
+func GetBusinessDays() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		allDays, err := businessDayStore.List(c)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing business days"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, allDays)
+	}
+}
+
+// GetBusinessDay returns a closed day with its Z report, or an X report of
+// the day so far while it is open.
+func GetBusinessDay() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		date := ctx.Param("business_date")
+
+		from, to, err := businessDayRange(date)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		day, err := businessDayStore.Get(c, date)
+		if err == nil {
+			ctx.JSON(http.StatusOK, day)
+			return
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while looking up the business day"})
+			return
+		}
+
+		report, _, err := dayReport(c, models.ReportX, from, to)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while preparing the report"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, models.BusinessDay{Business_date: date, Report: report})
+	}
+}
+
+// CloseBusinessDay takes the Z report of a day and locks it. Every drawer
+// open during the day has to be counted and closed first.
+func CloseBusinessDay() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		date := ctx.Param("business_date")
+
+		from, to, err := businessDayRange(date)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if from.After(time.Now()) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "business day has not started yet"})
+			return
+		}
+
+		report, openDrawers, err := dayReport(c, models.ReportZ, from, to)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while preparing the report"})
+			return
+		}
+		if len(openDrawers) > 0 {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "close every drawer before closing the day", "drawers": openDrawers})
+			return
+		}
+
+		Closed_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		day := models.BusinessDay{
+			ID:            primitive.NewObjectID(),
+			Business_date: date,
+			Closed_by:     ctx.GetString("uid"),
+			Closed_at:     &Closed_at,
+			Report:        report,
+		}
+
+		if err := businessDayStore.Close(c, day); err != nil {
+			if errors.Is(err, repository.ErrDuplicate) {
+				ctx.JSON(http.StatusConflict, gin.H{"error": "business day " + date + " is already closed"})
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "business day was not closed"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, day)
+	}
+}
+
+// dayReport tallies everything billed, paid and refunded in [from, to) and
+// the drawers used then. The day's expected cash includes cash taken outside
+// any drawer, so it shows up as short when counted. It also returns the ids
+// of the drawers still open.
+func dayReport(c context.Context, kind string, from, to time.Time) (models.CashReport, []string, error) {
+	var report models.CashReport
+
+	drawers, err := drawerStore.List(c, repository.DrawerFilter{From: from, To: to})
+	if err != nil {
+		return report, nil, err
+	}
+	allPayments, err := paymentStore.ListBetween(c, from, to)
+	if err != nil {
+		return report, nil, err
+	}
+	creditNotes, err := creditNoteStore.ListBetween(c, from, to)
+	if err != nil {
+		return report, nil, err
+	}
+	invoices, err := invoiceStore.ListBetween(c, from, to)
+	if err != nil {
+		return report, nil, err
+	}
+
+	till := billing.Till{Opening_float: money.Zero(), Payments: allPayments, Credit_notes: creditNotes, Invoices: invoices}
+	counted := money.Zero()
+	openDrawers := []string{}
+	tallies := []models.DrawerTally{}
+
+	for _, drawer := range drawers {
+		for _, movement := range drawer.Movements {
+			if !movement.Created_at.Before(from) && movement.Created_at.Before(to) {
+				till.Movements = append(till.Movements, movement)
+			}
+		}
+		if drawer.IsOpen() {
+			openDrawers = append(openDrawers, drawer.Drawer_id)
+		}
+
+		// a drawer left open overnight belongs to the day it was opened on,
+		// so its float and count go there only
+		if drawer.Opened_at.Before(from) {
+			continue
+		}
+		till.Opening_float = till.Opening_float.Add(drawer.Opening_float)
+		if drawer.Counted_cash != nil {
+			counted = counted.Add(*drawer.Counted_cash)
+		}
+
+		drawerReport := drawer.Report
+		if drawerReport == nil {
+			xReport, err := tallyDrawer(c, drawer, models.ReportX, time.Now())
+			if err != nil {
+				return report, nil, err
+			}
+			drawerReport = &xReport
+		}
+		tallies = append(tallies, models.DrawerTally{
+			Drawer_id:     drawer.Drawer_id,
+			Register:      drawer.Register,
+			Expected_cash: drawerReport.Expected_cash,
+			Counted_cash:  drawerReport.Counted_cash,
+			Over_short:    drawerReport.Over_short,
+		})
+	}
+	if len(openDrawers) == 0 {
+		till.Counted_cash = &counted
+	}
+
+	report = till.Report(kind, from, to)
+	sort.Slice(tallies, func(i, j int) bool { return tallies[i].Register < tallies[j].Register })
+	report.Drawers = tallies
+	report.Generated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	return report, openDrawers, nil
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/repository"
+)
+
+var creditNoteStore repository.CreditNoteStore
+
+func GetInvoiceCreditNotes() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		invoiceId := ctx.Param("invoice_id")
+
+		if _, err := invoiceStore.Get(c, invoiceId); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "invoice was not found"})
+			return
+		}
+
+		allCreditNotes, err := creditNoteStore.ListByInvoice(c, invoiceId)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing credit notes"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, allCreditNotes)
+	}
+}
+
+func GetCreditNote() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		creditNoteId := ctx.Param("credit_note_id")
+
+		creditNote, err := creditNoteStore.Get(c, creditNoteId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "error occured while fetching the credit note"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, creditNote)
+	}
+}
@@ -0,0 +1,440 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+	"github.com/tokha04/go-restautant-management/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var discountStore repository.DiscountStore
+
+// approvalThreshold is the value above which comps and voids need a manager.
+var approvalThreshold = money.New(2000)
+
+// UseApprovalThreshold sets the value above which comps and voids need a
+// manager's approval.
+func UseApprovalThreshold(threshold money.Money) {
+	approvalThreshold = threshold
+}
+
+// DiscountPack applies a discount to an order. A promotion is given by its
+// coupon Code or its Promotion_id; comps and voids carry a Reason, and need
+// a manager's Approval when they are worth more than the threshold, unless
+// a manager applies them.
+type DiscountPack struct {
+	Type          string       `json:"type" validate:"omitempty,eq=PROMOTION|eq=COMP|eq=VOID"`
+	Code          string       `json:"code"`
+	Promotion_id  string       `json:"promotion_id"`
+	Order_item_id string       `json:"order_item_id"`
+	Kind          string       `json:"kind" validate:"omitempty,eq=PERCENT|eq=FIXED"`
+	Percent       float64      `json:"percent" validate:"omitempty,gt=0,max=100"`
+	Amount        *money.Money `json:"amount" validate:"omitempty,gt=0"`
+	Reason        string       `json:"reason" validate:"max=250"`
+	Approval      *Approval    `json:"approval"`
+}
+
+// Approval is a manager's credentials, given on the spot to approve a comp or
+// a void.
+type Approval struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type RemoveDiscountPack struct {
+	Reason string `json:"reason" validate:"required,max=250"`
+}
+
+func GetOrderDiscounts() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		orderId := ctx.Param("order_id")
+
+		if _, err := orderStore.Get(c, orderId); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "order was not found"})
+			return
+		}
+
+		allDiscounts, err := discountStore.ListByOrder(c, orderId)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing discounts"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, allDiscounts)
+	}
+}
+
+// GetDiscounts is the discount audit trail: every discount applied in the
+// range, removed ones included, optionally of one type.
+func GetDiscounts() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, err := reportRange(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		allDiscounts, err := discountStore.ListBetween(c, from, to)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing discounts"})
+			return
+		}
+
+		discountType := strings.ToUpper(ctx.Query("type"))
+		discounts := []models.Discount{}
+		for _, discount := range allDiscounts {
+			if discountType == "" || discount.Type == discountType {
+				discounts = append(discounts, discount)
+			}
+		}
+
+		ctx.JSON(http.StatusOK, discounts)
+	}
+}
+
+func ApplyDiscount() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		orderId := ctx.Param("order_id")
+		var discountPack DiscountPack
+
+		if err := ctx.BindJSON(&discountPack); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := validate.Struct(discountPack)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if discountPack.Type == "" {
+			discountPack.Type = models.DiscountPromotion
+		}
+
+		if status, body := checkDiscountable(c, orderId); status != 0 {
+			ctx.JSON(status, body)
+			return
+		}
+
+		summary, err := orderItemStore.ItemsByOrder(c, orderId)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the order items"})
+			return
+		}
+
+		var line *models.OrderLine
+		if discountPack.Order_item_id != "" {
+			for i := range summary.Order_items {
+				if summary.Order_items[i].Order_item_id == discountPack.Order_item_id {
+					line = &summary.Order_items[i]
+				}
+			}
+			if line == nil {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "order item is not billed on this order"})
+				return
+			}
+		}
+
+		calc, err := newCalculator(c, summary)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while pricing the order"})
+			return
+		}
+
+		discount := models.Discount{
+			Order_id:      orderId,
+			Order_item_id: discountPack.Order_item_id,
+			Type:          discountPack.Type,
+			Reason:        discountPack.Reason,
+			Amount:        money.Zero(),
+			Created_by:    ctx.GetString("uid"),
+		}
+
+		var promotion models.Promotion
+		if discount.Type == models.DiscountPromotion {
+			promotion, err = findPromotion(c, discountPack)
+			if err != nil {
+				ctx.JSON(storeErrorStatus(err), gin.H{"error": "promotion was not found"})
+				return
+			}
+			if status, body := checkRedeemable(promotion, line, calc.Discounts); status != 0 {
+				ctx.JSON(status, body)
+				return
+			}
+
+			discount.Promotion_id = promotion.Promotion_id
+			discount.Name = *promotion.Name
+			discount.Kind = *promotion.Kind
+			if promotion.Code != nil {
+				discount.Code = *promotion.Code
+			}
+			if promotion.Percent != nil {
+				discount.Percent = *promotion.Percent
+			}
+			if promotion.Amount != nil {
+				discount.Amount = *promotion.Amount
+			}
+		} else {
+			if status, body := checkComp(discountPack, line, calc.Discounts); status != 0 {
+				ctx.JSON(status, body)
+				return
+			}
+
+			discount.Name = "Comp"
+			discount.Kind = discountPack.Kind
+			if discount.Type == models.DiscountVoid {
+				discount.Name = "Void"
+				discount.Kind = models.DiscountPercent
+				discount.Percent = 100
+			} else if discountPack.Kind == "" {
+				// a comp without an amount gives the item or bill away
+				discount.Kind = models.DiscountPercent
+				discount.Percent = 100
+			} else if discountPack.Kind == models.DiscountPercent {
+				discount.Percent = discountPack.Percent
+			} else {
+				discount.Amount = *discountPack.Amount
+			}
+		}
+
+		discount.ID = primitive.NewObjectID()
+		discount.Discount_id = discount.ID.Hex()
+		discount.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		// the value is what the discount takes off the order as it stands
+		var priced models.Invoice
+		calc.Discounts = append(calc.Discounts, discount)
+		calc.Calculate(&priced, summary.Order_items)
+		discount.Value = money.Zero()
+		for _, applied := range priced.Discounts {
+			if applied.Discount_id == discount.Discount_id {
+				discount.Value = applied.Amount
+			}
+		}
+		if discount.Value.IsZero() {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "discount would not take anything off the order"})
+			return
+		}
+
+		if discount.Type != models.DiscountPromotion && discount.Value.Minor > approvalThreshold.Minor {
+			approver, status, body := approveDiscount(c, ctx, discountPack.Approval)
+			if status != 0 {
+				body["value"] = discount.Value
+				body["threshold"] = approvalThreshold
+				ctx.JSON(status, body)
+				return
+			}
+			discount.Approved_by = approver
+		}
+
+		if discount.Promotion_id != "" {
+			if err := promotionStore.Redeem(c, discount.Promotion_id); err != nil {
+				if errors.Is(err, repository.ErrUsageLimit) {
+					ctx.JSON(http.StatusConflict, gin.H{"error": "coupon has reached its usage limit"})
+					return
+				}
+				ctx.JSON(storeErrorStatus(err), gin.H{"error": "coupon could not be redeemed"})
+				return
+			}
+		}
+
+		if err := discountStore.Create(c, discount); err != nil {
+			if discount.Promotion_id != "" {
+				if err := promotionStore.Release(c, discount.Promotion_id); err != nil {
+					log.Printf("could not release a use of promotion %s: %v", discount.Promotion_id, err)
+				}
+			}
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "discount was not created"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, discount)
+	}
+}
+
+// RemoveDiscount takes a discount off its order. The discount is kept, marked
+// as removed, and a coupon use is given back.
+func RemoveDiscount() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		discountId := ctx.Param("discount_id")
+		var removeDiscountPack RemoveDiscountPack
+
+		if err := ctx.BindJSON(&removeDiscountPack); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := validate.Struct(removeDiscountPack)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		discount, err := discountStore.Get(c, discountId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "discount was not found"})
+			return
+		}
+
+		if !discount.IsActive() {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "discount has already been removed", "removed_at": discount.Removed_at})
+			return
+		}
+
+		if status, body := checkDiscountable(c, discount.Order_id); status != 0 {
+			ctx.JSON(status, body)
+			return
+		}
+
+		removed, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		discount.Removed_at = &removed
+		discount.Removed_by = ctx.GetString("uid")
+		discount.Removed_reason = removeDiscountPack.Reason
+
+		if err := discountStore.Update(c, discount); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "discount update failed"})
+			return
+		}
+
+		if discount.Promotion_id != "" {
+			if err := promotionStore.Release(c, discount.Promotion_id); err != nil {
+				log.Printf("could not release a use of promotion %s: %v", discount.Promotion_id, err)
+			}
+		}
+
+		ctx.JSON(http.StatusOK, discount)
+	}
+}
+
+// checkDiscountable allows discounts on orders that are still running and
+// not invoiced yet; invoices keep the totals they were issued with.
+func checkDiscountable(c context.Context, orderId string) (int, gin.H) {
+	return checkOrderChangeable(c, orderId, "discounts")
+}
+
+func findPromotion(c context.Context, discountPack DiscountPack) (models.Promotion, error) {
+	if discountPack.Code != "" {
+		return promotionStore.GetByCode(c, strings.ToUpper(discountPack.Code))
+	}
+	if discountPack.Promotion_id != "" {
+		return promotionStore.Get(c, discountPack.Promotion_id)
+	}
+	return models.Promotion{}, repository.ErrNotFound
+}
+
+// checkRedeemable checks the promotion can be used now, on line for item
+// promotions, and was not used on the order yet. The usage limit is checked
+// when the promotion is redeemed.
+func checkRedeemable(promotion models.Promotion, line *models.OrderLine, discounts []models.Discount) (int, gin.H) {
+	if !promotion.ValidAt(time.Now()) {
+		return http.StatusConflict, gin.H{"error": "promotion is not valid at this time", "valid_from": promotion.Valid_from, "valid_to": promotion.Valid_to}
+	}
+
+	if *promotion.Scope == models.ScopeItem {
+		if line == nil {
+			return http.StatusBadRequest, gin.H{"error": "order_item_id is required for an item promotion"}
+		}
+		if promotion.Food_id != nil && *promotion.Food_id != "" && *promotion.Food_id != line.Food_id {
+			return http.StatusConflict, gin.H{"error": "promotion does not apply to this item"}
+		}
+	} else if line != nil {
+		return http.StatusBadRequest, gin.H{"error": "a bill promotion cannot be applied to an item"}
+	}
+
+	for _, discount := range discounts {
+		if discount.IsActive() && discount.Promotion_id == promotion.Promotion_id {
+			return http.StatusConflict, gin.H{"error": "promotion is already applied to this order", "discount_id": discount.Discount_id}
+		}
+	}
+
+	return 0, nil
+}
+
+// checkComp checks a comp or void request is complete. Voids take a single
+// item off the bill, once.
+func checkComp(discountPack DiscountPack, line *models.OrderLine, discounts []models.Discount) (int, gin.H) {
+	if strings.TrimSpace(discountPack.Reason) == "" {
+		return http.StatusBadRequest, gin.H{"error": "a reason is required for comps and voids"}
+	}
+
+	if discountPack.Type == models.DiscountVoid {
+		if line == nil {
+			return http.StatusBadRequest, gin.H{"error": "order_item_id is required to void an item"}
+		}
+		for _, discount := range discounts {
+			if discount.IsActive() && discount.Type == models.DiscountVoid && discount.Order_item_id == line.Order_item_id {
+				return http.StatusConflict, gin.H{"error": "item has already been voided", "discount_id": discount.Discount_id}
+			}
+		}
+		return 0, nil
+	}
+
+	switch discountPack.Kind {
+	case models.DiscountPercent:
+		if discountPack.Percent == 0 {
+			return http.StatusBadRequest, gin.H{"error": "percent is required for a percent comp"}
+		}
+	case models.DiscountFixed:
+		if discountPack.Amount == nil {
+			return http.StatusBadRequest, gin.H{"error": "amount is required for a fixed comp"}
+		}
+	}
+
+	return 0, nil
+}
+
+// approveDiscount returns who approves a comp or void: the caller when they
+// are a manager, otherwise the manager whose credentials came with it.
+func approveDiscount(c context.Context, ctx *gin.Context, approval *Approval) (string, int, gin.H) {
+	if isManager(ctx) {
+		return ctx.GetString("uid"), 0, nil
+	}
+
+	if approval == nil {
+		return "", http.StatusForbidden, gin.H{"error": "a manager has to approve this discount", "approval_required": true}
+	}
+
+	validationErr := validate.Struct(approval)
+	if validationErr != nil {
+		return "", http.StatusBadRequest, gin.H{"error": validationErr.Error()}
+	}
+
+	manager, err := userStore.GetByEmail(c, approval.Email)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", http.StatusForbidden, gin.H{"error": "approval credentials are incorrect", "approval_required": true}
+		}
+		return "", http.StatusInternalServerError, gin.H{"error": "error occured while checking the approval"}
+	}
+
+	if manager.Password == nil {
+		return "", http.StatusForbidden, gin.H{"error": "approval credentials are incorrect", "approval_required": true}
+	}
+	if valid, _ := VerifyPassword(approval.Password, *manager.Password); !valid {
+		return "", http.StatusForbidden, gin.H{"error": "approval credentials are incorrect", "approval_required": true}
+	}
+
+	if role := userRole(manager); role != models.RoleAdmin && role != models.RoleManager {
+		return "", http.StatusForbidden, gin.H{"error": "only a manager can approve this discount", "approval_required": true}
+	}
+
+	return manager.User_id, 0, nil
+}
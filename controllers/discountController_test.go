@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// orderWithItems is an open order of one item per price.
+func orderWithItems(t *testing.T, prices ...int64) (models.Order, []models.OrderItem) {
+	t.Helper()
+
+	order := storedOrder(t, models.OrderOpen)
+	orderItems := []models.OrderItem{}
+	for _, price := range prices {
+		orderItem := models.OrderItem{ID: primitive.NewObjectID(), Order_id: order.Order_id, Food_id: ptr("food"), Unit_price: ptr(money.New(price))}
+		orderItem.Order_item_id = orderItem.ID.Hex()
+		orderItems = append(orderItems, orderItem)
+	}
+	if err := orderItemStore.CreateMany(context.Background(), orderItems); err != nil {
+		t.Fatal(err)
+	}
+	return order, orderItems
+}
+
+func discountRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(middleware.Authentication())
+	router.POST("/orders/:order_id/discounts", ApplyDiscount())
+	router.POST("/discounts/:discount_id/remove", RemoveDiscount())
+	return router
+}
+
+func TestApplyCoupon(t *testing.T) {
+	useMemoryStores(t)
+	router := discountRouter()
+	_, waiter := signedIn(t, models.RoleWaiter)
+
+	promotion := models.Promotion{
+		ID:       primitive.NewObjectID(),
+		Name:     ptr("Ten off"),
+		Code:     ptr("SAVE10"),
+		Kind:     ptr(models.DiscountPercent),
+		Scope:    ptr(models.ScopeBill),
+		Percent:  ptr(10.0),
+		Max_uses: ptr(1),
+	}
+	promotion.Promotion_id = promotion.ID.Hex()
+	if err := promotionStore.Create(context.Background(), promotion); err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := orderWithItems(t, 1500, 2500)
+	second, _ := orderWithItems(t, 1000)
+	coupon := gin.H{"code": "save10"}
+
+	var discount models.Discount
+	if code := performAs(t, router, waiter.Token, http.MethodPost, "/orders/"+first.Order_id+"/discounts", coupon, &discount); code != http.StatusOK {
+		t.Fatalf("redeeming the coupon returned %d, want 200", code)
+	}
+	if discount.Value.Minor != 400 {
+		t.Errorf("the coupon takes %s off, want 4.00", discount.Value)
+	}
+
+	if code := performAs(t, router, waiter.Token, http.MethodPost, "/orders/"+first.Order_id+"/discounts", coupon, nil); code != http.StatusConflict {
+		t.Errorf("redeeming the coupon twice on an order returned %d, want 409", code)
+	}
+	if code := performAs(t, router, waiter.Token, http.MethodPost, "/orders/"+second.Order_id+"/discounts", coupon, nil); code != http.StatusConflict {
+		t.Errorf("redeeming a used up coupon returned %d, want 409", code)
+	}
+
+	// removing the discount gives the use back
+	if code := performAs(t, router, waiter.Token, http.MethodPost, "/discounts/"+discount.Discount_id+"/remove", gin.H{"reason": "wrong order"}, nil); code != http.StatusOK {
+		t.Fatalf("removing the discount returned %d, want 200", code)
+	}
+	if code := performAs(t, router, waiter.Token, http.MethodPost, "/orders/"+second.Order_id+"/discounts", coupon, nil); code != http.StatusOK {
+		t.Errorf("redeeming a released coupon returned %d, want 200", code)
+	}
+
+	if code := performAs(t, router, waiter.Token, http.MethodPost, "/orders/"+first.Order_id+"/discounts", gin.H{"code": "NOPE"}, nil); code != http.StatusNotFound {
+		t.Errorf("an unknown coupon returned %d, want 404", code)
+	}
+}
+
+func TestApplyCompNeedsApproval(t *testing.T) {
+	useMemoryStores(t)
+	UseApprovalThreshold(money.New(2000))
+	router := discountRouter()
+	_, waiter := signedIn(t, models.RoleWaiter)
+	_, manager := signedIn(t, models.RoleManager)
+
+	approver := models.User{ID: primitive.NewObjectID(), Role: ptr(models.RoleManager), Password: ptr(HashPassword("approve-me"))}
+	approver.User_id = approver.ID.Hex()
+	approver.Email = ptr(approver.User_id + "@example.com")
+	if err := userStore.Create(context.Background(), approver); err != nil {
+		t.Fatal(err)
+	}
+
+	order, orderItems := orderWithItems(t, 1000, 3000)
+	path := "/orders/" + order.Order_id + "/discounts"
+	small := gin.H{"type": models.DiscountComp, "order_item_id": orderItems[0].Order_item_id, "reason": "cold"}
+	large := gin.H{"type": models.DiscountComp, "order_item_id": orderItems[1].Order_item_id, "reason": "burnt"}
+
+	if code := performAs(t, router, waiter.Token, http.MethodPost, path, gin.H{"type": models.DiscountComp, "order_item_id": orderItems[0].Order_item_id}, nil); code != http.StatusBadRequest {
+		t.Errorf("a comp without a reason returned %d, want 400", code)
+	}
+
+	var discount models.Discount
+	if code := performAs(t, router, waiter.Token, http.MethodPost, path, small, &discount); code != http.StatusOK {
+		t.Fatalf("a comp under the threshold returned %d, want 200", code)
+	}
+	if discount.Value.Minor != 1000 || discount.Approved_by != "" {
+		t.Errorf("comp is worth %s approved by %q, want 10.00 without approval", discount.Value, discount.Approved_by)
+	}
+
+	if code := performAs(t, router, waiter.Token, http.MethodPost, path, large, nil); code != http.StatusForbidden {
+		t.Errorf("a comp over the threshold returned %d, want 403", code)
+	}
+	large["approval"] = gin.H{"email": *approver.Email, "password": "wrong"}
+	if code := performAs(t, router, waiter.Token, http.MethodPost, path, large, nil); code != http.StatusForbidden {
+		t.Errorf("a comp approved with a wrong password returned %d, want 403", code)
+	}
+	large["approval"] = gin.H{"email": *approver.Email, "password": "approve-me"}
+	if code := performAs(t, router, waiter.Token, http.MethodPost, path, large, &discount); code != http.StatusOK {
+		t.Fatalf("an approved comp returned %d, want 200", code)
+	}
+	if discount.Approved_by != approver.User_id {
+		t.Errorf("comp was approved by %q, want the manager who approved it", discount.Approved_by)
+	}
+
+	// managers approve their own comps
+	other, otherItems := orderWithItems(t, 3000)
+	void := gin.H{"type": models.DiscountVoid, "order_item_id": otherItems[0].Order_item_id, "reason": "sent back"}
+	if code := performAs(t, router, manager.Token, http.MethodPost, "/orders/"+other.Order_id+"/discounts", void, &discount); code != http.StatusOK {
+		t.Fatalf("a manager's void returned %d, want 200", code)
+	}
+	if code := performAs(t, router, manager.Token, http.MethodPost, "/orders/"+other.Order_id+"/discounts", void, nil); code != http.StatusConflict {
+		t.Errorf("voiding an item twice returned %d, want 409", code)
+	}
+}
@@ -0,0 +1,336 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/billing"
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+	"github.com/tokha04/go-restautant-management/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var drawerStore repository.DrawerStore
+
+// CloseDrawerPack closes a drawer with the cash counted in it.
+type CloseDrawerPack struct {
+	Counted_cash *money.Money `json:"counted_cash" validate:"required"`
+}
+
+func GetDrawers() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, err := reportRange(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		allDrawers, err := drawerStore.List(c, repository.DrawerFilter{Register: ctx.Query("register"), From: from, To: to})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing drawers"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, allDrawers)
+	}
+}
+
+func GetDrawer() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		drawerId := ctx.Param("drawer_id")
+
+		drawer, err := drawerStore.Get(c, drawerId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "drawer was not found"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, drawer)
+	}
+}
+
+// OpenDrawer opens a register's drawer with its float for the authenticated
+// user. A register has at most one open drawer, and so does a user.
+func OpenDrawer() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		uid := ctx.GetString("uid")
+		var drawer models.Drawer
+
+		if err := ctx.BindJSON(&drawer); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := validate.Struct(drawer)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if drawer.Opening_float.IsNegative() {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "opening_float cannot be negative"})
+			return
+		}
+		if drawer.Opening_float.Currency == "" {
+			drawer.Opening_float = money.Zero()
+		}
+
+		if status, body := checkDayOpen(c, time.Now()); body != nil {
+			ctx.JSON(status, body)
+			return
+		}
+
+		open, err := drawerStore.GetOpenByRegister(c, drawer.Register)
+		if err == nil {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "register already has an open drawer", "drawer_id": open.Drawer_id})
+			return
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while looking up the drawer"})
+			return
+		}
+
+		open, err = drawerStore.GetOpenByUser(c, uid)
+		if err == nil {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "you already have an open drawer", "drawer_id": open.Drawer_id, "register": open.Register})
+			return
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while looking up the drawer"})
+			return
+		}
+
+		drawer.ID = primitive.NewObjectID()
+		drawer.Drawer_id = drawer.ID.Hex()
+		drawer.Movements = []models.CashMovement{}
+		drawer.Opened_by = uid
+		drawer.Opened_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		drawer.Closed_by = ""
+		drawer.Closed_at = nil
+		drawer.Counted_cash = nil
+		drawer.Report = nil
+
+		if err := drawerStore.Create(c, drawer); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "drawer was not opened"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, drawer)
+	}
+}
+
+// AddCashMovement records cash put into or taken out of an open drawer
+// other than through a payment.
+func AddCashMovement() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		drawerId := ctx.Param("drawer_id")
+		var movement models.CashMovement
+
+		if err := ctx.BindJSON(&movement); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := validate.Struct(movement)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if movement.Amount.IsNegative() || movement.Amount.IsZero() {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "amount must be positive"})
+			return
+		}
+
+		if status, body := checkDayOpen(c, time.Now()); body != nil {
+			ctx.JSON(status, body)
+			return
+		}
+
+		movement.Movement_id = primitive.NewObjectID().Hex()
+		movement.Created_by = ctx.GetString("uid")
+		movement.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		drawer, err := drawerStore.AddMovement(c, drawerId, movement)
+		if err != nil {
+			if errors.Is(err, repository.ErrConflict) {
+				ctx.JSON(http.StatusConflict, gin.H{"error": "drawer is closed", "closed_at": drawer.Closed_at})
+				return
+			}
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "cash movement was not recorded"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, drawer)
+	}
+}
+
+// GetDrawerReport returns the X report of an open drawer, or the Z report
+// taken when it was closed.
+func GetDrawerReport() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		drawerId := ctx.Param("drawer_id")
+
+		drawer, err := drawerStore.Get(c, drawerId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "drawer was not found"})
+			return
+		}
+
+		if drawer.Report != nil {
+			ctx.JSON(http.StatusOK, drawer.Report)
+			return
+		}
+
+		report, err := tallyDrawer(c, drawer, models.ReportX, time.Now())
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while preparing the report"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, report)
+	}
+}
+
+// CloseDrawer closes a drawer with the cash counted in it and keeps its Z
+// report. Managers may close anyone's drawer.
+func CloseDrawer() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		drawerId := ctx.Param("drawer_id")
+		var closeDrawerPack CloseDrawerPack
+
+		if err := ctx.BindJSON(&closeDrawerPack); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := validate.Struct(closeDrawerPack)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if closeDrawerPack.Counted_cash.IsNegative() {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "counted_cash cannot be negative"})
+			return
+		}
+
+		drawer, err := drawerStore.Get(c, drawerId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "drawer was not found"})
+			return
+		}
+
+		if drawer.Opened_by != ctx.GetString("uid") && !isManager(ctx) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "only managers can close another user's drawer"})
+			return
+		}
+
+		if !drawer.IsOpen() {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "drawer is already closed", "closed_at": drawer.Closed_at})
+			return
+		}
+
+		Closed_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		drawer.Closed_by = ctx.GetString("uid")
+		drawer.Closed_at = &Closed_at
+		drawer.Counted_cash = closeDrawerPack.Counted_cash
+
+		report, err := tallyDrawer(c, drawer, models.ReportZ, Closed_at)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while preparing the report"})
+			return
+		}
+		drawer.Report = &report
+
+		if err := drawerStore.Close(c, drawer); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "drawer was not closed"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, drawer)
+	}
+}
+
+// tallyDrawer reports on what went through the drawer up to now. Discounts
+// are those on the invoices it took payments for.
+func tallyDrawer(c context.Context, drawer models.Drawer, kind string, now time.Time) (models.CashReport, error) {
+	allPayments, err := paymentStore.ListByDrawer(c, drawer.Drawer_id)
+	if err != nil {
+		return models.CashReport{}, err
+	}
+	creditNotes, err := creditNoteStore.ListByDrawer(c, drawer.Drawer_id)
+	if err != nil {
+		return models.CashReport{}, err
+	}
+
+	invoices := []models.Invoice{}
+	seen := map[string]bool{}
+	for _, payment := range allPayments {
+		if !payment.IsCaptured() || seen[payment.Invoice_id] {
+			continue
+		}
+		seen[payment.Invoice_id] = true
+
+		invoice, err := invoiceStore.Get(c, payment.Invoice_id)
+		if err != nil {
+			return models.CashReport{}, err
+		}
+		invoices = append(invoices, invoice)
+	}
+
+	till := billing.Till{
+		Opening_float: drawer.Opening_float,
+		Movements:     drawer.Movements,
+		Payments:      allPayments,
+		Credit_notes:  creditNotes,
+		Invoices:      invoices,
+		Counted_cash:  drawer.Counted_cash,
+	}
+	report := till.Report(kind, drawer.Opened_at, now)
+	report.Generated_at, _ = time.Parse(time.RFC3339, now.Format(time.RFC3339))
+
+	return report, nil
+}
+
+// paymentDrawer picks the drawer money taken or given back by uid goes
+// through: the one asked for, which has to be open, or else the drawer uid
+// opened. Without either the money is not tied to a drawer.
+func paymentDrawer(c context.Context, uid, drawerId string) (string, int, gin.H) {
+	if drawerId != "" {
+		drawer, err := drawerStore.Get(c, drawerId)
+		if err != nil {
+			return "", storeErrorStatus(err), gin.H{"error": "drawer was not found"}
+		}
+		if !drawer.IsOpen() {
+			return "", http.StatusConflict, gin.H{"error": "drawer is closed", "drawer_id": drawerId}
+		}
+		return drawerId, 0, nil
+	}
+
+	drawer, err := drawerStore.GetOpenByUser(c, uid)
+	if errors.Is(err, repository.ErrNotFound) {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", http.StatusInternalServerError, gin.H{"error": "error occured while looking up the drawer"}
+	}
+	return drawer.Drawer_id, 0, nil
+}
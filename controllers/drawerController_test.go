@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/models"
+)
+
+func drawerRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(middleware.Authentication())
+	router.POST("/drawers", OpenDrawer())
+	router.POST("/drawers/:drawer_id/movements", AddCashMovement())
+	router.GET("/drawers/:drawer_id/report", GetDrawerReport())
+	router.POST("/drawers/:drawer_id/close", CloseDrawer())
+	router.POST("/invoices/:invoice_id/payments", CreatePayment())
+	router.POST("/business-days/:business_date/close", CloseBusinessDay())
+	return router
+}
+
+func TestDrawerCashUp(t *testing.T) {
+	useMemoryStores(t)
+	useMockPayments()
+	router := drawerRouter()
+	_, cashier := signedIn(t, models.RoleCashier)
+	_, waiter := signedIn(t, models.RoleWaiter)
+	_, manager := signedIn(t, models.RoleManager)
+
+	var drawer models.Drawer
+	if code := performAs(t, router, cashier.Token, http.MethodPost, "/drawers", gin.H{"register": "front", "opening_float": "100.00"}, &drawer); code != http.StatusOK {
+		t.Fatalf("opening a drawer returned %d, want 200", code)
+	}
+	if code := performAs(t, router, cashier.Token, http.MethodPost, "/drawers", gin.H{"register": "bar"}, nil); code != http.StatusConflict {
+		t.Errorf("opening a second drawer returned %d, want 409", code)
+	}
+	if code := performAs(t, router, waiter.Token, http.MethodPost, "/drawers", gin.H{"register": "front"}, nil); code != http.StatusConflict {
+		t.Errorf("opening a register's second drawer returned %d, want 409", code)
+	}
+
+	// the cashier's payments go through their drawer
+	invoice := storedBill(t, 5000)
+	var payment models.Payment
+	if code := performAs(t, router, cashier.Token, http.MethodPost, "/invoices/"+invoice.Invoice_id+"/payments", gin.H{"method": models.PaymentCash, "tip": "5.00"}, &payment); code != http.StatusOK {
+		t.Fatalf("paying cash returned %d, want 200", code)
+	}
+	if payment.Drawer_id != drawer.Drawer_id {
+		t.Errorf("payment went through drawer %q, want the cashier's", payment.Drawer_id)
+	}
+
+	path := "/drawers/" + drawer.Drawer_id
+	if code := performAs(t, router, cashier.Token, http.MethodPost, path+"/movements", gin.H{"type": models.PaidOut, "amount": "5.00"}, nil); code != http.StatusBadRequest {
+		t.Errorf("a paid-out without a reason returned %d, want 400", code)
+	}
+	if code := performAs(t, router, cashier.Token, http.MethodPost, path+"/movements", gin.H{"type": models.PaidOut, "amount": "5.00", "reason": "milk"}, nil); code != http.StatusOK {
+		t.Fatalf("a paid-out returned %d, want 200", code)
+	}
+
+	var report models.CashReport
+	if code := performAs(t, router, cashier.Token, http.MethodGet, path+"/report", nil, &report); code != http.StatusOK {
+		t.Fatalf("the X report returned %d, want 200", code)
+	}
+	if report.Kind != models.ReportX || report.Expected_cash.Minor != 15000 {
+		t.Errorf("X report expects %s, want 150.00", report.Expected_cash)
+	}
+
+	// the day cannot close while a drawer is open
+	day := "/business-days/" + businessDate(time.Now()) + "/close"
+	if code := performAs(t, router, manager.Token, http.MethodPost, day, nil, nil); code != http.StatusConflict {
+		t.Errorf("closing the day with an open drawer returned %d, want 409", code)
+	}
+
+	if code := performAs(t, router, waiter.Token, http.MethodPost, path+"/close", gin.H{"counted_cash": "149.00"}, nil); code != http.StatusForbidden {
+		t.Errorf("closing another user's drawer returned %d, want 403", code)
+	}
+	if code := performAs(t, router, cashier.Token, http.MethodPost, path+"/close", gin.H{"counted_cash": "149.00"}, &drawer); code != http.StatusOK {
+		t.Fatalf("closing the drawer returned %d, want 200", code)
+	}
+	if drawer.Report == nil || drawer.Report.Kind != models.ReportZ || drawer.Report.Over_short == nil || drawer.Report.Over_short.Minor != -100 {
+		t.Fatalf("the drawer was closed with report %+v, want a Z report 1.00 short", drawer.Report)
+	}
+	if code := performAs(t, router, cashier.Token, http.MethodPost, path+"/close", gin.H{"counted_cash": "149.00"}, nil); code != http.StatusConflict {
+		t.Errorf("closing the drawer twice returned %d, want 409", code)
+	}
+
+	var closed models.BusinessDay
+	if code := performAs(t, router, manager.Token, http.MethodPost, day, nil, &closed); code != http.StatusOK {
+		t.Fatalf("closing the day returned %d, want 200", code)
+	}
+	if closed.Report.Kind != models.ReportZ || closed.Report.Cash_sales.Minor != 5000 || len(closed.Report.Drawers) != 1 {
+		t.Errorf("the day closed with report %+v, want the drawer's cash sales", closed.Report)
+	}
+	if code := performAs(t, router, manager.Token, http.MethodPost, day, nil, nil); code != http.StatusConflict {
+		t.Errorf("closing the day twice returned %d, want 409", code)
+	}
+
+	// a closed day takes no more money
+	if code := performAs(t, router, cashier.Token, http.MethodPost, "/invoices/"+storedBill(t, 1000).Invoice_id+"/payments", gin.H{"method": models.PaymentCash}, nil); code != http.StatusConflict {
+		t.Errorf("paying on a closed day returned %d, want 409", code)
+	}
+}
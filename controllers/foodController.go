@@ -2,28 +2,43 @@ package controllers
 
 import (
 	"context"
-	"log"
-	"math"
+	"fmt"
 	"net/http"
+	"reflect"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
-	"github.com/tokha04/go-restautant-management/database"
 	"github.com/tokha04/go-restautant-management/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/tokha04/go-restautant-management/money"
+	"github.com/tokha04/go-restautant-management/repository"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var foodCollection *mongo.Collection = database.OpenCollection(database.Client, "food")
+var foodStore repository.FoodStore
 var validate = validator.New()
 
+func init() {
+	// money is validated on its minor units, so gte=0 rejects negative prices
+	validate.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		return field.Interface().(money.Money).Minor
+	}, money.Money{})
+
+	validate.RegisterValidation("allergen", func(fl validator.FieldLevel) bool {
+		return slices.Contains(models.Allergens, fl.Field().String())
+	})
+	validate.RegisterValidation("dietary", func(fl validator.FieldLevel) bool {
+		return slices.Contains(models.DietaryTags, fl.Field().String())
+	})
+}
+
 func GetFoods() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
 		recordPerPage, err := strconv.Atoi(ctx.Query("recordPerPage"))
 		if err != nil || recordPerPage < 1 {
@@ -36,47 +51,35 @@ func GetFoods() gin.HandlerFunc {
 		}
 
 		startIndex := (page - 1) * recordPerPage
-		startIndex, err = strconv.Atoi(ctx.Query("startIndex"))
-
-		matchStage := bson.D{{"$match", bson.D{{}}}}
-		groupStage := bson.D{{"$group", bson.D{{"_id", bson.D{{"_id", "null"}}}, {"total_count", bson.D{{"$sum", 1}}}, {"data", bson.D{{"$push", "$$ROOT"}}}}}}
-		projectStage := bson.D{
-			{
-				"$project", bson.D{
-					{"_id", 0},
-					{"total_count", 1},
-					{"food_items", bson.D{{"$slice", []interface{}{"$data", startIndex, recordPerPage}}}},
-				},
-			},
-		}
-
-		res, err := foodCollection.Aggregate(c, mongo.Pipeline{
-			matchStage, groupStage, projectStage,
-		})
-		defer cancel()
+		if index, err := strconv.Atoi(ctx.Query("startIndex")); err == nil && index >= 0 {
+			startIndex = index
+		}
+
+		filter, err := foodFilter(ctx)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing food items"})
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
 
-		var allFoods []bson.M
-		if err = res.All(c, &allFoods); err != nil {
-			log.Fatal(err)
+		foods, total, err := foodStore.List(c, filter, startIndex, recordPerPage)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing food items"})
+			return
 		}
 
-		ctx.JSON(http.StatusOK, allFoods[0])
+		ctx.JSON(http.StatusOK, gin.H{"total_count": total, "food_items": foods})
 	}
 }
 
 func GetFood() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 		foodId := ctx.Param("food_id")
-		var food models.Food
 
-		err := foodCollection.FindOne(c, bson.M{"food_id": foodId}).Decode(&food)
-		defer cancel()
+		food, err := foodStore.Get(c, foodId)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the food item"})
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "error occured while fetching the food item"})
 			return
 		}
 
@@ -87,7 +90,7 @@ func GetFood() gin.HandlerFunc {
 func CreateFood() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-		var menu models.Menu
+		defer cancel()
 		var food models.Food
 
 		if err := ctx.BindJSON(&food); err != nil {
@@ -95,50 +98,41 @@ func CreateFood() gin.HandlerFunc {
 			return
 		}
 
+		food.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		food.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
 		validationErr := validate.Struct(food)
 		if validationErr != nil {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
 			return
 		}
 
-		err := menuCollection.FindOne(ctx, bson.M{"menu_id": food.Menu_id}).Decode(&menu)
-		defer cancel()
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "menu was not found"})
+		if status, body := checkFoodOptions(food); body != nil {
+			ctx.JSON(status, body)
+			return
+		}
+
+		if _, err := menuStore.Get(c, *food.Menu_id); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "menu was not found"})
 			return
 		}
 
-		food.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		food.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		food.ID = primitive.NewObjectID()
 		food.Food_id = food.ID.Hex()
-		var num = toFixed(*food.Price, 2)
-		food.Price = &num
-
-		res, insertErr := foodCollection.InsertOne(c, food)
-		if insertErr != nil {
+		if err := foodStore.Create(c, food); err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "food item was not created"})
 			return
 		}
 
-		ctx.JSON(http.StatusOK, res)
+		ctx.JSON(http.StatusOK, food)
 	}
 }
 
-func round(num float64) int {
-	return int(num + math.Copysign(0.5, num))
-}
-
-func toFixed(num float64, precision int) float64 {
-	output := math.Pow(10, float64(precision))
-	return float64(round(num*output)) / output
-}
-
 func UpdateFood() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 		foodId := ctx.Param("food_id")
-		var menu models.Menu
 		var food models.Food
 
 		if err := ctx.BindJSON(&food); err != nil {
@@ -146,54 +140,135 @@ func UpdateFood() gin.HandlerFunc {
 			return
 		}
 
-		var updateObj primitive.D
+		foundFood, err := foodStore.Get(c, foodId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "food item was not found"})
+			return
+		}
 
 		if food.Name != nil {
-			updateObj = append(updateObj, bson.E{"name", food.Name})
+			foundFood.Name = food.Name
 		}
 
 		if food.Price != nil {
-			updateObj = append(updateObj, bson.E{"price", food.Price})
+			foundFood.Price = food.Price
 		}
 
 		if food.Food_image != nil {
-			updateObj = append(updateObj, bson.E{"food_image", food.Food_image})
+			foundFood.Food_image = food.Food_image
+		}
+
+		// an empty list removes the variants, modifier groups, allergens or
+		// dietary tags, leaving them out keeps them
+		if food.Variants != nil {
+			foundFood.Variants = food.Variants
+		}
+		if food.Modifier_groups != nil {
+			foundFood.Modifier_groups = food.Modifier_groups
+		}
+		if food.Allergens != nil {
+			foundFood.Allergens = food.Allergens
+		}
+		if food.Dietary_tags != nil {
+			foundFood.Dietary_tags = food.Dietary_tags
 		}
 
 		if food.Menu_id != nil {
-			err := menuCollection.FindOne(c, bson.M{"menu_id": food.Menu_id}).Decode(&menu)
-			defer cancel()
-			if err != nil {
-				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "menu was not found"})
+			if _, err := menuStore.Get(c, *food.Menu_id); err != nil {
+				ctx.JSON(storeErrorStatus(err), gin.H{"error": "menu was not found"})
 				return
 			}
 
-			// why???
-			updateObj = append(updateObj, bson.E{"menu", food.Price})
+			foundFood.Menu_id = food.Menu_id
 		}
 
-		food.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		updateObj = append(updateObj, bson.E{"updated_at", food.Updated_at})
-
-		upsert := true
-		filter := bson.M{"food_id": foodId}
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
-		}
-
-		res, err := foodCollection.UpdateOne(
-			c,
-			filter,
-			bson.D{
-				{"$set", updateObj},
-			},
-			&opt,
-		)
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "food update failed"})
+		foundFood.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		validationErr := validate.Struct(foundFood)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
 		}
 
-		defer cancel()
-		ctx.JSON(http.StatusOK, res)
+		if status, body := checkFoodOptions(foundFood); body != nil {
+			ctx.JSON(status, body)
+			return
+		}
+
+		if err := foodStore.Update(c, foundFood); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "food update failed"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, foundFood)
+	}
+}
+
+// checkFoodOptions refuses food priced twice for the same variant and size,
+// and modifier groups that are ambiguous or can never be satisfied.
+func checkFoodOptions(food models.Food) (int, gin.H) {
+	seen := map[models.FoodVariant]bool{}
+	for _, variant := range food.Variants {
+		key := models.FoodVariant{Name: variant.Name, Size: variant.Size}
+		if seen[key] {
+			return http.StatusBadRequest, gin.H{"error": "variant is priced more than once", "name": variant.Name, "size": variant.Size}
+		}
+		seen[key] = true
+	}
+
+	groups := map[string]bool{}
+	for _, group := range food.Modifier_groups {
+		if groups[group.Name] {
+			return http.StatusBadRequest, gin.H{"error": "modifier group " + group.Name + " is defined more than once"}
+		}
+		groups[group.Name] = true
+
+		if group.Max > 0 && group.Max < group.Min {
+			return http.StatusBadRequest, gin.H{"error": "modifier group " + group.Name + " has a max below its min"}
+		}
+		if group.Min > len(group.Modifiers) {
+			return http.StatusBadRequest, gin.H{"error": "modifier group " + group.Name + " has fewer modifiers than its min"}
+		}
+
+		modifiers := map[string]bool{}
+		for _, modifier := range group.Modifiers {
+			if modifiers[modifier.Name] {
+				return http.StatusBadRequest, gin.H{"error": "modifier " + modifier.Name + " is in group " + group.Name + " more than once"}
+			}
+			modifiers[modifier.Name] = true
+		}
+	}
+	return 0, nil
+}
+
+// foodFilter reads the exclude_allergens and dietary_tags query parameters,
+// each a comma-separated list such as MILK,PEANUTS.
+func foodFilter(ctx *gin.Context) (repository.FoodFilter, error) {
+	var filter repository.FoodFilter
+
+	for _, allergen := range queryList(ctx, "exclude_allergens") {
+		if !slices.Contains(models.Allergens, allergen) {
+			return filter, fmt.Errorf("unknown allergen %s, expected one of %s", allergen, strings.Join(models.Allergens, ", "))
+		}
+		filter.Exclude_allergens = append(filter.Exclude_allergens, allergen)
+	}
+	for _, tag := range queryList(ctx, "dietary_tags") {
+		if !slices.Contains(models.DietaryTags, tag) {
+			return filter, fmt.Errorf("unknown dietary tag %s, expected one of %s", tag, strings.Join(models.DietaryTags, ", "))
+		}
+		filter.Dietary_tags = append(filter.Dietary_tags, tag)
+	}
+
+	return filter, nil
+}
+
+// queryList splits a comma-separated query parameter into upper-case values.
+func queryList(ctx *gin.Context, name string) []string {
+	values := []string{}
+	for _, value := range strings.Split(ctx.Query(name), ",") {
+		if value = strings.ToUpper(strings.TrimSpace(value)); value != "" {
+			values = append(values, value)
+		}
 	}
+	return values
 }
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// storedFood stores a food on an always served menu.
+func storedFood(t *testing.T, name string, allergens, dietaryTags []string) models.Food {
+	t.Helper()
+
+	menu := models.Menu{ID: primitive.NewObjectID(), Name: "All day", Category: "Mains"}
+	menu.Menu_id = menu.ID.Hex()
+	if err := menuStore.Create(context.Background(), menu); err != nil {
+		t.Fatal(err)
+	}
+
+	food := models.Food{ID: primitive.NewObjectID(), Name: ptr(name), Price: ptr(money.New(1000)), Menu_id: &menu.Menu_id, Allergens: allergens, Dietary_tags: dietaryTags}
+	food.Food_id = food.ID.Hex()
+	if err := foodStore.Create(context.Background(), food); err != nil {
+		t.Fatal(err)
+	}
+	return food
+}
+
+func TestGetFoodsFiltersAllergensAndDiets(t *testing.T) {
+	useMemoryStores(t)
+	router := gin.New()
+	router.GET("/foods", GetFoods())
+
+	storedFood(t, "Risotto", []string{models.AllergenMilk, models.AllergenCelery}, []string{models.DietVegetarian, models.DietGlutenFree})
+	storedFood(t, "Satay", []string{models.AllergenPeanuts, models.AllergenSoya}, []string{models.DietHalal})
+	storedFood(t, "Salad", nil, []string{models.DietVegan, models.DietVegetarian, models.DietGlutenFree})
+	storedFood(t, "Bread", []string{models.AllergenGluten}, []string{models.DietVegan, models.DietVegetarian})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"Bread", "Risotto", "Salad", "Satay"}},
+		{"exclude_allergens=milk", []string{"Bread", "Salad", "Satay"}},
+		{"exclude_allergens=MILK,%20peanuts", []string{"Bread", "Salad"}},
+		{"dietary_tags=vegetarian", []string{"Bread", "Risotto", "Salad"}},
+		{"dietary_tags=VEGETARIAN,GLUTEN_FREE", []string{"Risotto", "Salad"}},
+		{"dietary_tags=vegan&exclude_allergens=gluten", []string{"Salad"}},
+	}
+
+	for _, tt := range tests {
+		var page struct {
+			Total_count int           `json:"total_count"`
+			Food_items  []models.Food `json:"food_items"`
+		}
+		if code := perform(t, router, http.MethodGet, "/foods?"+tt.query, nil, &page); code != http.StatusOK {
+			t.Fatalf("GET /foods?%s returned %d", tt.query, code)
+		}
+
+		names := []string{}
+		for _, food := range page.Food_items {
+			names = append(names, *food.Name)
+		}
+		slices.Sort(names)
+		if !slices.Equal(names, tt.want) || page.Total_count != len(tt.want) {
+			t.Errorf("GET /foods?%s = %v of %d, want %v", tt.query, names, page.Total_count, tt.want)
+		}
+	}
+
+	for _, query := range []string{"exclude_allergens=nuts", "dietary_tags=keto"} {
+		if code := perform(t, router, http.MethodGet, "/foods?"+query, nil, nil); code != http.StatusBadRequest {
+			t.Errorf("GET /foods?%s returned %d, want 400", query, code)
+		}
+	}
+}
+
+func TestCreateOrderItemWarnsAboutAllergies(t *testing.T) {
+	useMemoryStores(t)
+	router := gin.New()
+	router.Use(middleware.Authentication())
+	router.POST("/orderItems", CreateOrderItem())
+	router.POST("/orderItems/:order_item_id/cancel", CancelOrderItem())
+	_, waiter := signedIn(t, models.RoleWaiter)
+
+	risotto := storedFood(t, "Risotto", []string{models.AllergenMilk, models.AllergenCelery}, nil)
+	salad := storedFood(t, "Salad", nil, nil)
+
+	pack := gin.H{
+		"table_id": "table",
+		"order_items": []gin.H{
+			{"food_id": risotto.Food_id, "quantity": "M", "seat": 2},
+			{"food_id": risotto.Food_id, "quantity": "M", "seat": 3},
+			{"food_id": salad.Food_id, "quantity": "M", "seat": 2},
+		},
+		"seat_allergies": []gin.H{{"seat": 2, "allergens": []string{models.AllergenMilk}}},
+	}
+	var orderItems []models.OrderItem
+	if code := performAs(t, router, waiter.Token, http.MethodPost, "/orderItems", pack, &orderItems); code != http.StatusOK {
+		t.Fatalf("ordering returned %d", code)
+	}
+
+	order, err := orderStore.Get(context.Background(), orderItems[0].Order_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !order.Allergen_warning || len(order.Allergen_warnings) != 1 {
+		t.Fatalf("order warnings = %+v, want one", order.Allergen_warnings)
+	}
+	warning := order.Allergen_warnings[0]
+	if warning.Order_item_id != orderItems[0].Order_item_id || warning.Seat != 2 || !slices.Equal(warning.Allergens, []string{models.AllergenMilk}) {
+		t.Errorf("warning = %+v, want milk for the risotto on seat 2", warning)
+	}
+
+	// taking the risotto back clears the warning
+	if code := performAs(t, router, waiter.Token, http.MethodPost, "/orderItems/"+orderItems[0].Order_item_id+"/cancel", nil, nil); code != http.StatusOK {
+		t.Fatalf("cancelling returned %d", code)
+	}
+	order, err = orderStore.Get(context.Background(), order.Order_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Allergen_warning || len(order.Allergen_warnings) != 0 {
+		t.Errorf("order warnings after cancelling = %+v, want none", order.Allergen_warnings)
+	}
+
+	pack["seat_allergies"] = []gin.H{{"seat": 2, "allergens": []string{"NUTS"}}}
+	if code := performAs(t, router, waiter.Token, http.MethodPost, "/orderItems", pack, nil); code != http.StatusBadRequest {
+		t.Errorf("ordering with an unknown allergy returned %d, want 400", code)
+	}
+}
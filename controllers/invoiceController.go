@@ -2,45 +2,62 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/tokha04/go-restautant-management/database"
+	"github.com/tokha04/go-restautant-management/billing"
 	"github.com/tokha04/go-restautant-management/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/tokha04/go-restautant-management/repository"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type InvoiceViewFormat struct {
-	Invoice_id       string
-	Payment_method   string
-	Order_id         string
-	Payment_status   *string
-	Payment_due      interface{}
-	Table_number     interface{}
-	Payment_due_date time.Time
-	Order_details    interface{}
+var invoiceStore repository.InvoiceStore
+var counterStore repository.CounterStore
+
+// Invoice numbers run from 1 in every fiscal year, e.g. 2026-000123. The
+// prefix tells apart restaurants sharing a database.
+var invoicePrefix string
+var fiscalYearStart = time.January
+
+// InvoicePack invoices an order. Everything else on the invoice is worked
+// out from the order.
+type InvoicePack struct {
+	Order_id         string    `json:"order_id" validate:"required"`
+	Payment_method   *string   `json:"payment_method" validate:"omitempty,eq=CARD|eq=CASH"`
+	Payment_due_date time.Time `json:"payment_due_date"`
 }
 
-var invoiceCollection *mongo.Collection = database.OpenCollection(database.Client, "invoice")
+// VoidInvoicePack cancels an invoice. The reason is kept on the invoice.
+type VoidInvoicePack struct {
+	Reason string `json:"reason" validate:"required,max=250"`
+}
+
+// UseInvoiceNumbering sets the invoice number prefix and the month fiscal
+// years start in. A fiscal year is named after the year it starts in.
+func UseInvoiceNumbering(prefix string, startMonth time.Month) {
+	invoicePrefix = prefix
+	fiscalYearStart = startMonth
+}
 
 func GetInvoices() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-
-		res, err := invoiceCollection.Find(context.TODO(), bson.M{})
 		defer cancel()
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing invoice items"})
+
+		var statuses []string
+		if status := ctx.Query("status"); status != "" {
+			statuses = strings.Split(strings.ToUpper(status), ",")
 		}
 
-		var allInvoices []bson.M
-		if err = res.All(c, &allInvoices); err != nil {
-			log.Fatal(err)
+		allInvoices, err := invoiceStore.List(c, statuses...)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing invoice items"})
+			return
 		}
 
 		ctx.JSON(http.StatusOK, allInvoices)
@@ -50,84 +67,170 @@ func GetInvoices() gin.HandlerFunc {
 func GetInvoice() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 		invoiceId := ctx.Param("invoice_id")
-		var invoice models.Invoice
 
-		err := invoiceCollection.FindOne(c, bson.M{"invoice_id": invoiceId}).Decode(&invoice)
-		defer cancel()
+		invoice, err := invoiceStore.Get(c, invoiceId)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the invoice item"})
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "error occured while fetching the invoice item"})
 			return
 		}
 
-		var invoiceView InvoiceViewFormat
-
-		allOrderItems, err := ItemsByOrder(invoice.Order_id)
-		invoiceView.Order_id = invoice.Order_id
-		invoiceView.Payment_due_date = invoice.Payment_due_date
-		invoiceView.Payment_method = "null"
-		if invoice.Payment_method != nil {
-			invoiceView.Payment_method = *invoice.Payment_method
+		invoiceView, err := viewInvoice(c, invoice)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while preparing the invoice"})
+			return
 		}
-		invoiceView.Invoice_id = invoice.Invoice_id
-		invoiceView.Payment_status = *&invoice.Payment_status
-		invoiceView.Payment_due = allOrderItems[0]["payment_due"]
-		invoiceView.Table_number = allOrderItems[0]["table_number"]
-		invoiceView.Order_details = allOrderItems[0]["order_items"]
 
 		ctx.JSON(http.StatusOK, invoiceView)
 	}
 }
 
+// viewInvoice builds the view of the invoice. Invoices created before taxes
+// were tracked are priced on the fly.
+func viewInvoice(c context.Context, invoice models.Invoice) (models.InvoiceViewFormat, error) {
+	var invoiceView models.InvoiceViewFormat
+
+	summary, err := orderItemStore.ItemsByOrder(c, invoice.Order_id)
+	if err != nil {
+		return invoiceView, err
+	}
+
+	if invoice.Lines == nil && invoice.Split_mode == "" {
+		if err := priceInvoice(c, &invoice, summary); err != nil {
+			return invoiceView, err
+		}
+		billing.Settle(&invoice, nil, nil)
+	}
+
+	invoiceView.Order_id = invoice.Order_id
+	invoiceView.Payment_due_date = invoice.Payment_due_date
+	invoiceView.Created_at = invoice.Created_at
+	invoiceView.Payment_method = "null"
+	if invoice.Payment_method != nil {
+		invoiceView.Payment_method = *invoice.Payment_method
+	}
+	invoiceView.Invoice_id = invoice.Invoice_id
+	invoiceView.Invoice_number = invoice.Invoice_number
+	invoiceView.Payment_status = invoice.Payment_status
+	invoiceView.Payment_due = invoice.Balance
+	invoiceView.Table_number = summary.Table_number
+	invoiceView.Discounts = invoice.Discounts
+	invoiceView.Discount_total = invoice.Discount_total
+	invoiceView.Subtotal = invoice.Subtotal
+	invoiceView.Tax_total = invoice.Tax_total
+	invoiceView.Service_charges = invoice.Service_charges
+	invoiceView.Service_total = invoice.Service_total
+	invoiceView.Total = invoice.Total
+	invoiceView.Tip_total = invoice.Tip_total
+	invoiceView.Amount_paid = invoice.Amount_paid
+	invoiceView.Amount_refunded = invoice.Amount_refunded
+	invoiceView.Amount_credited = invoice.Amount_credited
+	invoiceView.Balance = invoice.Balance
+	invoiceView.Tax_breakdown = invoice.Tax_breakdown
+	invoiceView.Split_mode = invoice.Split_mode
+	invoiceView.Split_index = invoice.Split_index
+	invoiceView.Split_count = invoice.Split_count
+	invoiceView.Order_details = invoice.Lines
+
+	return invoiceView, nil
+}
+
 func CreateInvoice() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-		var invoice models.Invoice
-		var order models.Order
+		defer cancel()
+		var invoicePack InvoicePack
 
-		if err := ctx.BindJSON(&order); err != nil {
+		if err := ctx.BindJSON(&invoicePack); err != nil {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		err := orderCollection.FindOne(c, bson.M{"order_id": invoice.Order_id}).Decode(&order)
-		defer cancel()
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "order was not found"})
+		validationErr := validate.Struct(invoicePack)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
 			return
 		}
 
-		status := "PENDING"
-		if invoice.Payment_status == nil {
-			invoice.Payment_status = &status
+		invoice := models.Invoice{
+			Order_id:         invoicePack.Order_id,
+			Payment_method:   invoicePack.Payment_method,
+			Payment_due_date: invoicePack.Payment_due_date,
+		}
+
+		if status, body := checkDayOpen(c, time.Now()); body != nil {
+			ctx.JSON(status, body)
+			return
+		}
+
+		if status, body := checkBillable(c, invoice.Order_id); body != nil {
+			ctx.JSON(status, body)
+			return
 		}
 
-		invoice.Payment_due_date, _ = time.Parse(time.RFC3339, time.Now().AddDate(0, 0, 1).Format(time.RFC3339))
+		// new invoices are unpaid; payments move them on
+		status := models.InvoicePending
+		invoice.Payment_status = &status
+
+		// invoices are due the next day unless the guest pays on terms, e.g.
+		// a catering or corporate account
+		if invoice.Payment_due_date.IsZero() {
+			invoice.Payment_due_date, _ = time.Parse(time.RFC3339, time.Now().AddDate(0, 0, 1).Format(time.RFC3339))
+		} else if invoice.Payment_due_date.Before(time.Now()) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "payment_due_date must be in the future"})
+			return
+		}
 		invoice.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		invoice.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		invoice.ID = primitive.NewObjectID()
 		invoice.Invoice_id = invoice.ID.Hex()
 
-		validationErr := validate.Struct(invoice)
+		validationErr = validate.Struct(invoice)
 		if validationErr != nil {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
 			return
 		}
 
-		res, insertErr := invoiceCollection.InsertOne(c, invoice)
-		if insertErr != nil {
+		summary, err := orderItemStore.ItemsByOrder(c, invoice.Order_id)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing order items by orderId"})
+			return
+		}
+
+		if err := priceInvoice(c, &invoice, summary); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while calculating the invoice taxes"})
+			return
+		}
+
+		if status, body := claimInvoicing(c, invoice.Order_id); body != nil {
+			ctx.JSON(status, body)
+			return
+		}
+
+		invoices := []models.Invoice{invoice}
+		if err := numberInvoices(c, invoices); err != nil {
+			releaseInvoicing(c, invoice.Order_id)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "invoice number could not be allocated"})
+			return
+		}
+		invoice = invoices[0]
+
+		if err := invoiceStore.Create(c, invoice); err != nil {
+			voidUnrecorded(c, invoices)
+			releaseInvoicing(c, invoice.Order_id)
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "invoice item was not created"})
 			return
 		}
 
-		defer cancel()
-		ctx.JSON(http.StatusOK, res)
+		ctx.JSON(http.StatusOK, invoice)
 	}
 }
 
 func UpdateInvoice() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 		invoiceId := ctx.Param("invoice_id")
 		var invoice models.Invoice
 
@@ -136,44 +239,331 @@ func UpdateInvoice() gin.HandlerFunc {
 			return
 		}
 
-		var updateObj primitive.D
+		foundInvoice, err := invoiceStore.Get(c, invoiceId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "invoice was not found"})
+			return
+		}
 
-		if invoice.Payment_method != nil {
-			updateObj = append(updateObj, bson.E{"payment_method", invoice.Payment_method})
+		if status, body := checkDayOpen(c, foundInvoice.Created_at); body != nil {
+			ctx.JSON(status, body)
+			return
 		}
 
-		if invoice.Payment_status != nil {
-			updateObj = append(updateObj, bson.E{"payment_status", invoice.Payment_status})
+		from := foundInvoice.StoredStatus()
+		if from == models.InvoiceVoided || from == models.InvoicePaid {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "voided and paid invoices cannot be changed", "status": from})
+			return
 		}
 
-		invoice.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		updateObj = append(updateObj, bson.E{"updated_at", invoice.Updated_at})
+		if invoice.Payment_method != nil {
+			foundInvoice.Payment_method = invoice.Payment_method
+		}
 
-		upsert := true
-		filter := bson.M{"inovice_id": invoiceId}
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
+		// only recorded payments move an invoice to PAID or FAILED
+		if invoice.Payment_status != nil && (foundInvoice.Payment_status == nil || *invoice.Payment_status != *foundInvoice.Payment_status) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "payment status is set by recording a payment, not by updating the invoice"})
+			return
 		}
 
-		status := "PENDING"
-		if invoice.Payment_status == nil {
-			invoice.Payment_status = &status
+		foundInvoice.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		validationErr := validate.Struct(foundInvoice)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
 		}
 
-		res, err := invoiceCollection.UpdateOne(
-			c,
-			filter,
-			bson.D{
-				{"$set", updateObj},
-			},
-			&opt,
-		)
+		// only the payment method is written, and only while no payment or
+		// void has changed the invoice's status since it was read
+		updatedInvoice, err := invoiceStore.UpdatePaymentMethod(c, invoiceId, from, foundInvoice.Payment_method, foundInvoice.Updated_at)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "invoice update failed"})
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "invoice update failed"})
 			return
 		}
 
+		ctx.JSON(http.StatusOK, updatedInvoice)
+	}
+}
+
+// VoidInvoice cancels an invoice that holds no money. The invoice is kept
+// with its number, and the order can be invoiced again.
+func VoidInvoice() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
 		defer cancel()
-		ctx.JSON(http.StatusOK, res)
+		invoiceId := ctx.Param("invoice_id")
+		var voidInvoicePack VoidInvoicePack
+
+		if err := ctx.BindJSON(&voidInvoicePack); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := validate.Struct(voidInvoicePack)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		invoice, err := invoiceStore.Get(c, invoiceId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "invoice was not found"})
+			return
+		}
+		from := invoice.StoredStatus()
+
+		if invoice.IsVoided() {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "invoice has already been voided", "voided_at": invoice.Voided_at})
+			return
+		}
+
+		if status, body := checkDayOpen(c, invoice.Created_at); body != nil {
+			ctx.JSON(status, body)
+			return
+		}
+
+		allPayments, err := paymentStore.ListByInvoice(c, invoiceId)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing payments"})
+			return
+		}
+		for _, payment := range allPayments {
+			if payment.Status == models.PaymentPending || payment.Status == models.PaymentAuthorized {
+				ctx.JSON(http.StatusConflict, gin.H{"error": "invoice has a payment in progress", "payment_id": payment.Payment_id})
+				return
+			}
+		}
+
+		creditNotes, err := creditNoteStore.ListByInvoice(c, invoiceId)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing credit notes"})
+			return
+		}
+
+		billing.Settle(&invoice, allPayments, creditNotes)
+		if held := invoice.Amount_paid.Sub(invoice.Amount_refunded); held.Minor > 0 {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "refund the payments before voiding the invoice", "amount_paid": held})
+			return
+		}
+
+		voidInvoice(&invoice, ctx.GetString("uid"), voidInvoicePack.Reason)
+
+		// a payment taken since the invoice was read moves its status on and
+		// stops the void
+		if _, err := invoiceStore.Void(c, invoice, from); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "invoice update failed"})
+			return
+		}
+
+		releaseInvoicing(c, invoice.Order_id)
+
+		ctx.JSON(http.StatusOK, invoice)
+	}
+}
+
+func voidInvoice(invoice *models.Invoice, uid, reason string) {
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	status := models.InvoiceVoided
+	invoice.Payment_status = &status
+	invoice.Voided_at = &now
+	invoice.Voided_by = uid
+	invoice.Void_reason = reason
+	invoice.Updated_at = now
+}
+
+// fiscalYear names the fiscal year t falls in after the year it starts in.
+func fiscalYear(t time.Time) int {
+	if t.Month() < fiscalYearStart {
+		return t.Year() - 1
+	}
+	return t.Year()
+}
+
+// numberInvoices gives the invoices, in order, the next numbers of the fiscal
+// year they are issued in. The numbers are taken in one atomic step, so
+// concurrent checkouts never share or skip one.
+func numberInvoices(c context.Context, invoices []models.Invoice) error {
+	year := fiscalYear(invoices[0].Created_at.In(restaurantTimezone))
+
+	series := fmt.Sprintf("invoice:%s:%d", invoicePrefix, year)
+	last, err := counterStore.Next(c, series, int64(len(invoices)))
+	if err != nil {
+		return err
+	}
+
+	prefix := ""
+	if invoicePrefix != "" {
+		prefix = invoicePrefix + "-"
+	}
+
+	first := last - int64(len(invoices)) + 1
+	for i := range invoices {
+		invoices[i].Fiscal_year = year
+		invoices[i].Invoice_number = fmt.Sprintf("%s%d-%06d", prefix, year, first+int64(i))
+	}
+	return nil
+}
+
+// voidUnrecorded records the invoices that could not be stored as voided, so
+// the numbers they were given still show up in the sequence.
+func voidUnrecorded(c context.Context, invoices []models.Invoice) {
+	for _, invoice := range invoices {
+		if _, err := invoiceStore.Get(c, invoice.Invoice_id); !errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+
+		voidInvoice(&invoice, "", "the invoice could not be recorded")
+		if err := invoiceStore.Create(c, invoice); err != nil {
+			log.Printf("invoice number %s was allocated but could not be recorded: %v", invoice.Invoice_number, err)
+		}
+	}
+}
+
+// priceInvoice fills the invoice lines, tax breakdown, service charges and
+// totals from the order summary.
+func priceInvoice(c context.Context, invoice *models.Invoice, summary models.OrderSummary) error {
+	calc, err := newCalculator(c, summary)
+	if err != nil {
+		return err
+	}
+
+	calc.Calculate(invoice, summary.Order_items)
+	billing.Settle(invoice, nil, nil)
+
+	return nil
+}
+
+// newCalculator prices the order with its active discounts and the tax rates
+// and service charges configured right now.
+func newCalculator(c context.Context, summary models.OrderSummary) (billing.Calculator, error) {
+	taxRates, err := taxRateStore.List(c)
+	if err != nil {
+		return billing.Calculator{}, err
+	}
+
+	serviceCharges, err := serviceChargeStore.List(c)
+	if err != nil {
+		return billing.Calculator{}, err
+	}
+
+	allDiscounts, err := discountStore.ListByOrder(c, summary.Order_id)
+	if err != nil {
+		return billing.Calculator{}, err
+	}
+
+	discounts := []models.Discount{}
+	for _, discount := range allDiscounts {
+		if discount.IsActive() {
+			discounts = append(discounts, discount)
+		}
+	}
+
+	return billing.Calculator{Tax_rates: taxRates, Service_charges: serviceCharges, Discounts: discounts, Guests: summary.Guests}, nil
+}
+
+// checkBillable makes sure the order can be invoiced: it has been served and
+// is not billed yet.
+func checkBillable(c context.Context, orderId string) (int, gin.H) {
+	order, err := orderStore.Get(c, orderId)
+	if err != nil {
+		return storeErrorStatus(err), gin.H{"error": "order was not found"}
+	}
+
+	if order.CurrentStatus() != models.OrderServed {
+		return http.StatusConflict, gin.H{"error": "invoices can only be created for served orders", "status": order.CurrentStatus()}
+	}
+
+	invoices, err := liveInvoices(c, orderId)
+	if err != nil {
+		return http.StatusInternalServerError, gin.H{"error": "error occured while listing the order invoices"}
+	}
+	if len(invoices) > 0 {
+		return http.StatusConflict, gin.H{"error": "order has already been invoiced", "invoice_count": len(invoices)}
+	}
+
+	return 0, nil
+}
+
+// claimInvoicing marks the order as invoiced in one atomic step. Of two
+// cashiers invoicing the same order at once, only one gets the claim.
+func claimInvoicing(c context.Context, orderId string) (int, gin.H) {
+	if _, err := orderStore.SetInvoiced(c, orderId, true); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return http.StatusConflict, gin.H{"error": "order has already been invoiced"}
+		}
+		return storeErrorStatus(err), gin.H{"error": "order could not be marked as invoiced"}
+	}
+	return 0, nil
+}
+
+// releaseInvoicing lets the order be invoiced again once none of its
+// invoices is live.
+func releaseInvoicing(c context.Context, orderId string) {
+	invoices, err := liveInvoices(c, orderId)
+	if err != nil {
+		log.Printf("order %s could not be released for invoicing: %v", orderId, err)
+		return
+	}
+	if len(invoices) > 0 {
+		return
+	}
+
+	if _, err := orderStore.SetInvoiced(c, orderId, false); err != nil && !errors.Is(err, repository.ErrConflict) {
+		log.Printf("order %s could not be released for invoicing: %v", orderId, err)
+	}
+}
+
+// liveInvoices returns the invoices of the order that were not voided.
+func liveInvoices(c context.Context, orderId string) ([]models.Invoice, error) {
+	allInvoices, err := invoiceStore.ListByOrder(c, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	invoices := []models.Invoice{}
+	for _, invoice := range allInvoices {
+		if !invoice.IsVoided() {
+			invoices = append(invoices, invoice)
+		}
+	}
+	return invoices, nil
+}
+
+// unpaidInvoices counts the invoices of the order that are not paid yet.
+func unpaidInvoices(c context.Context, orderId string) (int, error) {
+	invoices, err := liveInvoices(c, orderId)
+	if err != nil {
+		return 0, err
+	}
+
+	unpaid := 0
+	for _, invoice := range invoices {
+		if invoice.Payment_status == nil {
+			unpaid++
+			continue
+		}
+		if *invoice.Payment_status != models.InvoicePaid && *invoice.Payment_status != models.InvoiceRefunded {
+			unpaid++
+		}
+	}
+	return unpaid, nil
+}
+
+// settleOrder marks the order of a paid invoice as paid when no other invoice
+// of the order is still open.
+func settleOrder(c context.Context, invoice models.Invoice, uid string) {
+	order, err := orderStore.Get(c, invoice.Order_id)
+	if err != nil || order.CurrentStatus() != models.OrderServed {
+		return
+	}
+
+	unpaid, err := unpaidInvoices(c, order.Order_id)
+	if err != nil || unpaid > 0 {
+		return
+	}
+
+	if _, err := advanceOrder(c, order.Order_id, models.OrderPaid, uid); err != nil {
+		log.Printf("invoice %s is paid but order %s could not be marked paid: %v", invoice.Invoice_id, order.Order_id, err)
 	}
 }
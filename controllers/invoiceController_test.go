@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/models"
+)
+
+func invoiceRouter() *gin.Engine {
+	router := paymentRouter()
+	router.PATCH("/invoices/:invoice_id", UpdateInvoice())
+	router.POST("/invoices/:invoice_id/void", VoidInvoice())
+	return router
+}
+
+func TestUpdateInvoice(t *testing.T) {
+	useMemoryStores(t)
+	useMockPayments()
+	router := invoiceRouter()
+	invoice := storedBill(t, 5000)
+	path := "/invoices/" + invoice.Invoice_id
+
+	var updated models.Invoice
+	if code := perform(t, router, http.MethodPatch, path, gin.H{"payment_method": models.PaymentCard}, &updated); code != http.StatusOK {
+		t.Fatalf("setting the payment method returned %d, want 200", code)
+	}
+	if updated.Payment_method == nil || *updated.Payment_method != models.PaymentCard || updated.Total != invoice.Total {
+		t.Errorf("updated invoice is %+v, want the payment method set and the rest kept", updated)
+	}
+
+	if code := perform(t, router, http.MethodPatch, path, gin.H{"payment_status": models.InvoicePaid}, nil); code != http.StatusBadRequest {
+		t.Errorf("setting the payment status returned %d, want 400", code)
+	}
+
+	if code := perform(t, router, http.MethodPost, path+"/payments", gin.H{"method": models.PaymentCash}, nil); code != http.StatusOK {
+		t.Fatalf("paying cash returned %d, want 200", code)
+	}
+	if code := perform(t, router, http.MethodPatch, path, gin.H{"payment_method": models.PaymentCard}, nil); code != http.StatusConflict {
+		t.Errorf("changing a paid invoice returned %d, want 409", code)
+	}
+
+	voided := storedBill(t, 5000)
+	if code := perform(t, router, http.MethodPost, "/invoices/"+voided.Invoice_id+"/void", gin.H{"reason": "wrong table"}, nil); code != http.StatusOK {
+		t.Fatalf("voiding returned %d, want 200", code)
+	}
+	if code := perform(t, router, http.MethodPatch, "/invoices/"+voided.Invoice_id, gin.H{"payment_method": models.PaymentCard}, nil); code != http.StatusConflict {
+		t.Errorf("changing a voided invoice returned %d, want 409", code)
+	}
+}
+
+func TestVoidInvoice(t *testing.T) {
+	useMemoryStores(t)
+	useMockPayments()
+	router := invoiceRouter()
+	invoice := storedBill(t, 5000)
+	path := "/invoices/" + invoice.Invoice_id
+
+	if code := perform(t, router, http.MethodPost, path+"/payments", gin.H{"method": models.PaymentCash, "amount": "10.00"}, nil); code != http.StatusOK {
+		t.Fatalf("paying cash returned %d, want 200", code)
+	}
+	if code := perform(t, router, http.MethodPost, path+"/void", gin.H{"reason": "wrong table"}, nil); code != http.StatusConflict {
+		t.Errorf("voiding an invoice holding money returned %d, want 409", code)
+	}
+
+	other := storedBill(t, 5000)
+	var voided models.Invoice
+	if code := perform(t, router, http.MethodPost, "/invoices/"+other.Invoice_id+"/void", gin.H{"reason": "wrong table"}, &voided); code != http.StatusOK {
+		t.Fatalf("voiding returned %d, want 200", code)
+	}
+	stored, err := invoiceStore.Get(context.Background(), other.Invoice_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.IsVoided() || stored.Void_reason != "wrong table" || stored.Total != other.Total {
+		t.Errorf("stored invoice is %+v, want it voided with its amounts kept", stored)
+	}
+	if code := perform(t, router, http.MethodPost, "/invoices/"+other.Invoice_id+"/void", gin.H{"reason": "again"}, nil); code != http.StatusConflict {
+		t.Errorf("voiding twice returned %d, want 409", code)
+	}
+}
+
+func TestInvoiceWritesFromStaleStatus(t *testing.T) {
+	useMemoryStores(t)
+	invoice := storedBill(t, 5000)
+
+	// a payment settled the invoice since it was read
+	paid := invoice
+	paid.Payment_status = ptr(models.InvoicePaid)
+	if _, err := invoiceStore.UpdateSettlement(context.Background(), paid, models.InvoicePending); err != nil {
+		t.Fatal(err)
+	}
+
+	voided := invoice
+	voidInvoice(&voided, "uid", "wrong table")
+	if _, err := invoiceStore.Void(context.Background(), voided, models.InvoicePending); storeErrorStatus(err) != http.StatusConflict {
+		t.Errorf("voiding from a stale status returned %v, want a conflict", err)
+	}
+	if _, err := invoiceStore.UpdatePaymentMethod(context.Background(), invoice.Invoice_id, models.InvoicePending, ptr(models.PaymentCard), invoice.Updated_at); storeErrorStatus(err) != http.StatusConflict {
+		t.Errorf("updating from a stale status returned %v, want a conflict", err)
+	}
+	if _, err := invoiceStore.UpdateSettlement(context.Background(), invoice, models.InvoicePending); storeErrorStatus(err) != http.StatusConflict {
+		t.Errorf("settling from a stale status returned %v, want a conflict", err)
+	}
+}
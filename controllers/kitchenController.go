@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/kitchen"
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/repository"
+)
+
+var kitchenFeed kitchen.Feed = kitchen.NewBroker()
+
+// UseKitchenFeed replaces the in-process kitchen broker, e.g. with a feed
+// backed by MongoDB change streams.
+func UseKitchenFeed(feed kitchen.Feed) {
+	kitchenFeed = feed
+}
+
+const kitchenHeartbeat = 15 * time.Second
+
+// GetKitchenItems lists the items still waiting to be prepared, which is what
+// a kitchen screen shows before it starts following the stream.
+func GetKitchenItems() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		pendingItems, err := orderItemStore.ListPending(c)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing kitchen items"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, pendingItems)
+	}
+}
+
+// KitchenStream pushes order item events to a kitchen screen as Server-Sent
+// Events until the client disconnects.
+func KitchenStream() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		events, unsubscribe := kitchenFeed.Subscribe()
+		defer unsubscribe()
+
+		ctx.Header("Cache-Control", "no-cache")
+		ctx.Header("Connection", "keep-alive")
+		ctx.Header("X-Accel-Buffering", "no")
+
+		heartbeat := time.NewTicker(kitchenHeartbeat)
+		defer heartbeat.Stop()
+
+		ctx.Stream(func(w io.Writer) bool {
+			select {
+			case event, ok := <-events:
+				// the screen fell behind and was cut off; ending the stream
+				// makes it reconnect and reload /kitchen/items
+				if !ok {
+					return false
+				}
+				ctx.SSEvent(strings.ToLower(event.Type), event)
+				return true
+			case <-heartbeat.C:
+				ctx.SSEvent("ping", gin.H{"at": time.Now()})
+				return true
+			case <-ctx.Request.Context().Done():
+				return false
+			}
+		})
+	}
+}
+
+// BumpOrderItem marks an order item as prepared and clears it from the
+// kitchen screens.
+func BumpOrderItem() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		orderItemId := ctx.Param("order_item_id")
+
+		orderItem, err := orderItemStore.Get(c, orderItemId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "order item was not found"})
+			return
+		}
+
+		if orderItem.CurrentKitchenStatus() != models.KitchenPending {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "only pending order items can be bumped", "kitchen_status": orderItem.CurrentKitchenStatus()})
+			return
+		}
+
+		Prepared_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		// a waiter may have changed or cancelled the item since it was read
+		orderItem, err = orderItemStore.Bump(c, orderItemId, ctx.GetString("uid"), Prepared_at)
+		if errors.Is(err, repository.ErrConflict) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "only pending order items can be bumped"})
+			return
+		}
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "order item update failed"})
+			return
+		}
+
+		kitchenFeed.Publish(kitchen.Event{Type: kitchen.EventUpdated, Order_item: orderItem})
+		ctx.JSON(http.StatusOK, orderItem)
+	}
+}
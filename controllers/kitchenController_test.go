@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/kitchen"
+	"github.com/tokha04/go-restautant-management/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func storedOrderItem(t *testing.T, orderId, kitchenStatus string) models.OrderItem {
+	t.Helper()
+
+	orderItem := models.OrderItem{ID: primitive.NewObjectID(), Order_id: orderId, Kitchen_status: kitchenStatus}
+	orderItem.Order_item_id = orderItem.ID.Hex()
+	if err := orderItemStore.CreateMany(context.Background(), []models.OrderItem{orderItem}); err != nil {
+		t.Fatal(err)
+	}
+	return orderItem
+}
+
+func TestBumpOrderItem(t *testing.T) {
+	useMemoryStores(t)
+	UseKitchenFeed(kitchen.NewBroker())
+	router := gin.New()
+	router.GET("/kitchen/items", GetKitchenItems())
+	router.POST("/kitchen/items/:order_item_id/bump", BumpOrderItem())
+
+	pending := storedOrderItem(t, "order", models.KitchenPending)
+	storedOrderItem(t, "order", models.KitchenCancelled)
+
+	var items []models.OrderItem
+	if code := perform(t, router, http.MethodGet, "/kitchen/items", nil, &items); code != http.StatusOK {
+		t.Fatalf("listing kitchen items returned %d, want 200", code)
+	}
+	if len(items) != 1 || items[0].Order_item_id != pending.Order_item_id {
+		t.Fatalf("the kitchen sees %+v, want the pending item only", items)
+	}
+
+	events, unsubscribe := kitchenFeed.Subscribe()
+	defer unsubscribe()
+
+	var bumped models.OrderItem
+	if code := perform(t, router, http.MethodPost, "/kitchen/items/"+pending.Order_item_id+"/bump", nil, &bumped); code != http.StatusOK {
+		t.Fatalf("bumping returned %d, want 200", code)
+	}
+	if bumped.Kitchen_status != models.KitchenPrepared || bumped.Prepared_at == nil {
+		t.Errorf("bumped item is %s prepared at %v, want it prepared", bumped.Kitchen_status, bumped.Prepared_at)
+	}
+	if event := <-events; event.Type != kitchen.EventUpdated || event.Order_item.Kitchen_status != models.KitchenPrepared {
+		t.Errorf("the kitchen heard %+v, want the prepared item", event)
+	}
+
+	if code := perform(t, router, http.MethodPost, "/kitchen/items/"+pending.Order_item_id+"/bump", nil, nil); code != http.StatusConflict {
+		t.Errorf("bumping twice returned %d, want 409", code)
+	}
+	if code := perform(t, router, http.MethodGet, "/kitchen/items", nil, &items); code != http.StatusOK || len(items) != 0 {
+		t.Errorf("the kitchen still sees %d item(s) after the bump", len(items))
+	}
+}
@@ -2,34 +2,26 @@ package controllers
 
 import (
 	"context"
-	"log"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/tokha04/go-restautant-management/database"
 	"github.com/tokha04/go-restautant-management/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/tokha04/go-restautant-management/repository"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var menuCollection *mongo.Collection = database.OpenCollection(database.Client, "menu")
+var menuStore repository.MenuStore
 
 func GetMenus() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-
-		res, err := menuCollection.Find(context.TODO(), bson.M{})
 		defer cancel()
+
+		allMenus, err := menuStore.List(c)
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing menu items"})
-		}
-
-		var allMenus []bson.M
-		if err = res.All(c, &allMenus); err != nil {
-			log.Fatal(err)
+			return
 		}
 
 		ctx.JSON(http.StatusOK, allMenus)
@@ -39,13 +31,12 @@ func GetMenus() gin.HandlerFunc {
 func GetMenu() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 		menuId := ctx.Param("menu_id")
-		var menu models.Menu
 
-		err := foodCollection.FindOne(c, bson.M{"menu_id": menuId}).Decode(&menu)
-		defer cancel()
+		menu, err := menuStore.Get(c, menuId)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the food item"})
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "error occured while fetching the menu item"})
 			return
 		}
 
@@ -56,6 +47,7 @@ func GetMenu() gin.HandlerFunc {
 func CreateMenu() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 		var menu models.Menu
 
 		if err := ctx.BindJSON(&menu); err != nil {
@@ -69,26 +61,29 @@ func CreateMenu() gin.HandlerFunc {
 			return
 		}
 
+		if status, body := checkMenuSchedule(menu); body != nil {
+			ctx.JSON(status, body)
+			return
+		}
+
 		menu.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		menu.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		menu.ID = primitive.NewObjectID()
 		menu.Menu_id = menu.ID.Hex()
 
-		res, insertErr := menuCollection.InsertOne(c, menu)
-		if insertErr != nil {
+		if err := menuStore.Create(c, menu); err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "menu item was not created"})
 			return
 		}
 
-		defer cancel()
-		ctx.JSON(http.StatusOK, res)
-		defer cancel()
+		ctx.JSON(http.StatusOK, menu)
 	}
 }
 
 func UpdateMenu() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 		menuId := ctx.Param("menu_id")
 		var menu models.Menu
 
@@ -97,53 +92,145 @@ func UpdateMenu() gin.HandlerFunc {
 			return
 		}
 
-		filter := bson.M{"menu_id": menuId}
-		var updateObj primitive.D
+		foundMenu, err := menuStore.Get(c, menuId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "menu was not found"})
+			return
+		}
+
+		if menu.Start_date != nil {
+			foundMenu.Start_date = menu.Start_date
+		}
+		if menu.End_date != nil {
+			foundMenu.End_date = menu.End_date
+		}
+		// an empty list clears the dayparts or seasons, leaving them out keeps them
+		if menu.Dayparts != nil {
+			foundMenu.Dayparts = menu.Dayparts
+		}
+		if menu.Seasons != nil {
+			foundMenu.Seasons = menu.Seasons
+		}
 
-		if menu.Start_date != nil && menu.End_date != nil {
-			if !inTimeSpan(*menu.Start_date, *menu.End_date, time.Now()) {
-				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "kindly retype the time"})
-				defer cancel()
-				return
-			}
+		if menu.Name != "" {
+			foundMenu.Name = menu.Name
+		}
+		if menu.Category != "" {
+			foundMenu.Category = menu.Category
+		}
 
-			updateObj = append(updateObj, bson.E{"start_date", menu.Start_date})
-			updateObj = append(updateObj, bson.E{"end_date", menu.End_date})
+		validationErr := validate.Struct(foundMenu)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
 
-			if menu.Name != "" {
-				updateObj = append(updateObj, bson.E{"name", menu.Name})
-			}
-			if menu.Category != "" {
-				updateObj = append(updateObj, bson.E{"category", menu.Category})
+		if status, body := checkMenuSchedule(foundMenu); body != nil {
+			ctx.JSON(status, body)
+			return
+		}
+
+		foundMenu.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := menuStore.Update(c, foundMenu); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "menu update failed"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, foundMenu)
+	}
+}
+
+// GetCurrentMenus resolves the menus being served and the food that can be
+// ordered from them, now or at the RFC3339 time given as at. Food can be
+// filtered as for GetFoods.
+func GetCurrentMenus() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		at := time.Now()
+		if value := ctx.Query("at"); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "at must be an RFC3339 time"})
+				return
 			}
+			at = t
+		}
+		at = at.In(restaurantTimezone)
 
-			menu.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-			updateObj = append(updateObj, bson.E{"updated_at", menu.Updated_at})
+		filter, err := foodFilter(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-			upsert := true
+		allMenus, err := menuStore.List(c)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing menu items"})
+			return
+		}
 
-			opt := options.UpdateOptions{
-				Upsert: &upsert,
+		current := models.CurrentMenus{At: at, Timezone: restaurantTimezone.String(), Menus: []models.AvailableMenu{}}
+		menuIds := []string{}
+		for _, menu := range allMenus {
+			daypart, active := menu.ActiveDaypart(at)
+			if !active {
+				continue
 			}
+			current.Menus = append(current.Menus, models.AvailableMenu{Menu: menu, Daypart: daypart, Foods: []models.Food{}})
+			menuIds = append(menuIds, menu.Menu_id)
+		}
 
-			res, err := menuCollection.UpdateOne(
-				c,
-				filter,
-				bson.D{
-					{"$set", updateObj},
-				},
-				&opt,
-			)
+		if len(menuIds) > 0 {
+			foods, err := foodStore.ListByMenus(c, menuIds, filter)
 			if err != nil {
-				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "menu update failed"})
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing food items"})
+				return
+			}
+			for _, food := range foods {
+				for i := range current.Menus {
+					if current.Menus[i].Menu_id == *food.Menu_id {
+						current.Menus[i].Foods = append(current.Menus[i].Foods, food)
+					}
+				}
 			}
+		}
 
-			defer cancel()
-			ctx.JSON(http.StatusOK, res)
+		ctx.JSON(http.StatusOK, current)
+	}
+}
+
+// checkMenuSchedule refuses a menu whose end date is not after its start
+// date, or with a daypart that starts when it ends.
+func checkMenuSchedule(menu models.Menu) (int, gin.H) {
+	if menu.Start_date != nil && menu.End_date != nil && !menu.End_date.After(*menu.Start_date) {
+		return http.StatusBadRequest, gin.H{"error": "end_date must be after start_date"}
+	}
+	for _, daypart := range menu.Dayparts {
+		if daypart.Start == daypart.End {
+			return http.StatusBadRequest, gin.H{"error": "daypart " + daypart.Name + " has to end at another time than it starts"}
 		}
 	}
+	return 0, nil
 }
 
-func inTimeSpan(start, end, check time.Time) bool {
-	return start.After(time.Now()) && end.After(start)
+// orderableFood looks up food that can be ordered at t, refusing food that
+// is not on a menu being served then.
+func orderableFood(c context.Context, foodId string, t time.Time) (models.Food, int, gin.H) {
+	food, err := foodStore.Get(c, foodId)
+	if err != nil {
+		return food, storeErrorStatus(err), gin.H{"error": "food was not found", "food_id": foodId}
+	}
+
+	menu, err := menuStore.Get(c, *food.Menu_id)
+	if err != nil {
+		return food, storeErrorStatus(err), gin.H{"error": "menu was not found", "menu_id": *food.Menu_id}
+	}
+
+	if !menu.IsActive(t.In(restaurantTimezone)) {
+		return food, http.StatusConflict, gin.H{"error": *food.Name + " is not being served now", "food_id": foodId, "menu_id": menu.Menu_id}
+	}
+	return food, 0, nil
 }
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/notify"
+)
+
+var notifier notify.Notifier = notify.NewBroker()
+
+// UseNotifier replaces the in-process notification broker. Background jobs
+// must notify through the same one for staff to hear about it.
+func UseNotifier(n notify.Notifier) {
+	notifier = n
+}
+
+// NotificationStream pushes notifications, such as overdue invoices, to a
+// staff screen as Server-Sent Events until the client disconnects.
+func NotificationStream() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		events, unsubscribe := notifier.Subscribe()
+		defer unsubscribe()
+
+		ctx.Header("Cache-Control", "no-cache")
+		ctx.Header("Connection", "keep-alive")
+		ctx.Header("X-Accel-Buffering", "no")
+
+		heartbeat := time.NewTicker(kitchenHeartbeat)
+		defer heartbeat.Stop()
+
+		ctx.Stream(func(w io.Writer) bool {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return false
+				}
+				ctx.SSEvent(strings.ToLower(event.Type), event)
+				return true
+			case <-heartbeat.C:
+				ctx.SSEvent("ping", gin.H{"at": time.Now()})
+				return true
+			case <-ctx.Request.Context().Done():
+				return false
+			}
+		})
+	}
+}
@@ -2,34 +2,36 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/tokha04/go-restautant-management/database"
+	"github.com/tokha04/go-restautant-management/kitchen"
 	"github.com/tokha04/go-restautant-management/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/tokha04/go-restautant-management/repository"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var orderCollection *mongo.Collection = database.OpenCollection(database.Client, "order")
+var orderStore repository.OrderStore
 
 func GetOrders() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-
-		res, err := orderCollection.Find(context.TODO(), bson.M{})
 		defer cancel()
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing order items"})
+
+		var statuses []string
+		if status := ctx.Query("status"); status != "" {
+			statuses = strings.Split(status, ",")
 		}
 
-		var allOrders []bson.M
-		if err = res.All(c, &allOrders); err != nil {
-			log.Fatal(err)
+		allOrders, err := orderStore.List(c, statuses...)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing order items"})
+			return
 		}
 
 		ctx.JSON(http.StatusOK, allOrders)
@@ -39,13 +41,12 @@ func GetOrders() gin.HandlerFunc {
 func GetOrder() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 		orderId := ctx.Param("order_id")
-		var order models.Order
 
-		err := orderCollection.FindOne(c, bson.M{"order_id": orderId}).Decode(&order)
-		defer cancel()
+		order, err := orderStore.Get(c, orderId)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the order item"})
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "error occured while fetching the order item"})
 			return
 		}
 
@@ -56,7 +57,7 @@ func GetOrder() gin.HandlerFunc {
 func CreateOrder() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-		var table models.Table
+		defer cancel()
 		var order models.Order
 
 		if err := ctx.BindJSON(&order); err != nil {
@@ -64,42 +65,48 @@ func CreateOrder() gin.HandlerFunc {
 			return
 		}
 
+		order.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		order.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
 		validationErr := validate.Struct(order)
 		if validationErr != nil {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
 			return
 		}
 
-		if order.Table_id != nil {
-			err := tableCollection.FindOne(c, bson.M{"table_id": order.Table_id}).Decode(&table)
-			defer cancel()
-			if err != nil {
-				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "table was not found"})
-				return
-			}
+		if _, err := tableStore.Get(c, *order.Table_id); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "table was not found"})
+			return
 		}
 
-		order.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		order.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		order.ID = primitive.NewObjectID()
 		order.Order_id = order.ID.Hex()
+		order.Status = models.OrderOpen
+		order.Status_history = []models.OrderStatusChange{newStatusChange("", models.OrderOpen, ctx.GetString("uid"))}
+
+		// the member of staff taking the order serves it unless told otherwise
+		if order.Server_id == nil {
+			uid := ctx.GetString("uid")
+			order.Server_id = &uid
+		} else if _, err := userStore.Get(c, *order.Server_id); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "server was not found"})
+			return
+		}
 
-		res, insertErr := orderCollection.InsertOne(c, order)
-		if insertErr != nil {
+		if err := orderStore.Create(c, order); err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "order item was not created"})
 			return
 		}
 
-		defer cancel()
-		ctx.JSON(http.StatusOK, res)
+		ctx.JSON(http.StatusOK, order)
 	}
 }
 
 func UpdateOrder() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 		orderId := ctx.Param("order_id")
-		var table models.Table
 		var order models.Order
 
 		if err := ctx.BindJSON(&order); err != nil {
@@ -107,56 +114,255 @@ func UpdateOrder() gin.HandlerFunc {
 			return
 		}
 
-		var updateObj primitive.D
+		foundOrder, err := orderStore.Get(c, orderId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "order was not found"})
+			return
+		}
 
 		if order.Table_id != nil {
-			err := menuCollection.FindOne(c, bson.M{"table_id": order.Table_id}).Decode(&table)
-			defer cancel()
-			if err != nil {
-				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "table was not found"})
+			if _, err := tableStore.Get(c, *order.Table_id); err != nil {
+				ctx.JSON(storeErrorStatus(err), gin.H{"error": "table was not found"})
 				return
 			}
 
-			// why???
-			updateObj = append(updateObj, bson.E{"menu", order.Table_id})
+			foundOrder.Table_id = order.Table_id
 		}
 
-		order.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		updateObj = append(updateObj, bson.E{"updated_at", order.Updated_at})
-
-		upsert := true
-		filter := bson.M{"order_id": orderId}
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
-		}
-
-		res, err := orderCollection.UpdateOne(
-			c,
-			filter,
-			bson.D{
-				{"$set", updateObj},
-			},
-			&opt,
-		)
+		if order.Server_id != nil {
+			if _, err := userStore.Get(c, *order.Server_id); err != nil {
+				ctx.JSON(storeErrorStatus(err), gin.H{"error": "server was not found"})
+				return
+			}
+
+			foundOrder.Server_id = order.Server_id
+		}
+
+		// allergies are replaced as a whole, an empty list clears them
+		if order.Allergies != nil || order.Seat_allergies != nil {
+			if order.Allergies != nil {
+				foundOrder.Allergies = order.Allergies
+			}
+			if order.Seat_allergies != nil {
+				foundOrder.Seat_allergies = order.Seat_allergies
+			}
+
+			validationErr := validate.StructPartial(foundOrder, "Allergies", "Seat_allergies")
+			if validationErr != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+
+			orderItems, err := orderItemStore.ListByOrder(c, orderId)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing order items"})
+				return
+			}
+			foundOrder.Allergen_warnings = foundOrder.AllergenWarnings(orderItems)
+			foundOrder.Allergen_warning = len(foundOrder.Allergen_warnings) > 0
+		}
+
+		foundOrder.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		// only what can be patched is written, so a status change made in
+		// the meantime is kept
+		updatedOrder, err := orderStore.UpdateDetails(c, foundOrder)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "order update failed"})
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "order update failed"})
 			return
 		}
 
+		ctx.JSON(http.StatusOK, updatedOrder)
+	}
+}
+
+// TransitionOrder returns a handler that moves an order to the given status,
+// rejecting moves the order lifecycle does not allow.
+func TransitionOrder(to string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
 		defer cancel()
-		ctx.JSON(http.StatusOK, res)
+		orderId := ctx.Param("order_id")
+
+		order, err := advanceOrder(c, orderId, to, ctx.GetString("uid"))
+		if err != nil {
+			var transitionErr *orderTransitionError
+			if errors.As(err, &transitionErr) {
+				ctx.JSON(http.StatusConflict, gin.H{
+					"error":   transitionErr.Error(),
+					"status":  transitionErr.from,
+					"allowed": models.NextOrderStatuses(transitionErr.from),
+				})
+				return
+			}
+
+			if errors.Is(err, errNotInvoiced) {
+				ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+
+			var unpaidErr *unpaidInvoicesError
+			if errors.As(err, &unpaidErr) {
+				ctx.JSON(http.StatusConflict, gin.H{"error": unpaidErr.Error(), "unpaid_invoices": unpaidErr.count})
+				return
+			}
+
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "order status update failed"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, order)
+	}
+}
+
+type orderTransitionError struct {
+	from, to string
+}
+
+func (e *orderTransitionError) Error() string {
+	return fmt.Sprintf("order cannot move from %s to %s", e.from, e.to)
+}
+
+var errNotInvoiced = errors.New("order has no invoice to pay")
+
+type unpaidInvoicesError struct {
+	count int
+}
+
+func (e *unpaidInvoicesError) Error() string {
+	return fmt.Sprintf("order still has %d unpaid invoice(s)", e.count)
+}
+
+// advanceOrder moves the order to status to on behalf of the user uid.
+func advanceOrder(c context.Context, orderId, to, uid string) (models.Order, error) {
+	order, err := orderStore.Get(c, orderId)
+	if err != nil {
+		return order, err
+	}
+
+	from := order.CurrentStatus()
+	if !models.CanTransitionOrder(from, to) {
+		return order, &orderTransitionError{from: from, to: to}
+	}
+
+	// an order is only paid or closed once it is invoiced and every
+	// invoice is paid
+	if to == models.OrderPaid || to == models.OrderClosed {
+		invoices, err := liveInvoices(c, orderId)
+		if err != nil {
+			return order, err
+		}
+		if len(invoices) == 0 {
+			return order, errNotInvoiced
+		}
+
+		unpaid, err := unpaidInvoices(c, orderId)
+		if err != nil {
+			return order, err
+		}
+		if unpaid > 0 {
+			return order, &unpaidInvoicesError{count: unpaid}
+		}
+	}
+
+	order, err = orderStore.Transition(c, orderId, from, newStatusChange(from, to, uid))
+	if err != nil || to != models.OrderCancelled {
+		return order, err
 	}
+
+	cancelKitchenTickets(c, orderId, order.Updated_at)
+	return order, nil
 }
 
-func OrderItemOrderCreator(order models.Order) string {
-	var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+// cancelKitchenTickets takes the items of a cancelled order the kitchen has
+// not prepared yet off its screens. The order is cancelled already, so an
+// item that cannot be cancelled is logged rather than failing the request.
+func cancelKitchenTickets(c context.Context, orderId string, cancelledAt time.Time) {
+	orderItems, err := orderItemStore.ListByOrder(c, orderId)
+	if err != nil {
+		log.Printf("could not cancel the kitchen tickets of order %s: %v", orderId, err)
+		return
+	}
+
+	for _, orderItem := range orderItems {
+		if orderItem.CurrentKitchenStatus() != models.KitchenPending {
+			continue
+		}
+
+		cancelled, err := orderItemStore.Cancel(c, orderItem.Order_item_id, cancelledAt)
+		if errors.Is(err, repository.ErrConflict) {
+			// bumped in the meantime
+			continue
+		}
+		if err != nil {
+			log.Printf("could not cancel order item %s of cancelled order %s: %v", orderItem.Order_item_id, orderId, err)
+			continue
+		}
+
+		kitchenFeed.Publish(kitchen.Event{Type: kitchen.EventCancelled, Order_item: cancelled})
+	}
+}
+
+func newStatusChange(from, to, uid string) models.OrderStatusChange {
+	Changed_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	return models.OrderStatusChange{From: from, To: to, Changed_by: uid, Changed_at: Changed_at}
+}
+
+func OrderItemOrderCreator(c context.Context, order models.Order) (string, error) {
 	order.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 	order.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 	order.ID = primitive.NewObjectID()
 	order.Order_id = order.ID.Hex()
+	order.Status = models.OrderOpen
+
+	if err := orderStore.Create(c, order); err != nil {
+		return "", err
+	}
 
-	orderCollection.InsertOne(c, order)
-	defer cancel()
+	return order.Order_id, nil
+}
+
+// checkOrderChangeable allows what is billed on an order, its items or
+// discounts, to change only while the order is running and not invoiced
+// yet; invoices keep the totals they were issued with.
+func checkOrderChangeable(c context.Context, orderId, what string) (int, gin.H) {
+	order, err := orderStore.Get(c, orderId)
+	if err != nil {
+		return storeErrorStatus(err), gin.H{"error": "order was not found"}
+	}
+
+	switch order.CurrentStatus() {
+	case models.OrderCancelled, models.OrderPaid, models.OrderClosed:
+		return http.StatusConflict, gin.H{"error": what + " cannot change on a finished order", "status": order.CurrentStatus()}
+	}
+
+	if order.Invoiced {
+		return http.StatusConflict, gin.H{"error": "order has already been invoiced"}
+	}
+
+	invoices, err := liveInvoices(c, orderId)
+	if err != nil {
+		return http.StatusInternalServerError, gin.H{"error": "error occured while listing the order invoices"}
+	}
+	if len(invoices) > 0 {
+		return http.StatusConflict, gin.H{"error": "order has already been invoiced", "invoice_count": len(invoices)}
+	}
+
+	return 0, nil
+}
+
+// refreshAllergenWarnings checks an order's items again after they changed.
+func refreshAllergenWarnings(c context.Context, orderId string) error {
+	order, err := orderStore.Get(c, orderId)
+	if err != nil {
+		return err
+	}
+
+	orderItems, err := orderItemStore.ListByOrder(c, orderId)
+	if err != nil {
+		return err
+	}
 
-	return order.Order_id
+	_, err = orderStore.SetAllergenWarnings(c, orderId, order.AllergenWarnings(orderItems))
+	return err
 }
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/kitchen"
+	"github.com/tokha04/go-restautant-management/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func storedOrder(t *testing.T, status string) models.Order {
+	t.Helper()
+
+	order := models.Order{ID: primitive.NewObjectID(), Table_id: ptr("table"), Status: status}
+	order.Order_id = order.ID.Hex()
+	if err := orderStore.Create(context.Background(), order); err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+func storedInvoice(t *testing.T, orderId, status string) models.Invoice {
+	t.Helper()
+
+	invoice := models.Invoice{ID: primitive.NewObjectID(), Order_id: orderId, Payment_status: ptr(status)}
+	invoice.Invoice_id = invoice.ID.Hex()
+	if err := invoiceStore.Create(context.Background(), invoice); err != nil {
+		t.Fatal(err)
+	}
+	return invoice
+}
+
+func transitionRouter() *gin.Engine {
+	router := gin.New()
+	router.POST("/orders/:order_id/send", TransitionOrder(models.OrderSentToKitchen))
+	router.POST("/orders/:order_id/ready", TransitionOrder(models.OrderReady))
+	router.POST("/orders/:order_id/serve", TransitionOrder(models.OrderServed))
+	router.POST("/orders/:order_id/pay", TransitionOrder(models.OrderPaid))
+	router.POST("/orders/:order_id/close", TransitionOrder(models.OrderClosed))
+	router.POST("/orders/:order_id/cancel", TransitionOrder(models.OrderCancelled))
+	return router
+}
+
+func TestTransitionOrderLifecycle(t *testing.T) {
+	useMemoryStores(t)
+	router := transitionRouter()
+	order := storedOrder(t, "")
+
+	for _, step := range []string{"send", "ready", "serve"} {
+		if code := perform(t, router, http.MethodPost, "/orders/"+order.Order_id+"/"+step, nil, &order); code != http.StatusOK {
+			t.Fatalf("%s returned %d, want 200", step, code)
+		}
+	}
+	if order.Status != models.OrderServed {
+		t.Fatalf("order is %s, want %s", order.Status, models.OrderServed)
+	}
+	if len(order.Status_history) != 3 || order.Status_history[0].From != models.OrderOpen {
+		t.Errorf("status history is %+v, want three changes from %s", order.Status_history, models.OrderOpen)
+	}
+
+	// a served order cannot go back to the kitchen or be cancelled
+	for _, step := range []string{"send", "cancel"} {
+		if code := perform(t, router, http.MethodPost, "/orders/"+order.Order_id+"/"+step, nil, nil); code != http.StatusConflict {
+			t.Errorf("%s on a served order returned %d, want 409", step, code)
+		}
+	}
+}
+
+func TestTransitionOrderNeedsPaidInvoices(t *testing.T) {
+	useMemoryStores(t)
+	router := transitionRouter()
+	order := storedOrder(t, models.OrderServed)
+	pay := "/orders/" + order.Order_id + "/pay"
+
+	if _, err := advanceOrder(context.Background(), order.Order_id, models.OrderPaid, "uid"); !errors.Is(err, errNotInvoiced) {
+		t.Fatalf("paying an order without invoices returned %v, want errNotInvoiced", err)
+	}
+
+	// voided invoices do not count
+	storedInvoice(t, order.Order_id, models.InvoiceVoided)
+	if code := perform(t, router, http.MethodPost, pay, nil, nil); code != http.StatusConflict {
+		t.Fatalf("paying an order with only a voided invoice returned %d, want 409", code)
+	}
+
+	invoice := storedInvoice(t, order.Order_id, models.InvoicePartiallyPaid)
+	_, err := advanceOrder(context.Background(), order.Order_id, models.OrderPaid, "uid")
+	var unpaidErr *unpaidInvoicesError
+	if !errors.As(err, &unpaidErr) || unpaidErr.count != 1 {
+		t.Fatalf("paying an order with an unpaid invoice returned %v, want one unpaid invoice", err)
+	}
+
+	invoice.Payment_status = ptr(models.InvoicePaid)
+	if _, err := invoiceStore.UpdateSettlement(context.Background(), invoice, models.InvoicePartiallyPaid); err != nil {
+		t.Fatal(err)
+	}
+	if code := perform(t, router, http.MethodPost, pay, nil, &order); code != http.StatusOK {
+		t.Fatalf("paying an invoiced order returned %d, want 200", code)
+	}
+	if order.Status != models.OrderPaid {
+		t.Errorf("order is %s, want %s", order.Status, models.OrderPaid)
+	}
+
+	if code := perform(t, router, http.MethodPost, "/orders/"+order.Order_id+"/close", nil, &order); code != http.StatusOK {
+		t.Fatalf("closing a paid order returned %d, want 200", code)
+	}
+	if order.Status != models.OrderClosed {
+		t.Errorf("order is %s, want %s", order.Status, models.OrderClosed)
+	}
+}
+
+func TestTransitionOrderFromStaleStatus(t *testing.T) {
+	useMemoryStores(t)
+	order := storedOrder(t, models.OrderOpen)
+
+	// another request moved the order since it was read
+	if _, err := orderStore.Transition(context.Background(), order.Order_id, models.OrderOpen, newStatusChange(models.OrderOpen, models.OrderCancelled, "uid")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orderStore.Transition(context.Background(), order.Order_id, models.OrderOpen, newStatusChange(models.OrderOpen, models.OrderSentToKitchen, "uid")); storeErrorStatus(err) != http.StatusConflict {
+		t.Errorf("moving an order from a stale status returned %v, want a conflict", err)
+	}
+}
+
+func TestCancelOrderCancelsKitchenTickets(t *testing.T) {
+	useMemoryStores(t)
+	UseKitchenFeed(kitchen.NewBroker())
+	order := storedOrder(t, models.OrderSentToKitchen)
+
+	pending := models.OrderItem{ID: primitive.NewObjectID(), Order_id: order.Order_id, Kitchen_status: models.KitchenPending}
+	pending.Order_item_id = pending.ID.Hex()
+	legacy := models.OrderItem{ID: primitive.NewObjectID(), Order_id: order.Order_id}
+	legacy.Order_item_id = legacy.ID.Hex()
+	prepared := models.OrderItem{ID: primitive.NewObjectID(), Order_id: order.Order_id, Kitchen_status: models.KitchenPrepared}
+	prepared.Order_item_id = prepared.ID.Hex()
+	if err := orderItemStore.CreateMany(context.Background(), []models.OrderItem{pending, legacy, prepared}); err != nil {
+		t.Fatal(err)
+	}
+
+	events, unsubscribe := kitchenFeed.Subscribe()
+	defer unsubscribe()
+
+	if code := perform(t, transitionRouter(), http.MethodPost, "/orders/"+order.Order_id+"/cancel", nil, &order); code != http.StatusOK {
+		t.Fatalf("cancelling returned %d, want 200", code)
+	}
+
+	cancelled := map[string]bool{}
+	for range 2 {
+		select {
+		case event := <-events:
+			if event.Type != kitchen.EventCancelled {
+				t.Errorf("got a %s event, want %s", event.Type, kitchen.EventCancelled)
+			}
+			cancelled[event.Order_item.Order_item_id] = true
+		case <-time.After(time.Second):
+			t.Fatalf("the kitchen heard of %d cancelled item(s), want 2", len(cancelled))
+		}
+	}
+	if !cancelled[pending.Order_item_id] || !cancelled[legacy.Order_item_id] {
+		t.Errorf("the kitchen heard of %v, want the pending items", cancelled)
+	}
+
+	still, err := orderItemStore.ListPending(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(still) != 0 {
+		t.Errorf("%d item(s) of the cancelled order are still on the kitchen's list", len(still))
+	}
+
+	item, err := orderItemStore.Get(context.Background(), prepared.Order_item_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Kitchen_status != models.KitchenPrepared {
+		t.Errorf("the prepared item is %s, want it left %s", item.Kitchen_status, models.KitchenPrepared)
+	}
+}
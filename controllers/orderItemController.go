@@ -2,39 +2,38 @@ package controllers
 
 import (
 	"context"
-	"log"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/tokha04/go-restautant-management/database"
+	"github.com/tokha04/go-restautant-management/billing"
+	"github.com/tokha04/go-restautant-management/kitchen"
 	"github.com/tokha04/go-restautant-management/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/tokha04/go-restautant-management/repository"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// OrderItemPack opens an order for a table with its items and the
+// allergies noted for the table and its guests.
 type OrderItemPack struct {
-	Table_id    *string
-	Order_items []models.OrderItem
+	Table_id       *string
+	Order_items    []models.OrderItem
+	Allergies      []string               `json:"allergies" validate:"dive,allergen"`
+	Seat_allergies []models.SeatAllergies `json:"seat_allergies" validate:"dive"`
 }
 
-var orderItemCollection *mongo.Collection = database.OpenCollection(database.Client, "orderItem")
+var orderItemStore repository.OrderItemStore
 
 func GetOrderItems() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-
-		res, err := orderItemCollection.Find(context.TODO(), bson.M{})
 		defer cancel()
+
+		allOrderItems, err := orderItemStore.List(c)
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing ordered items"})
-		}
-
-		var allOrderItems []bson.M
-		if err = res.All(c, &allOrderItems); err != nil {
-			log.Fatal(err)
+			return
 		}
 
 		ctx.JSON(http.StatusOK, allOrderItems)
@@ -44,13 +43,12 @@ func GetOrderItems() gin.HandlerFunc {
 func GetOrderItem() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 		orderItemId := ctx.Param("order_item_id")
-		var orderItem models.OrderItem
 
-		err := orderItemCollection.FindOne(c, bson.M{"orderItem_id": orderItemId}).Decode(&orderItem)
-		defer cancel()
+		orderItem, err := orderItemStore.Get(c, orderItemId)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the ordered item"})
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "error occured while fetching the ordered item"})
 			return
 		}
 
@@ -65,88 +63,24 @@ func GetOrderItemsByOrder() gin.HandlerFunc {
 		allOrderItems, err := ItemsByOrder(orderId)
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing order items by orderId"})
+			return
 		}
 
 		ctx.JSON(http.StatusOK, allOrderItems)
 	}
 }
 
-func ItemsByOrder(id string) (OrderItems []primitive.M, err error) {
+func ItemsByOrder(id string) (models.OrderSummary, error) {
 	var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-
-	matchStage := bson.D{{"$match", bson.D{{"order_id", id}}}}
-	lookupStage := bson.D{{"$lookup", bson.D{{"from", "food"}, {"localField", "food_id"}, {"foreignField", "food_id"}, {"as", "food"}}}}
-	unwindStage := bson.D{{"$unwind", bson.D{{"path", "$food"}, {"preserveNullAndEmptyArrays", true}}}}
-
-	lookupOrderStage := bson.D{{"$lookup", bson.D{{"from", "order"}, {"localField", "order_id"}, {"foreignField", "order_id"}, {"as", "order"}}}}
-	unwindOrderStage := bson.D{{"$unwind", bson.D{{"path", "$order"}, {"preserveNullAndEmptyArrays", true}}}}
-
-	lookupTableStage := bson.D{{"$lookup", bson.D{{"from", "table"}, {"localField", "order.table_id"}, {"foreignField", "table_id"}, {"as", "table"}}}}
-	unwindTableStage := bson.D{{"$unwind", bson.D{{"path", "$table"}, {"preserveNullAndEmptyArrays", true}}}}
-
-	projectStage := bson.D{
-		{
-			"$project", bson.D{
-				{"id", 0},
-				{"amount", "$food_price"},
-				{"total_count", 1},
-				{"food_name", "$food.name"},
-				{"food_image", "$food.food_image"},
-				{"table_number", "$table.table_number"},
-				{"table_id", "$table.table_id"},
-				{"order_id", "$order.order_id"},
-				{"price", "$food.price"},
-				{"quantity", 1},
-			},
-		},
-	}
-
-	groupStage := bson.D{{"$group", bson.D{{"_id", bson.D{{"order_id", "$order"}, {"table_id", "$table_id"}, {"table_number", "$table_number"}}}, {"payment_due", bson.D{{"$sum", "$amount"}}}, {"total_count", bson.D{{"$sum", 1}}}, {"order_items", bson.D{{"$push", "$$ROOT"}}}}}}
-
-	projectStage2 := bson.D{
-		{
-			"$project", bson.D{
-				{"id", 0},
-				{"payment_due", 1},
-				{"total_count", 1},
-				{"total_number", "$_id.table_number"},
-				{"food_image", "$food.food_image"},
-				{"order_items", 1},
-				{"table_id", "$table.table_id"},
-				{"order_id", "$order.order_id"},
-				{"price", "$food.price"},
-				{"quantity", 1},
-			},
-		},
-	}
-
-	res, err := orderItemCollection.Aggregate(c, mongo.Pipeline{
-		matchStage,
-		lookupStage,
-		unwindStage,
-		lookupOrderStage,
-		unwindOrderStage,
-		lookupTableStage,
-		unwindTableStage,
-		projectStage,
-		groupStage,
-		projectStage2,
-	})
-	if err != nil {
-		panic(err)
-	}
-
-	if err = res.All(c, &OrderItems); err != nil {
-		panic(err)
-	}
-
 	defer cancel()
-	return OrderItems, err
+
+	return orderItemStore.ItemsByOrder(c, id)
 }
 
 func CreateOrderItem() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 		var orderItemPack OrderItemPack
 		var order models.Order
 
@@ -155,83 +89,266 @@ func CreateOrderItem() gin.HandlerFunc {
 			return
 		}
 
-		order.Order_date, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-
-		orderItemsToBeInserted := []interface{}{}
-		order.Table_id = orderItemPack.Table_id
-		order_id := OrderItemOrderCreator(order)
+		validationErr := validate.Struct(orderItemPack)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
 
+		// the order id is assigned below, so validate and price everything
+		// first to avoid leaving an empty order behind
+		pricedOrderItems := []models.OrderItem{}
 		for _, orderItem := range orderItemPack.Order_items {
-			orderItem.Order_id = order_id
-
-			validationErr := validate.Struct(orderItem)
+			validationErr := validate.StructExcept(orderItem, "Order_id", "Unit_price")
 			if validationErr != nil {
 				ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
 				return
 			}
 
+			food, status, body := orderableFood(c, *orderItem.Food_id, time.Now())
+			if body != nil {
+				ctx.JSON(status, body)
+				return
+			}
+
+			if status, body := priceOrderItem(&orderItem, food); body != nil {
+				ctx.JSON(status, body)
+				return
+			}
+			orderItem.Allergens = orderItemAllergens(food, orderItem.Modifiers)
+			pricedOrderItems = append(pricedOrderItems, orderItem)
+		}
+
+		order.Order_date, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		order.Table_id = orderItemPack.Table_id
+		order.Allergies = orderItemPack.Allergies
+		order.Seat_allergies = orderItemPack.Seat_allergies
+		order.Status_history = []models.OrderStatusChange{newStatusChange("", models.OrderOpen, ctx.GetString("uid"))}
+		uid := ctx.GetString("uid")
+		order.Server_id = &uid
+
+		order_id, err := OrderItemOrderCreator(c, order)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "order was not created"})
+			return
+		}
+
+		orderItemsToBeInserted := []models.OrderItem{}
+		for _, orderItem := range pricedOrderItems {
+			orderItem.Order_id = order_id
 			orderItem.ID = primitive.NewObjectID()
 			orderItem.Order_item_id = orderItem.ID.Hex()
 			orderItem.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 			orderItem.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-			var num = toFixed(*orderItem.Unit_price, 2)
-			orderItem.Unit_price = &num
+			orderItem.Kitchen_status = models.KitchenPending
+			if orderItem.Count == nil {
+				count := 1
+				orderItem.Count = &count
+			}
+			if orderItem.Notes != nil && *orderItem.Notes == "" {
+				orderItem.Notes = nil
+			}
 			orderItemsToBeInserted = append(orderItemsToBeInserted, orderItem)
 		}
 
-		insertedOrderItems, err := orderItemCollection.InsertMany(c, orderItemsToBeInserted)
-		if err != nil {
-			log.Fatal(err)
+		if err := orderItemStore.CreateMany(c, orderItemsToBeInserted); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "order items were not created"})
+			return
 		}
 
-		defer cancel()
-		ctx.JSON(http.StatusOK, insertedOrderItems)
+		if _, err := orderStore.SetAllergenWarnings(c, order_id, order.AllergenWarnings(orderItemsToBeInserted)); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "allergen warnings were not recorded"})
+			return
+		}
+
+		for _, orderItem := range orderItemsToBeInserted {
+			kitchenFeed.Publish(kitchen.Event{Type: kitchen.EventCreated, Order_item: orderItem})
+		}
+
+		ctx.JSON(http.StatusOK, orderItemsToBeInserted)
 	}
 }
 
 func UpdateOrderItem() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 		orderItemId := ctx.Param("order_item_id")
 		var orderItem models.OrderItem
 
-		var updateObj primitive.D
+		if err := ctx.BindJSON(&orderItem); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		foundOrderItem, err := orderItemStore.Get(c, orderItemId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "order item was not found"})
+			return
+		}
 
-		if orderItem.Unit_price != nil {
-			updateObj = append(updateObj, bson.E{"unit_price", orderItem.Unit_price})
+		if status, body := checkOrderChangeable(c, foundOrderItem.Order_id, "order items"); body != nil {
+			ctx.JSON(status, body)
+			return
 		}
 
 		if orderItem.Quantity != nil {
-			updateObj = append(updateObj, bson.E{"quantity", orderItem.Quantity})
+			foundOrderItem.Quantity = orderItem.Quantity
 		}
 
-		if orderItem.Food_id != nil {
-			updateObj = append(updateObj, bson.E{"food_id", orderItem.Food_id})
+		if orderItem.Count != nil {
+			foundOrderItem.Count = orderItem.Count
 		}
 
-		orderItem.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		updateObj = append(updateObj, bson.E{"updated_at", orderItem.Updated_at})
+		// empty notes and seat 0 clear them
+		if orderItem.Notes != nil {
+			foundOrderItem.Notes = orderItem.Notes
+			if *orderItem.Notes == "" {
+				foundOrderItem.Notes = nil
+			}
+		}
+		if orderItem.Seat != nil {
+			foundOrderItem.Seat = orderItem.Seat
+			if *orderItem.Seat == 0 {
+				foundOrderItem.Seat = nil
+			}
+		}
+
+		// the modifiers are replaced as a whole, an empty list removes them
+		if orderItem.Modifiers != nil {
+			foundOrderItem.Modifiers = orderItem.Modifiers
+		}
+
+		// an empty variant goes back to the food ordered plainly
+		if orderItem.Variant != nil {
+			foundOrderItem.Variant = orderItem.Variant
+			if *orderItem.Variant == "" {
+				foundOrderItem.Variant = nil
+			}
+		}
+
+		if orderItem.Food_id != nil && (foundOrderItem.Food_id == nil || *orderItem.Food_id != *foundOrderItem.Food_id) {
+			if _, status, body := orderableFood(c, *orderItem.Food_id, time.Now()); body != nil {
+				ctx.JSON(status, body)
+				return
+			}
+			foundOrderItem.Food_id = orderItem.Food_id
+		}
+
+		foundOrderItem.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
-		upsert := true
-		filter := bson.M{"order_item_id": orderItemId}
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
+		validationErr := validate.StructExcept(foundOrderItem, "Unit_price")
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
 		}
 
-		res, err := orderItemCollection.UpdateOne(
-			c,
-			filter,
-			bson.D{
-				{"$set", updateObj},
-			},
-			&opt,
-		)
+		food, err := foodStore.Get(c, *foundOrderItem.Food_id)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "order item update failed"})
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "food was not found", "food_id": *foundOrderItem.Food_id})
+			return
+		}
+
+		if status, body := priceOrderItem(&foundOrderItem, food); body != nil {
+			ctx.JSON(status, body)
 			return
 		}
+		foundOrderItem.Allergens = orderItemAllergens(food, foundOrderItem.Modifiers)
 
+		if err := orderItemStore.Update(c, foundOrderItem); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "order item update failed"})
+			return
+		}
+
+		if err := refreshAllergenWarnings(c, foundOrderItem.Order_id); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "allergen warnings were not recorded"})
+			return
+		}
+
+		kitchenFeed.Publish(kitchen.Event{Type: kitchen.EventUpdated, Order_item: foundOrderItem})
+		ctx.JSON(http.StatusOK, foundOrderItem)
+	}
+}
+
+func CancelOrderItem() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
 		defer cancel()
-		ctx.JSON(http.StatusOK, res)
+		orderItemId := ctx.Param("order_item_id")
+
+		orderItem, err := orderItemStore.Get(c, orderItemId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "order item was not found"})
+			return
+		}
+
+		if orderItem.CurrentKitchenStatus() == models.KitchenCancelled {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "order item is already cancelled"})
+			return
+		}
+
+		if status, body := checkOrderChangeable(c, orderItem.Order_id, "order items"); body != nil {
+			ctx.JSON(status, body)
+			return
+		}
+
+		Cancelled_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		orderItem.Kitchen_status = models.KitchenCancelled
+		orderItem.Cancelled_at = &Cancelled_at
+		orderItem.Updated_at = Cancelled_at
+
+		if err := orderItemStore.Update(c, orderItem); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "order item update failed"})
+			return
+		}
+
+		if err := refreshAllergenWarnings(c, orderItem.Order_id); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "allergen warnings were not recorded"})
+			return
+		}
+
+		kitchenFeed.Publish(kitchen.Event{Type: kitchen.EventCancelled, Order_item: orderItem})
+		ctx.JSON(http.StatusOK, orderItem)
+	}
+}
+
+// priceOrderItem sets what the order item costs from its food, size,
+// variant and modifiers, checking the modifiers against the food's groups.
+// Whatever prices the client sent are ignored.
+func priceOrderItem(orderItem *models.OrderItem, food models.Food) (int, gin.H) {
+	variant := ""
+	if orderItem.Variant != nil {
+		variant = *orderItem.Variant
+	}
+
+	price, err := billing.UnitPrice(food, variant, *orderItem.Quantity)
+	if err != nil {
+		return http.StatusBadRequest, gin.H{"error": err.Error(), "food_id": food.Food_id}
+	}
+
+	modifiers, extra, err := billing.ChooseModifiers(food, orderItem.Modifiers)
+	if err != nil {
+		return http.StatusBadRequest, gin.H{"error": err.Error(), "food_id": food.Food_id}
+	}
+	price = price.Add(extra)
+	if price.IsNegative() {
+		return http.StatusBadRequest, gin.H{"error": "modifiers take the price below zero", "food_id": food.Food_id}
+	}
+
+	orderItem.Modifiers = modifiers
+	orderItem.Unit_price = &price
+	return 0, nil
+}
+
+// orderItemAllergens lists the allergens in food ordered with modifiers.
+func orderItemAllergens(food models.Food, modifiers []models.OrderModifier) []string {
+	allergens := append([]string{}, food.Allergens...)
+	for _, modifier := range modifiers {
+		for _, allergen := range modifier.Allergens {
+			if !slices.Contains(allergens, allergen) {
+				allergens = append(allergens, allergen)
+			}
+		}
 	}
+	return allergens
 }
@@ -0,0 +1,646 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/billing"
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+	"github.com/tokha04/go-restautant-management/payments"
+	"github.com/tokha04/go-restautant-management/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const cashProvider = "cash"
+
+var paymentStore repository.PaymentStore
+var paymentProvider payments.Provider
+var webhookSecret []byte
+
+// UsePayments sets the card processor and the secret its webhooks are signed
+// with.
+func UsePayments(provider payments.Provider, secret []byte) {
+	paymentProvider = provider
+	webhookSecret = secret
+}
+
+// cardsEnabled reports whether a card processor is configured.
+func cardsEnabled() bool {
+	_, disabled := paymentProvider.(payments.DisabledProvider)
+	return !disabled
+}
+
+// providerFailure is the status and error answered when the card processor
+// fails: 503 when card payments are disabled, 502 when it is unreachable.
+func providerFailure(err error) (int, string) {
+	if errors.Is(err, payments.ErrDisabled) {
+		return http.StatusServiceUnavailable, "card payments are disabled, only cash can be taken"
+	}
+	return http.StatusBadGateway, "payment provider is unavailable"
+}
+
+func GetInvoicePayments() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		invoiceId := ctx.Param("invoice_id")
+
+		if _, err := invoiceStore.Get(c, invoiceId); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "invoice was not found"})
+			return
+		}
+
+		allPayments, err := paymentStore.ListByInvoice(c, invoiceId)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing payments"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, allPayments)
+	}
+}
+
+// CreatePayment charges the invoice total. Cash is recorded as captured by
+// the cashier; cards are authorized and captured through the provider, which
+// may also answer PENDING and confirm through the webhook later.
+func CreatePayment() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		invoiceId := ctx.Param("invoice_id")
+		var payment models.Payment
+
+		if err := ctx.BindJSON(&payment); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := validate.Struct(payment)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if *payment.Method == models.PaymentCard && !cardsEnabled() {
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "card payments are disabled, only cash can be taken"})
+			return
+		}
+
+		if status, body := checkDayOpen(c, time.Now()); body != nil {
+			ctx.JSON(status, body)
+			return
+		}
+
+		invoice, err := invoiceStore.Get(c, invoiceId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "invoice was not found"})
+			return
+		}
+
+		if invoice.IsVoided() {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "invoice has been voided"})
+			return
+		}
+
+		existing, err := paymentStore.ListByInvoice(c, invoiceId)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing payments"})
+			return
+		}
+		for _, other := range existing {
+			if other.Status == models.PaymentPending || other.Status == models.PaymentAuthorized {
+				ctx.JSON(http.StatusConflict, gin.H{"error": "invoice has a payment in progress", "payment_id": other.Payment_id})
+				return
+			}
+		}
+
+		creditNotes, err := creditNoteStore.ListByInvoice(c, invoiceId)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing credit notes"})
+			return
+		}
+
+		billing.Settle(&invoice, existing, creditNotes)
+		if invoice.Balance.Minor <= 0 {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "invoice has no outstanding balance", "status": invoice.Payment_status})
+			return
+		}
+
+		// without an amount the payment settles the whole balance
+		if payment.Amount.IsZero() {
+			payment.Amount = invoice.Balance
+		}
+		if payment.Amount.IsNegative() || payment.Amount.Minor > invoice.Balance.Minor {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "amount must be positive and at most the outstanding balance", "balance": invoice.Balance})
+			return
+		}
+
+		if payment.Tip.IsNegative() {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "tip cannot be negative"})
+			return
+		}
+		if payment.Tip.Currency == "" {
+			payment.Tip = money.Zero()
+		}
+
+		// tips go to whoever served the order, falling back to the member of
+		// staff taking the payment
+		if payment.Tip_recipient == "" {
+			payment.Tip_recipient = ctx.GetString("uid")
+			if order, err := orderStore.Get(c, invoice.Order_id); err == nil && order.Server_id != nil {
+				payment.Tip_recipient = *order.Server_id
+			}
+		} else if _, err := userStore.Get(c, payment.Tip_recipient); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "tip recipient was not found"})
+			return
+		}
+
+		if shift, err := shiftStore.GetOpenByUser(c, payment.Tip_recipient); err == nil {
+			payment.Shift_id = shift.Shift_id
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while looking up the shift"})
+			return
+		}
+
+		drawerId, status, body := paymentDrawer(c, ctx.GetString("uid"), payment.Drawer_id)
+		if body != nil {
+			ctx.JSON(status, body)
+			return
+		}
+		payment.Drawer_id = drawerId
+
+		payment.ID = primitive.NewObjectID()
+		payment.Payment_id = payment.ID.Hex()
+		payment.Invoice_id = invoiceId
+		payment.Refunded = money.Zero()
+		payment.Created_by = ctx.GetString("uid")
+		payment.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		payment.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		payment.Events = []models.PaymentEvent{}
+		payment.Status = models.PaymentPending
+
+		// the payment holds the balance while pending, before anything is
+		// charged, so two cashiers cannot both take it
+		if err := paymentStore.Create(c, payment); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "payment was not recorded"})
+			return
+		}
+
+		if lost, err := lostPaymentRace(c, invoice, payment); err != nil || lost {
+			recordPaymentEvent(&payment, "", "authorize", models.PaymentFailed, "another payment was taken on the invoice at the same time")
+			paymentStore.Update(c, payment)
+			ctx.JSON(http.StatusConflict, gin.H{"error": "another payment was taken on the invoice at the same time"})
+			return
+		}
+
+		providerErr := chargePayment(c, &payment)
+		payment.Card_token = ""
+
+		if err := paymentStore.Update(c, payment); err != nil {
+			log.Printf("payment %s for invoice %s reached status %s but was not recorded: %v", payment.Reference, invoiceId, payment.Status, err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "payment was not recorded"})
+			return
+		}
+
+		if providerErr != nil {
+			status, reason := providerFailure(providerErr)
+			ctx.JSON(status, gin.H{"error": reason, "payment": payment})
+			return
+		}
+
+		if _, err := refreshInvoice(c, invoiceId, ctx.GetString("uid")); err != nil {
+			log.Printf("payment %s is %s but invoice %s could not be updated: %v", payment.Payment_id, payment.Status, invoiceId, err)
+		}
+
+		if payment.Status == models.PaymentFailed {
+			ctx.JSON(http.StatusPaymentRequired, payment)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, payment)
+	}
+}
+
+// lostPaymentRace reports whether the payment, already stored as pending,
+// has to give way: another payment on the invoice is in progress, or what was
+// captured since the balance was read leaves less than its amount to pay.
+// Two payments racing each other both give way rather than both charge.
+func lostPaymentRace(c context.Context, invoice models.Invoice, payment models.Payment) (bool, error) {
+	invoicePayments, err := paymentStore.ListByInvoice(c, invoice.Invoice_id)
+	if err != nil {
+		return false, err
+	}
+
+	others := []models.Payment{}
+	for _, other := range invoicePayments {
+		if other.Payment_id == payment.Payment_id {
+			continue
+		}
+		if other.Status == models.PaymentPending || other.Status == models.PaymentAuthorized {
+			return true, nil
+		}
+		others = append(others, other)
+	}
+
+	creditNotes, err := creditNoteStore.ListByInvoice(c, invoice.Invoice_id)
+	if err != nil {
+		return false, err
+	}
+
+	billing.Settle(&invoice, others, creditNotes)
+	return payment.Amount.Minor > invoice.Balance.Minor, nil
+}
+
+// chargePayment runs the payment through cash handling or the provider and
+// leaves the outcome on payment. The tip is charged together with the amount.
+// A provider error fails the payment.
+func chargePayment(c context.Context, payment *models.Payment) error {
+	charge := payment.Amount.Add(payment.Tip)
+
+	if *payment.Method == models.PaymentCash {
+		payment.Provider = cashProvider
+		payment.Reference = payment.Payment_id
+		recordPaymentEvent(payment, "", "capture", models.PaymentCaptured, "cash received")
+		return nil
+	}
+
+	payment.Provider = paymentProvider.Name()
+
+	result, err := paymentProvider.Authorize(c, payments.AuthorizeRequest{
+		Payment_id: payment.Payment_id,
+		Invoice_id: payment.Invoice_id,
+		Amount:     charge,
+		Card_token: payment.Card_token,
+	})
+	if err != nil {
+		recordPaymentEvent(payment, "", "authorize", models.PaymentFailed, err.Error())
+		return err
+	}
+	payment.Reference = result.Reference
+	recordPaymentEvent(payment, "", "authorize", result.Status, result.Message)
+
+	if result.Status != models.PaymentAuthorized {
+		return nil
+	}
+
+	result, err = paymentProvider.Capture(c, payment.Reference, charge)
+	if err != nil {
+		recordPaymentEvent(payment, "", "capture", models.PaymentFailed, err.Error())
+		return err
+	}
+	recordPaymentEvent(payment, "", "capture", result.Status, result.Message)
+
+	return nil
+}
+
+// recordPaymentEvent appends an event and moves the payment to its status.
+func recordPaymentEvent(payment *models.Payment, eventId, eventType, status, message string) {
+	at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	payment.Events = append(payment.Events, models.PaymentEvent{
+		Event_id: eventId,
+		Type:     eventType,
+		Status:   status,
+		Message:  message,
+		At:       at,
+	})
+	payment.Status = status
+	payment.Updated_at = at
+	if status == models.PaymentFailed {
+		payment.Failure_reason = message
+	}
+}
+
+// refreshInvoice settles the invoice against its payments and credit notes
+// and stores the result. Only the settled fields are written, and only while
+// the invoice keeps the status it was settled from, so a void or another
+// refresh in the meantime makes it settle again. The order is paid once its
+// last open invoice is.
+func refreshInvoice(c context.Context, invoiceId string, uid string) (models.Invoice, error) {
+	for attempt := 0; attempt < 3; attempt++ {
+		invoice, err := invoiceStore.Get(c, invoiceId)
+		if err != nil {
+			return invoice, err
+		}
+		from := invoice.StoredStatus()
+
+		invoicePayments, err := paymentStore.ListByInvoice(c, invoiceId)
+		if err != nil {
+			return invoice, err
+		}
+
+		creditNotes, err := creditNoteStore.ListByInvoice(c, invoiceId)
+		if err != nil {
+			return invoice, err
+		}
+
+		billing.Settle(&invoice, invoicePayments, creditNotes)
+		invoice.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		invoice, err = invoiceStore.UpdateSettlement(c, invoice, from)
+		if errors.Is(err, repository.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			return invoice, err
+		}
+
+		if *invoice.Payment_status == models.InvoicePaid {
+			settleOrder(c, invoice, uid)
+		}
+
+		return invoice, nil
+	}
+	return models.Invoice{}, repository.ErrConflict
+}
+
+// RefundPack is the optional body of a refund. Without an amount whatever
+// is left of the payment is refunded.
+type RefundPack struct {
+	Amount    *money.Money `json:"amount"`
+	Reason    *string      `json:"reason" validate:"omitempty,max=200"`
+	Drawer_id string       `json:"drawer_id"`
+}
+
+// RefundPayment returns all or part of a captured payment and issues a credit
+// note for it against the invoice.
+func RefundPayment() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		paymentId := ctx.Param("payment_id")
+		var refundPack RefundPack
+
+		if ctx.Request.ContentLength != 0 {
+			if err := ctx.BindJSON(&refundPack); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		validationErr := validate.Struct(refundPack)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if status, body := checkDayOpen(c, time.Now()); body != nil {
+			ctx.JSON(status, body)
+			return
+		}
+
+		payment, err := paymentStore.Get(c, paymentId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "payment was not found"})
+			return
+		}
+
+		refundable := payment.Refundable()
+		if refundable.Minor <= 0 {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "payment has nothing left to refund", "status": payment.Status})
+			return
+		}
+
+		amount := refundable
+		if refundPack.Amount != nil {
+			amount = *refundPack.Amount
+		}
+		if amount.IsNegative() || amount.IsZero() || amount.Minor > refundable.Minor {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "amount must be positive and at most what is left of the payment", "refundable": refundable})
+			return
+		}
+
+		invoice, err := invoiceStore.Get(c, payment.Invoice_id)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "invoice was not found"})
+			return
+		}
+
+		credited, err := billing.Portion(invoice, amount)
+		if err != nil {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		drawerId, code, body := paymentDrawer(c, ctx.GetString("uid"), refundPack.Drawer_id)
+		if body != nil {
+			ctx.JSON(code, body)
+			return
+		}
+
+		// the refund is reserved on the payment before the money moves, so a
+		// second refund racing this one cannot take the same amount again
+		message := "cash returned"
+		if payment.Provider != cashProvider {
+			message = "refund requested"
+		}
+		previous := payment
+		payment.Refunded = payment.Refunded.Add(amount)
+		recordPaymentEvent(&payment, "", "refund", refundStatus(payment), message)
+
+		if err := paymentStore.UpdateRefunded(c, payment, previous); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "payment was refunded by someone else at the same time"})
+			return
+		}
+
+		if payment.Provider != cashProvider {
+			result, err := paymentProvider.Refund(c, payment.Reference, amount)
+			if err != nil || result.Status != models.PaymentRefunded {
+				status, reason := http.StatusConflict, "refund was declined: "+result.Message
+				if err != nil {
+					status, reason = providerFailure(err)
+				}
+				if err := releaseRefund(c, payment.Payment_id, amount, reason); err != nil {
+					log.Printf("refund of %s on payment %s failed but stays reserved: %v", amount, payment.Payment_id, err)
+				}
+				ctx.JSON(status, gin.H{"error": reason})
+				return
+			}
+		}
+
+		creditNote := models.CreditNote{
+			ID:            primitive.NewObjectID(),
+			Invoice_id:    invoice.Invoice_id,
+			Order_id:      invoice.Order_id,
+			Payment_id:    payment.Payment_id,
+			Method:        *payment.Method,
+			Drawer_id:     drawerId,
+			Subtotal:      credited.Subtotal,
+			Tax_total:     credited.Tax_total,
+			Total:         credited.Total,
+			Tax_breakdown: credited.Tax_breakdown,
+			Created_by:    ctx.GetString("uid"),
+		}
+		creditNote.Credit_note_id = creditNote.ID.Hex()
+		creditNote.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		if refundPack.Reason != nil {
+			creditNote.Reason = *refundPack.Reason
+		}
+
+		if err := creditNoteStore.Create(c, creditNote); err != nil {
+			log.Printf("refund of %s on payment %s has no credit note: %v", amount, payment.Payment_id, err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "credit note was not created"})
+			return
+		}
+
+		if _, err := refreshInvoice(c, invoice.Invoice_id, ctx.GetString("uid")); err != nil {
+			log.Printf("refund on payment %s is recorded but invoice %s could not be updated: %v", payment.Payment_id, invoice.Invoice_id, err)
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"payment": payment, "credit_note": creditNote})
+	}
+}
+
+// refundStatus is the status of a captured payment with its refunded amount.
+func refundStatus(payment models.Payment) string {
+	switch {
+	case payment.Refunded.IsZero():
+		return models.PaymentCaptured
+	case payment.Refundable().IsZero():
+		return models.PaymentRefunded
+	}
+	return models.PaymentPartiallyRefunded
+}
+
+// releaseRefund gives back a refund reserved on the payment that the
+// provider did not make. Other refunds may have been reserved since, so it
+// takes the amount off whatever is refunded now.
+func releaseRefund(c context.Context, paymentId string, amount money.Money, reason string) error {
+	for attempt := 0; attempt < 3; attempt++ {
+		payment, err := paymentStore.Get(c, paymentId)
+		if err != nil {
+			return err
+		}
+
+		previous := payment
+		payment.Refunded = payment.Refunded.Sub(amount)
+		recordPaymentEvent(&payment, "", "refund", refundStatus(payment), reason)
+
+		if err := paymentStore.UpdateRefunded(c, payment, previous); !errors.Is(err, repository.ErrConflict) {
+			return err
+		}
+	}
+	return repository.ErrConflict
+}
+
+func VoidPayment() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		paymentId := ctx.Param("payment_id")
+
+		payment, err := paymentStore.Get(c, paymentId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "payment was not found"})
+			return
+		}
+
+		if payment.Status != models.PaymentAuthorized && payment.Status != models.PaymentPending {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "only uncaptured payments can be voided", "status": payment.Status})
+			return
+		}
+
+		result, err := paymentProvider.Void(c, payment.Reference)
+		if err != nil {
+			status, reason := providerFailure(err)
+			ctx.JSON(status, gin.H{"error": reason})
+			return
+		}
+		if result.Status != models.PaymentVoided {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "void was declined", "message": result.Message})
+			return
+		}
+		recordPaymentEvent(&payment, "", "void", result.Status, result.Message)
+
+		if err := paymentStore.Update(c, payment); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "payment update failed"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, payment)
+	}
+}
+
+// PaymentWebhook applies the provider's asynchronous result to a pending
+// payment. It is not behind Authentication; the HMAC signature is what
+// proves the request came from the provider. Redelivered events, and events
+// reporting an outcome the payment already reached, are acknowledged without
+// being applied twice.
+func PaymentWebhook() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		body, err := ctx.GetRawData()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := payments.VerifySignature(webhookSecret, ctx.GetHeader(payments.SignatureHeader), body, time.Now()); err != nil {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		var event payments.Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var status string
+		switch event.Type {
+		case payments.EventPaymentSucceeded:
+			status = models.PaymentCaptured
+		case payments.EventPaymentFailed:
+			status = models.PaymentFailed
+		default:
+			// acknowledge events we do not act on so the provider stops retrying
+			ctx.JSON(http.StatusOK, gin.H{"ignored": event.Type})
+			return
+		}
+
+		payment, err := paymentStore.GetByReference(c, paymentProvider.Name(), event.Reference)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "payment was not found"})
+			return
+		}
+
+		if payment.HasEvent(event.Event_id) {
+			ctx.JSON(http.StatusOK, payment)
+			return
+		}
+
+		// e.g. a capture that was also answered synchronously
+		if payment.Status == status || (status == models.PaymentCaptured && payment.IsCaptured()) {
+			ctx.JSON(http.StatusOK, payment)
+			return
+		}
+
+		if payment.Status != models.PaymentPending && payment.Status != models.PaymentAuthorized {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "payment is already settled", "status": payment.Status})
+			return
+		}
+
+		from := payment.Status
+		recordPaymentEvent(&payment, event.Event_id, event.Type, status, event.Message)
+
+		// a capture or void that settled the payment since it was read wins
+		if err := paymentStore.UpdateStatus(c, payment, from); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "payment update failed"})
+			return
+		}
+
+		if _, err := refreshInvoice(c, payment.Invoice_id, "provider:"+payment.Provider); err != nil {
+			log.Printf("payment %s is %s but invoice %s could not be updated: %v", payment.Payment_id, payment.Status, payment.Invoice_id, err)
+		}
+
+		ctx.JSON(http.StatusOK, payment)
+	}
+}
@@ -0,0 +1,269 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+	"github.com/tokha04/go-restautant-management/payments"
+	"github.com/tokha04/go-restautant-management/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var testWebhookSecret = []byte("webhook-secret")
+
+// useMockPayments sets a mock provider that never posts its own webhooks.
+func useMockPayments() {
+	UsePayments(payments.NewMockProvider("", testWebhookSecret), testWebhookSecret)
+}
+
+// storedBill is an invoice of total for a served order.
+func storedBill(t *testing.T, total int64) models.Invoice {
+	t.Helper()
+
+	order := storedOrder(t, models.OrderServed)
+	invoice := models.Invoice{
+		ID:             primitive.NewObjectID(),
+		Order_id:       order.Order_id,
+		Payment_status: ptr(models.InvoicePending),
+		Subtotal:       money.New(total),
+		Total:          money.New(total),
+	}
+	invoice.Invoice_id = invoice.ID.Hex()
+	if err := invoiceStore.Create(context.Background(), invoice); err != nil {
+		t.Fatal(err)
+	}
+	return invoice
+}
+
+func paymentRouter() *gin.Engine {
+	router := gin.New()
+	router.POST("/invoices/:invoice_id/payments", CreatePayment())
+	router.POST("/payments/webhook", PaymentWebhook())
+	return router
+}
+
+// deliver posts a webhook event signed with secret.
+func deliver(t *testing.T, router *gin.Engine, secret []byte, event payments.Event) int {
+	t.Helper()
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/payments/webhook", bytes.NewReader(body))
+	req.Header.Set(payments.SignatureHeader, payments.Sign(secret, body, time.Now()))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestCreatePayment(t *testing.T) {
+	useMemoryStores(t)
+	useMockPayments()
+	router := paymentRouter()
+	invoice := storedBill(t, 5000)
+	path := "/invoices/" + invoice.Invoice_id + "/payments"
+
+	var payment models.Payment
+	if code := perform(t, router, http.MethodPost, path, gin.H{"method": models.PaymentCash, "amount": "20.00"}, &payment); code != http.StatusOK {
+		t.Fatalf("paying cash returned %d, want 200", code)
+	}
+	if payment.Status != models.PaymentCaptured {
+		t.Errorf("cash payment is %s, want %s", payment.Status, models.PaymentCaptured)
+	}
+
+	if code := perform(t, router, http.MethodPost, path, gin.H{"method": models.PaymentCash, "amount": "40.00"}, nil); code != http.StatusBadRequest {
+		t.Errorf("paying more than the balance returned %d, want 400", code)
+	}
+
+	if code := perform(t, router, http.MethodPost, path, gin.H{"method": models.PaymentCard, "card_token": payments.MockTokenDeclined}, &payment); code != http.StatusPaymentRequired {
+		t.Fatalf("a declined card returned %d, want 402", code)
+	}
+
+	// without an amount the card pays the rest
+	if code := perform(t, router, http.MethodPost, path, gin.H{"method": models.PaymentCard, "card_token": "tok_visa"}, &payment); code != http.StatusOK {
+		t.Fatalf("paying by card returned %d, want 200", code)
+	}
+	checkPaymentAmount(t, payment, 3000)
+
+	stored, err := invoiceStore.Get(context.Background(), invoice.Invoice_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *stored.Payment_status != models.InvoicePaid || !stored.Balance.IsZero() {
+		t.Errorf("invoice is %s with %s to pay, want it paid", *stored.Payment_status, stored.Balance)
+	}
+
+	order, err := orderStore.Get(context.Background(), invoice.Order_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != models.OrderPaid {
+		t.Errorf("order is %s once its invoice is paid, want %s", order.Status, models.OrderPaid)
+	}
+
+	if code := perform(t, router, http.MethodPost, path, gin.H{"method": models.PaymentCash}, nil); code != http.StatusConflict {
+		t.Errorf("paying a paid invoice returned %d, want 409", code)
+	}
+}
+
+func checkPaymentAmount(t *testing.T, payment models.Payment, want int64) {
+	t.Helper()
+	if payment.Amount.Minor != want {
+		t.Errorf("payment is for %s, want %s", payment.Amount, money.New(want))
+	}
+}
+
+func TestCreatePaymentWithCardsDisabled(t *testing.T) {
+	useMemoryStores(t)
+	UsePayments(payments.DisabledProvider{}, nil)
+	router := paymentRouter()
+	invoice := storedBill(t, 5000)
+	path := "/invoices/" + invoice.Invoice_id + "/payments"
+
+	if code := perform(t, router, http.MethodPost, path, gin.H{"method": models.PaymentCard, "card_token": "tok_visa"}, nil); code != http.StatusServiceUnavailable {
+		t.Fatalf("paying by card returned %d, want 503", code)
+	}
+
+	recorded, err := paymentStore.ListByInvoice(context.Background(), invoice.Invoice_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded) != 0 {
+		t.Errorf("a refused card payment was recorded: %+v", recorded)
+	}
+
+	if code := perform(t, router, http.MethodPost, path, gin.H{"method": models.PaymentCash}, nil); code != http.StatusOK {
+		t.Errorf("paying cash returned %d, want 200", code)
+	}
+}
+
+func TestPaymentWebhook(t *testing.T) {
+	useMemoryStores(t)
+	useMockPayments()
+	router := paymentRouter()
+	invoice := storedBill(t, 5000)
+
+	var payment models.Payment
+	if code := perform(t, router, http.MethodPost, "/invoices/"+invoice.Invoice_id+"/payments", gin.H{"method": models.PaymentCard, "card_token": payments.MockTokenPending}, &payment); code != http.StatusOK {
+		t.Fatalf("paying by card returned %d, want 200", code)
+	}
+	if payment.Status != models.PaymentPending {
+		t.Fatalf("payment is %s, want %s", payment.Status, models.PaymentPending)
+	}
+
+	succeeded := payments.Event{Event_id: "evt_1", Type: payments.EventPaymentSucceeded, Reference: payment.Reference}
+	if code := deliver(t, router, []byte("forged"), succeeded); code != http.StatusUnauthorized {
+		t.Fatalf("a forged webhook returned %d, want 401", code)
+	}
+
+	if code := deliver(t, router, testWebhookSecret, succeeded); code != http.StatusOK {
+		t.Fatalf("the webhook returned %d, want 200", code)
+	}
+	stored, err := paymentStore.Get(context.Background(), payment.Payment_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.PaymentCaptured {
+		t.Fatalf("payment is %s after the webhook, want %s", stored.Status, models.PaymentCaptured)
+	}
+	storedInvoice, err := invoiceStore.Get(context.Background(), invoice.Invoice_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *storedInvoice.Payment_status != models.InvoicePaid {
+		t.Errorf("invoice is %s after the webhook, want %s", *storedInvoice.Payment_status, models.InvoicePaid)
+	}
+
+	// a redelivery and a second event with the same outcome are acknowledged
+	// so the provider stops retrying, without being applied again
+	again := succeeded
+	again.Event_id = "evt_2"
+	for _, event := range []payments.Event{succeeded, again} {
+		if code := deliver(t, router, testWebhookSecret, event); code != http.StatusOK {
+			t.Errorf("delivering %s again returned %d, want 200", event.Event_id, code)
+		}
+	}
+	if stored, _ := paymentStore.Get(context.Background(), payment.Payment_id); len(stored.Events) != len(payment.Events)+1 {
+		t.Errorf("payment has %d events, want the webhook applied once", len(stored.Events))
+	}
+
+	failed := payments.Event{Event_id: "evt_3", Type: payments.EventPaymentFailed, Reference: payment.Reference}
+	if code := deliver(t, router, testWebhookSecret, failed); code != http.StatusConflict {
+		t.Errorf("failing a captured payment returned %d, want 409", code)
+	}
+
+	unknown := payments.Event{Event_id: "evt_4", Type: payments.EventPaymentSucceeded, Reference: "mock_unknown"}
+	if code := deliver(t, router, testWebhookSecret, unknown); code != http.StatusNotFound {
+		t.Errorf("a webhook for an unknown payment returned %d, want 404", code)
+	}
+}
+
+func TestUpdatePaymentStatusFromStaleStatus(t *testing.T) {
+	useMemoryStores(t)
+	payment := models.Payment{Payment_id: "p1", Method: ptr(models.PaymentCard), Status: models.PaymentPending}
+	if err := paymentStore.Create(context.Background(), payment); err != nil {
+		t.Fatal(err)
+	}
+
+	// a void settled the payment since the webhook read it
+	voided := payment
+	voided.Status = models.PaymentVoided
+	if err := paymentStore.UpdateStatus(context.Background(), voided, models.PaymentPending); err != nil {
+		t.Fatal(err)
+	}
+
+	captured := payment
+	captured.Status = models.PaymentCaptured
+	if err := paymentStore.UpdateStatus(context.Background(), captured, models.PaymentPending); !errors.Is(err, repository.ErrConflict) {
+		t.Errorf("settling a payment from a stale status returned %v, want ErrConflict", err)
+	}
+}
+
+func TestCreatePaymentWithTip(t *testing.T) {
+	useMemoryStores(t)
+	useMockPayments()
+	router := paymentRouter()
+	invoice := storedBill(t, 5000)
+	path := "/invoices/" + invoice.Invoice_id + "/payments"
+
+	order, err := orderStore.Get(context.Background(), invoice.Order_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order.Server_id = ptr("server")
+	if _, err := orderStore.UpdateDetails(context.Background(), order); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := perform(t, router, http.MethodPost, path, gin.H{"method": models.PaymentCash, "tip": "-1.00"}, nil); code != http.StatusBadRequest {
+		t.Errorf("a negative tip returned %d, want 400", code)
+	}
+
+	var payment models.Payment
+	if code := perform(t, router, http.MethodPost, path, gin.H{"method": models.PaymentCard, "card_token": "tok_visa", "tip": "7.50"}, &payment); code != http.StatusOK {
+		t.Fatalf("paying with a tip returned %d, want 200", code)
+	}
+	if payment.Tip_recipient != "server" {
+		t.Errorf("the tip went to %q, want the order's server", payment.Tip_recipient)
+	}
+
+	// the tip is paid on top and does not count towards the balance
+	stored, err := invoiceStore.Get(context.Background(), invoice.Invoice_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *stored.Payment_status != models.InvoicePaid || stored.Tip_total.Minor != 750 || stored.Amount_paid.Minor != 5000 {
+		t.Errorf("invoice is %s with %s paid and %s in tips, want it paid with 7.50 in tips", *stored.Payment_status, stored.Amount_paid, stored.Tip_total)
+	}
+}
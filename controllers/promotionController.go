@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var promotionStore repository.PromotionStore
+
+func GetPromotions() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		allPromotions, err := promotionStore.List(c)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing promotions"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, allPromotions)
+	}
+}
+
+func GetPromotion() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		promotionId := ctx.Param("promotion_id")
+
+		promotion, err := promotionStore.Get(c, promotionId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "error occured while fetching the promotion"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, promotion)
+	}
+}
+
+func CreatePromotion() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var promotion models.Promotion
+
+		if err := ctx.BindJSON(&promotion); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := validate.Struct(promotion)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if status, body := checkPromotion(c, &promotion); status != 0 {
+			ctx.JSON(status, body)
+			return
+		}
+
+		if promotion.Active == nil {
+			active := true
+			promotion.Active = &active
+		}
+
+		promotion.Uses = 0
+		promotion.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		promotion.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		promotion.ID = primitive.NewObjectID()
+		promotion.Promotion_id = promotion.ID.Hex()
+
+		if err := promotionStore.Create(c, promotion); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "promotion was not created"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, promotion)
+	}
+}
+
+func UpdatePromotion() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		promotionId := ctx.Param("promotion_id")
+		var promotion models.Promotion
+
+		if err := ctx.BindJSON(&promotion); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		foundPromotion, err := promotionStore.Get(c, promotionId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "promotion was not found"})
+			return
+		}
+
+		if promotion.Name != nil {
+			foundPromotion.Name = promotion.Name
+		}
+
+		if promotion.Code != nil {
+			foundPromotion.Code = promotion.Code
+		}
+
+		if promotion.Kind != nil {
+			foundPromotion.Kind = promotion.Kind
+		}
+
+		if promotion.Scope != nil {
+			foundPromotion.Scope = promotion.Scope
+		}
+
+		if promotion.Percent != nil {
+			foundPromotion.Percent = promotion.Percent
+		}
+
+		if promotion.Amount != nil {
+			foundPromotion.Amount = promotion.Amount
+		}
+
+		if promotion.Food_id != nil {
+			foundPromotion.Food_id = promotion.Food_id
+		}
+
+		if promotion.Valid_from != nil {
+			foundPromotion.Valid_from = promotion.Valid_from
+		}
+
+		if promotion.Valid_to != nil {
+			foundPromotion.Valid_to = promotion.Valid_to
+		}
+
+		if promotion.Max_uses != nil {
+			foundPromotion.Max_uses = promotion.Max_uses
+		}
+
+		if promotion.Active != nil {
+			foundPromotion.Active = promotion.Active
+		}
+
+		foundPromotion.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		validationErr := validate.Struct(foundPromotion)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if status, body := checkPromotion(c, &foundPromotion); status != 0 {
+			ctx.JSON(status, body)
+			return
+		}
+
+		if err := promotionStore.Update(c, foundPromotion); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "promotion update failed"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, foundPromotion)
+	}
+}
+
+// checkPromotion normalises the coupon code and checks what validation tags
+// cannot: the validity window, the food of item promotions and that no other
+// promotion uses the code.
+func checkPromotion(c context.Context, promotion *models.Promotion) (int, gin.H) {
+	if promotion.Valid_from != nil && promotion.Valid_to != nil && !promotion.Valid_to.After(*promotion.Valid_from) {
+		return http.StatusBadRequest, gin.H{"error": "valid_to must be after valid_from"}
+	}
+
+	if promotion.Food_id != nil && *promotion.Food_id != "" {
+		if *promotion.Scope != models.ScopeItem {
+			return http.StatusBadRequest, gin.H{"error": "only item promotions can be limited to a food"}
+		}
+		if _, err := foodStore.Get(c, *promotion.Food_id); err != nil {
+			return storeErrorStatus(err), gin.H{"error": "food was not found"}
+		}
+	}
+
+	if promotion.Code != nil {
+		code := strings.ToUpper(*promotion.Code)
+		promotion.Code = &code
+
+		other, err := promotionStore.GetByCode(c, code)
+		if err == nil && other.Promotion_id != promotion.Promotion_id {
+			return http.StatusConflict, gin.H{"error": "another promotion already uses this code"}
+		}
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return http.StatusInternalServerError, gin.H{"error": "error occured while checking the code"}
+		}
+	}
+
+	return 0, nil
+}
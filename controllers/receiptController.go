@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/receipt"
+)
+
+var receiptHeader receipt.Header
+
+// UseRestaurant sets the restaurant details printed at the top of receipts.
+func UseRestaurant(header receipt.Header) {
+	receiptHeader = header
+}
+
+// GetInvoiceReceipt renders the invoice as a PDF (the default), as plain text
+// or as an ESC/POS stream for a thermal printer. Text and ESC/POS receipts
+// are 48 characters wide to fit 80mm paper unless width says otherwise.
+func GetInvoiceReceipt() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		invoiceId := ctx.Param("invoice_id")
+
+		format := ctx.DefaultQuery("format", "pdf")
+		if format != "pdf" && format != "txt" && format != "escpos" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of pdf, txt or escpos"})
+			return
+		}
+
+		width := receipt.ThermalWidth
+		if value := ctx.Query("width"); value != "" {
+			var err error
+			width, err = strconv.Atoi(value)
+			if err != nil || width < 32 || width > 80 {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "width must be a number of characters between 32 and 80"})
+				return
+			}
+		}
+
+		invoice, err := invoiceStore.Get(c, invoiceId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "error occured while fetching the invoice item"})
+			return
+		}
+
+		invoiceView, err := viewInvoice(c, invoice)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while preparing the invoice"})
+			return
+		}
+
+		allPayments, err := paymentStore.ListByInvoice(c, invoiceId)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing payments"})
+			return
+		}
+
+		r := receipt.Receipt{
+			Header:     receiptHeader,
+			Invoice:    invoiceView,
+			Payments:   allPayments,
+			Printed_at: time.Now(),
+		}
+
+		filename := "receipt-" + invoiceId
+		switch format {
+		case "txt":
+			ctx.Header("Content-Disposition", `inline; filename="`+filename+`.txt"`)
+			ctx.Data(http.StatusOK, "text/plain; charset=utf-8", receipt.Text(r, width))
+		case "escpos":
+			ctx.Header("Content-Disposition", `attachment; filename="`+filename+`.bin"`)
+			ctx.Data(http.StatusOK, "application/octet-stream", receipt.ESCPOS(r, width))
+		default:
+			ctx.Header("Content-Disposition", `inline; filename="`+filename+`.pdf"`)
+			ctx.Data(http.StatusOK, "application/pdf", receipt.PDF(r))
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+	"github.com/tokha04/go-restautant-management/repository"
+)
+
+var salesStore repository.SalesStore
+
+// TipSummary totals the tips of one staff member or one shift.
+type TipSummary struct {
+	User_id  string      `json:"user_id"`
+	Shift_id string      `json:"shift_id,omitempty"`
+	Tips     money.Money `json:"tips"`
+	Count    int         `json:"count"`
+}
+
+type TipReport struct {
+	From     time.Time    `json:"from"`
+	To       time.Time    `json:"to"`
+	Total    money.Money  `json:"total"`
+	By_user  []TipSummary `json:"by_user"`
+	By_shift []TipSummary `json:"by_shift"`
+}
+
+// reportRange reads the from and to query parameters as RFC3339 times or
+// YYYY-MM-DD dates. A date given as to includes that whole day. Without
+// parameters the range is the current day.
+func reportRange(ctx *gin.Context) (time.Time, time.Time, error) {
+	now := time.Now().In(restaurantTimezone)
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	to := from.AddDate(0, 0, 1)
+
+	parse := func(name string, endOfDay bool) (time.Time, bool, error) {
+		value := ctx.Query(name)
+		if value == "" {
+			return time.Time{}, false, nil
+		}
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t, true, nil
+		}
+		day, err := time.ParseInLocation("2006-01-02", value, now.Location())
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("%s must be an RFC3339 time or a YYYY-MM-DD date", name)
+		}
+		if endOfDay {
+			day = day.AddDate(0, 0, 1)
+		}
+		return day, true, nil
+	}
+
+	if t, ok, err := parse("from", false); err != nil {
+		return from, to, err
+	} else if ok {
+		from = t
+		to = from.AddDate(0, 0, 1)
+	}
+	if t, ok, err := parse("to", true); err != nil {
+		return from, to, err
+	} else if ok {
+		to = t
+	}
+
+	if !to.After(from) {
+		return from, to, fmt.Errorf("to must be after from")
+	}
+	return from, to, nil
+}
+
+// GetTipReport totals the tips of captured payments per staff member and per
+// shift. Staff other than managers only see their own tips.
+func GetTipReport() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, err := reportRange(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		userId := ctx.Query("user_id")
+		if !isManager(ctx) {
+			userId = ctx.GetString("uid")
+		}
+
+		allPayments, err := paymentStore.ListBetween(c, from, to)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing payments"})
+			return
+		}
+
+		report := TipReport{From: from, To: to, Total: money.Zero(), By_user: []TipSummary{}, By_shift: []TipSummary{}}
+		byUser := map[string]*TipSummary{}
+		byShift := map[string]*TipSummary{}
+
+		for _, payment := range allPayments {
+			if !payment.IsCaptured() || payment.Tip.Minor <= 0 {
+				continue
+			}
+			if userId != "" && payment.Tip_recipient != userId {
+				continue
+			}
+
+			report.Total = report.Total.Add(payment.Tip)
+			addTip(byUser, TipSummary{User_id: payment.Tip_recipient}, payment.Tip_recipient, payment.Tip)
+			if payment.Shift_id != "" {
+				addTip(byShift, TipSummary{User_id: payment.Tip_recipient, Shift_id: payment.Shift_id}, payment.Shift_id, payment.Tip)
+			}
+		}
+
+		for _, summary := range byUser {
+			report.By_user = append(report.By_user, *summary)
+		}
+		for _, summary := range byShift {
+			report.By_shift = append(report.By_shift, *summary)
+		}
+		sort.Slice(report.By_user, func(i, j int) bool { return report.By_user[i].User_id < report.By_user[j].User_id })
+		sort.Slice(report.By_shift, func(i, j int) bool { return report.By_shift[i].Shift_id < report.By_shift[j].Shift_id })
+
+		ctx.JSON(http.StatusOK, report)
+	}
+}
+
+// addTip adds tip to the summary stored under key, starting from empty when
+// there is none yet.
+func addTip(summaries map[string]*TipSummary, empty TipSummary, key string, tip money.Money) {
+	summary, ok := summaries[key]
+	if !ok {
+		empty.Tips = money.Zero()
+		summary = &empty
+		summaries[key] = summary
+	}
+	summary.Tips = summary.Tips.Add(tip)
+	summary.Count++
+}
+
+// GetSalesReport totals the invoiced sales of a period, grouped by day
+// (the default), hour, menu, food, table, staff user or payment method. It
+// answers in JSON, or as a CSV spreadsheet with format=csv.
+func GetSalesReport() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, err := reportRange(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		groupBy := ctx.DefaultQuery("group_by", models.SalesByDay)
+		if !slices.Contains(models.SalesGroups, groupBy) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "group_by must be one of " + strings.Join(models.SalesGroups, ", ")})
+			return
+		}
+
+		format := ctx.DefaultQuery("format", "json")
+		if format != "json" && format != "csv" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+			return
+		}
+
+		filter := repository.SalesFilter{From: from, To: to, Group_by: groupBy, Location: restaurantTimezone}
+		rows, err := salesStore.Sales(c, filter)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while totalling sales"})
+			return
+		}
+
+		filter.Group_by = ""
+		totals, err := salesStore.Sales(c, filter)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while totalling sales"})
+			return
+		}
+
+		// days and hours read in order; everything else best seller first
+		if groupBy == models.SalesByDay || groupBy == models.SalesByHour {
+			sort.Slice(rows, func(i, j int) bool { return rows[i].Key < rows[j].Key })
+		} else {
+			sort.Slice(rows, func(i, j int) bool {
+				if rows[i].Gross.Minor != rows[j].Gross.Minor {
+					return rows[i].Gross.Minor > rows[j].Gross.Minor
+				}
+				return rows[i].Key < rows[j].Key
+			})
+		}
+
+		report := models.SalesReport{From: from, To: to, Group_by: groupBy, Rows: rows}
+		if len(totals) > 0 {
+			report.Totals = totals[0]
+		} else {
+			report.Totals = models.SalesRow{Label: "Total", Gross: money.Zero(), Discounts: money.Zero(), Tax: money.Zero(), Net: money.Zero()}
+		}
+
+		if format == "csv" {
+			filename := fmt.Sprintf("sales-by-%s-%s-%s.csv", groupBy, from.Format("20060102"), to.Format("20060102"))
+			ctx.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+			ctx.Data(http.StatusOK, "text/csv; charset=utf-8", salesCSV(report))
+			return
+		}
+
+		ctx.JSON(http.StatusOK, report)
+	}
+}
+
+// salesCSV writes one line per row of the report followed by the totals.
+// Amounts are plain decimals so spreadsheets read them as numbers.
+func salesCSV(report models.SalesReport) []byte {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+
+	w.Write([]string{report.Group_by, "label", "invoices", "items", "covers", "gross", "discounts", "tax", "net", "currency"})
+	record := func(row models.SalesRow) []string {
+		return []string{
+			row.Key,
+			row.Label,
+			strconv.Itoa(row.Invoices),
+			strconv.Itoa(row.Items),
+			strconv.Itoa(row.Covers),
+			row.Gross.String(),
+			row.Discounts.String(),
+			row.Tax.String(),
+			row.Net.String(),
+			row.Gross.Currency,
+		}
+	}
+	for _, row := range report.Rows {
+		w.Write(record(row))
+	}
+
+	totals := record(report.Totals)
+	totals[0] = "TOTAL"
+	w.Write(totals)
+	w.Flush()
+
+	return b.Bytes()
+}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// servedOrderWithItems stores a served order with one item per price, ready
+// to be invoiced.
+func servedOrderWithItems(t *testing.T, prices ...int64) (models.Order, []models.OrderItem) {
+	t.Helper()
+
+	order := storedOrder(t, models.OrderServed)
+	orderItems := []models.OrderItem{}
+	for _, price := range prices {
+		orderItem := models.OrderItem{ID: primitive.NewObjectID(), Order_id: order.Order_id, Food_id: ptr("food"), Unit_price: ptr(money.New(price))}
+		orderItem.Order_item_id = orderItem.ID.Hex()
+		orderItems = append(orderItems, orderItem)
+	}
+	if err := orderItemStore.CreateMany(context.Background(), orderItems); err != nil {
+		t.Fatal(err)
+	}
+	return order, orderItems
+}
+
+func TestSalesReportCountsSplitBills(t *testing.T) {
+	useMemoryStores(t)
+	router := gin.New()
+	router.POST("/invoices/split", SplitInvoice())
+	router.GET("/reports/sales", GetSalesReport())
+
+	// prices include 20% VAT, which the shares only carry in their totals
+	vat := models.TaxRate{ID: primitive.NewObjectID(), Name: ptr("VAT"), Rate: ptr(20.0), Inclusive: ptr(true)}
+	vat.Tax_rate_id = vat.ID.Hex()
+	if err := taxRateStore.Create(context.Background(), vat); err != nil {
+		t.Fatal(err)
+	}
+
+	even, _ := servedOrderWithItems(t, 1000, 2000)
+	if code := perform(t, router, http.MethodPost, "/invoices/split", gin.H{"order_id": even.Order_id, "mode": models.SplitEvenly, "guests": 3}, nil); code != http.StatusOK {
+		t.Fatalf("even split returned %d", code)
+	}
+	byItems, items := servedOrderWithItems(t, 500, 700)
+	bills := [][]string{{items[0].Order_item_id}, {items[1].Order_item_id}}
+	if code := perform(t, router, http.MethodPost, "/invoices/split", gin.H{"order_id": byItems.Order_id, "mode": models.SplitByItems, "bills": bills}, nil); code != http.StatusOK {
+		t.Fatalf("split by items returned %d", code)
+	}
+
+	now := time.Now().UTC()
+	query := url.Values{
+		"from": {now.Add(-time.Hour).Format(time.RFC3339)},
+		"to":   {now.Add(time.Hour).Format(time.RFC3339)},
+	}
+
+	tests := []struct {
+		group_by string
+		rows     int
+	}{
+		{models.SalesByDay, 1},
+		{models.SalesByPayment, 1},
+		// the shares of the even split sold no food of their own
+		{models.SalesByFood, 2},
+	}
+
+	for _, tt := range tests {
+		query.Set("group_by", tt.group_by)
+		var report models.SalesReport
+		if code := perform(t, router, http.MethodGet, "/reports/sales?"+query.Encode(), nil, &report); code != http.StatusOK {
+			t.Fatalf("sales by %s returned %d", tt.group_by, code)
+		}
+
+		totals := report.Totals
+		if totals.Invoices != 5 || totals.Items != 2 || totals.Gross.Minor != 4200 || totals.Net.Minor != 3500 || totals.Tax.Minor != 700 {
+			t.Errorf("sales by %s totals = %+v, want 5 invoices, 2 items, 42.00 gross, 35.00 net and 7.00 tax", tt.group_by, totals)
+		}
+		if len(report.Rows) != tt.rows {
+			t.Fatalf("sales by %s has %d rows, want %d", tt.group_by, len(report.Rows), tt.rows)
+		}
+
+		var gross int64
+		for _, row := range report.Rows {
+			gross += row.Gross.Minor
+		}
+		if gross != 4200 {
+			t.Errorf("sales by %s rows add up to %d, want 4200", tt.group_by, gross)
+		}
+	}
+}
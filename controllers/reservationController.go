@@ -0,0 +1,357 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var reservationStore repository.ReservationStore
+
+const defaultReservationMinutes = 90
+
+func GetReservations() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter := repository.ReservationFilter{Table_id: ctx.Query("table_id")}
+
+		for _, param := range []struct {
+			name   string
+			target **time.Time
+		}{{"from", &filter.From}, {"to", &filter.To}} {
+			if value := ctx.Query(param.name); value != "" {
+				t, err := time.Parse(time.RFC3339, value)
+				if err != nil {
+					ctx.JSON(http.StatusBadRequest, gin.H{"error": param.name + " must be an RFC3339 time"})
+					return
+				}
+				*param.target = &t
+			}
+		}
+
+		allReservations, err := reservationStore.List(c, filter)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing reservations"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, allReservations)
+	}
+}
+
+func GetReservation() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		reservationId := ctx.Param("reservation_id")
+
+		reservation, err := reservationStore.Get(c, reservationId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "error occured while fetching the reservation"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, reservation)
+	}
+}
+
+func CreateReservation() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var reservation models.Reservation
+
+		if err := ctx.BindJSON(&reservation); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if reservation.Duration_minutes == nil {
+			duration := defaultReservationMinutes
+			reservation.Duration_minutes = &duration
+		}
+
+		validationErr := validate.Struct(reservation)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if reservation.Start_time.Before(time.Now()) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "reservations cannot start in the past"})
+			return
+		}
+
+		reservation.End_time = reservation.Start_time.Add(time.Duration(*reservation.Duration_minutes) * time.Minute)
+
+		if status, body := checkReservation(c, reservation); body != nil {
+			ctx.JSON(status, body)
+			return
+		}
+
+		reservation.Status = models.ReservationBooked
+		reservation.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		reservation.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		reservation.ID = primitive.NewObjectID()
+		reservation.Reservation_id = reservation.ID.Hex()
+
+		if err := reservationStore.Create(c, reservation); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "reservation was not created"})
+			return
+		}
+
+		// two hosts may have booked the same slot at once; the older booking wins
+		if lost, err := lostBookingRace(c, reservation); err != nil || lost {
+			reservation.Status = models.ReservationCancelled
+			if err := reservationStore.Update(c, reservation); err != nil {
+				log.Printf("reservation %s lost a booking race but could not be withdrawn: %v", reservation.Reservation_id, err)
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "the table was booked by someone else at the same time and this booking could not be withdrawn", "reservation_id": reservation.Reservation_id})
+				return
+			}
+			ctx.JSON(http.StatusConflict, gin.H{"error": "the table was booked by someone else at the same time"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, reservation)
+	}
+}
+
+func UpdateReservation() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		reservationId := ctx.Param("reservation_id")
+		var reservation models.Reservation
+
+		if err := ctx.BindJSON(&reservation); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		foundReservation, err := reservationStore.Get(c, reservationId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "reservation was not found"})
+			return
+		}
+
+		if foundReservation.Status == models.ReservationCancelled {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "cancelled reservations cannot be changed"})
+			return
+		}
+		original := foundReservation
+
+		if reservation.Table_id != nil {
+			foundReservation.Table_id = reservation.Table_id
+		}
+		if reservation.Guest_name != nil {
+			foundReservation.Guest_name = reservation.Guest_name
+		}
+		if reservation.Phone != nil {
+			foundReservation.Phone = reservation.Phone
+		}
+		if reservation.Party_size != nil {
+			foundReservation.Party_size = reservation.Party_size
+		}
+		if reservation.Start_time != nil && !reservation.Start_time.Equal(*foundReservation.Start_time) {
+			if reservation.Start_time.Before(time.Now()) {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "reservations cannot be moved into the past"})
+				return
+			}
+			foundReservation.Start_time = reservation.Start_time
+		}
+		if reservation.Duration_minutes != nil {
+			foundReservation.Duration_minutes = reservation.Duration_minutes
+		}
+		if reservation.Notes != nil {
+			foundReservation.Notes = reservation.Notes
+		}
+
+		validationErr := validate.Struct(foundReservation)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		foundReservation.End_time = foundReservation.Start_time.Add(time.Duration(*foundReservation.Duration_minutes) * time.Minute)
+
+		if status, body := checkReservation(c, foundReservation); body != nil {
+			ctx.JSON(status, body)
+			return
+		}
+
+		foundReservation.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := reservationStore.Update(c, foundReservation); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "reservation update failed"})
+			return
+		}
+
+		// a move races other bookings like a new booking does, but the
+		// moved booking is old and would always win; it gives way instead
+		if movedReservation(original, foundReservation) {
+			if lost, err := lostMoveRace(c, foundReservation); err != nil || lost {
+				original.Updated_at = foundReservation.Updated_at
+				if err := reservationStore.Update(c, original); err != nil {
+					log.Printf("reservation %s lost a booking race but could not be moved back: %v", original.Reservation_id, err)
+					ctx.JSON(http.StatusInternalServerError, gin.H{"error": "the table was booked by someone else at the same time and this booking could not be moved back"})
+					return
+				}
+				ctx.JSON(http.StatusConflict, gin.H{"error": "the table was booked by someone else at the same time"})
+				return
+			}
+		}
+
+		ctx.JSON(http.StatusOK, foundReservation)
+	}
+}
+
+// CancelReservation keeps the booking for the record but frees its table.
+func CancelReservation() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		reservationId := ctx.Param("reservation_id")
+
+		reservation, err := reservationStore.Get(c, reservationId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "reservation was not found"})
+			return
+		}
+
+		reservation.Status = models.ReservationCancelled
+		reservation.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := reservationStore.Update(c, reservation); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "reservation update failed"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, reservation)
+	}
+}
+
+// GetAvailability answers "a table for 4 at 19:30": it lists the tables that
+// seat the party and are free for the whole slot, smallest table first.
+func GetAvailability() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		partySize, err := strconv.Atoi(ctx.Query("party_size"))
+		if err != nil || partySize < 1 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "party_size must be a positive number"})
+			return
+		}
+
+		start, err := time.Parse(time.RFC3339, ctx.Query("start"))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "start must be an RFC3339 time"})
+			return
+		}
+
+		duration := defaultReservationMinutes
+		if value := ctx.Query("duration_minutes"); value != "" {
+			if duration, err = strconv.Atoi(value); err != nil || duration < 15 {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "duration_minutes must be at least 15"})
+				return
+			}
+		}
+		end := start.Add(time.Duration(duration) * time.Minute)
+
+		allTables, err := tableStore.List(c)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing table items"})
+			return
+		}
+
+		freeTables := []models.Table{}
+		for _, table := range allTables {
+			if table.Number_of_guests == nil || *table.Number_of_guests < partySize {
+				continue
+			}
+
+			conflicts, err := reservationStore.ListOverlapping(c, table.Table_id, start, end, "")
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking reservations"})
+				return
+			}
+			if len(conflicts) == 0 {
+				freeTables = append(freeTables, table)
+			}
+		}
+
+		sort.SliceStable(freeTables, func(i, j int) bool {
+			return *freeTables[i].Number_of_guests < *freeTables[j].Number_of_guests
+		})
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"party_size":       partySize,
+			"start_time":       start,
+			"end_time":         end,
+			"available_tables": freeTables,
+		})
+	}
+}
+
+// checkReservation rejects bookings for unknown tables, parties larger than
+// the table and slots that overlap another booking. It returns a nil body
+// when the reservation is acceptable.
+func checkReservation(c context.Context, reservation models.Reservation) (int, gin.H) {
+	table, err := tableStore.Get(c, *reservation.Table_id)
+	if err != nil {
+		return storeErrorStatus(err), gin.H{"error": "table was not found"}
+	}
+
+	if table.Number_of_guests != nil && *reservation.Party_size > *table.Number_of_guests {
+		return http.StatusConflict, gin.H{"error": fmt.Sprintf("table seats %d guests, the party has %d", *table.Number_of_guests, *reservation.Party_size)}
+	}
+
+	conflicts, err := reservationStore.ListOverlapping(c, *reservation.Table_id, *reservation.Start_time, reservation.End_time, reservation.Reservation_id)
+	if err != nil {
+		return http.StatusInternalServerError, gin.H{"error": "error occured while checking reservations"}
+	}
+	if len(conflicts) > 0 {
+		return http.StatusConflict, gin.H{"error": "the table is already booked for that time", "conflicts": conflicts}
+	}
+
+	return http.StatusOK, nil
+}
+
+func movedReservation(from, to models.Reservation) bool {
+	return *from.Table_id != *to.Table_id || !from.Start_time.Equal(*to.Start_time) || !from.End_time.Equal(to.End_time)
+}
+
+// lostMoveRace reports whether a moved booking found any other booking in
+// its new slot after it was written.
+func lostMoveRace(c context.Context, reservation models.Reservation) (bool, error) {
+	conflicts, err := reservationStore.ListOverlapping(c, *reservation.Table_id, *reservation.Start_time, reservation.End_time, reservation.Reservation_id)
+	if err != nil {
+		return false, err
+	}
+	return len(conflicts) > 0, nil
+}
+
+func lostBookingRace(c context.Context, reservation models.Reservation) (bool, error) {
+	conflicts, err := reservationStore.ListOverlapping(c, *reservation.Table_id, *reservation.Start_time, reservation.End_time, reservation.Reservation_id)
+	if err != nil {
+		return false, err
+	}
+
+	for _, conflict := range conflicts {
+		if conflict.ID.Hex() < reservation.ID.Hex() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,293 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// dinner is in two days, so every booking in these tests is in the future.
+var dinner = time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+
+func storedTable(t *testing.T, seats int) models.Table {
+	t.Helper()
+
+	table := models.Table{ID: primitive.NewObjectID(), Number_of_guests: ptr(seats), Table_number: ptr(1)}
+	table.Table_id = table.ID.Hex()
+	if err := tableStore.Create(context.Background(), table); err != nil {
+		t.Fatal(err)
+	}
+	return table
+}
+
+// storedReservation writes a booking straight to the store, the way a
+// concurrent request that already passed its checks would.
+func storedReservation(t *testing.T, tableId string, start time.Time, minutes int) models.Reservation {
+	t.Helper()
+
+	reservation := models.Reservation{
+		ID:               primitive.NewObjectID(),
+		Table_id:         ptr(tableId),
+		Guest_name:       ptr("Grace"),
+		Phone:            ptr("+10000000000"),
+		Party_size:       ptr(2),
+		Start_time:       ptr(start),
+		Duration_minutes: ptr(minutes),
+		End_time:         start.Add(time.Duration(minutes) * time.Minute),
+		Status:           models.ReservationBooked,
+	}
+	reservation.Reservation_id = reservation.ID.Hex()
+	if err := reservationStore.Create(context.Background(), reservation); err != nil {
+		t.Fatal(err)
+	}
+	return reservation
+}
+
+func booking(tableId string, start time.Time, minutes int) gin.H {
+	return gin.H{
+		"table_id":         tableId,
+		"guest_name":       "Grace",
+		"phone":            "+10000000000",
+		"party_size":       2,
+		"start_time":       start,
+		"duration_minutes": minutes,
+	}
+}
+
+func reservationRouter() *gin.Engine {
+	router := gin.New()
+	router.POST("/reservations", CreateReservation())
+	router.PATCH("/reservations/:reservation_id", UpdateReservation())
+	return router
+}
+
+func TestCreateReservationOverlap(t *testing.T) {
+	useMemoryStores(t)
+	router := reservationRouter()
+	table := storedTable(t, 4)
+	other := storedTable(t, 4)
+
+	if code := perform(t, router, http.MethodPost, "/reservations", booking(table.Table_id, dinner, 120), nil); code != http.StatusOK {
+		t.Fatalf("booking a free table returned %d, want 200", code)
+	}
+
+	tests := []struct {
+		name    string
+		tableId string
+		start   time.Time
+		minutes int
+		want    int
+	}{
+		{"same slot", table.Table_id, dinner, 120, http.StatusConflict},
+		{"starts during", table.Table_id, dinner.Add(time.Hour), 120, http.StatusConflict},
+		{"ends during", table.Table_id, dinner.Add(-time.Hour), 90, http.StatusConflict},
+		{"around", table.Table_id, dinner.Add(-time.Hour), 240, http.StatusConflict},
+		{"ends as it starts", table.Table_id, dinner.Add(-time.Hour), 60, http.StatusOK},
+		{"starts as it ends", table.Table_id, dinner.Add(2 * time.Hour), 60, http.StatusOK},
+		{"another table", other.Table_id, dinner, 120, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code := perform(t, router, http.MethodPost, "/reservations", booking(test.tableId, test.start, test.minutes), nil)
+			if code != test.want {
+				t.Errorf("booking returned %d, want %d", code, test.want)
+			}
+		})
+	}
+}
+
+func TestCreateReservationIgnoresCancelledBookings(t *testing.T) {
+	useMemoryStores(t)
+	table := storedTable(t, 4)
+
+	cancelled := storedReservation(t, table.Table_id, dinner, 120)
+	cancelled.Status = models.ReservationCancelled
+	if err := reservationStore.Update(context.Background(), cancelled); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := perform(t, reservationRouter(), http.MethodPost, "/reservations", booking(table.Table_id, dinner, 120), nil); code != http.StatusOK {
+		t.Errorf("booking over a cancelled booking returned %d, want 200", code)
+	}
+}
+
+func TestLostBookingRace(t *testing.T) {
+	useMemoryStores(t)
+	table := storedTable(t, 4)
+
+	// both bookings were checked before either was written
+	first := storedReservation(t, table.Table_id, dinner, 120)
+	second := storedReservation(t, table.Table_id, dinner.Add(time.Hour), 120)
+
+	if lost, err := lostBookingRace(context.Background(), first); err != nil || lost {
+		t.Errorf("the older booking lost the race: %v, %v", lost, err)
+	}
+	if lost, err := lostBookingRace(context.Background(), second); err != nil || !lost {
+		t.Errorf("the newer booking won the race: %v, %v", lost, err)
+	}
+}
+
+func TestUpdateReservationMove(t *testing.T) {
+	useMemoryStores(t)
+	router := reservationRouter()
+	table := storedTable(t, 4)
+
+	lunch := storedReservation(t, table.Table_id, dinner.Add(-6*time.Hour), 90)
+	storedReservation(t, table.Table_id, dinner, 120)
+	path := "/reservations/" + lunch.Reservation_id
+
+	if code := perform(t, router, http.MethodPatch, path, gin.H{"start_time": dinner.Add(time.Hour)}, nil); code != http.StatusConflict {
+		t.Fatalf("moving onto a booked slot returned %d, want 409", code)
+	}
+
+	var moved models.Reservation
+	if code := perform(t, router, http.MethodPatch, path, gin.H{"start_time": dinner.Add(-3 * time.Hour)}, &moved); code != http.StatusOK {
+		t.Fatalf("moving to a free slot returned %d, want 200", code)
+	}
+	if !moved.End_time.Equal(dinner.Add(-90 * time.Minute)) {
+		t.Errorf("moved booking ends at %s, want %s", moved.End_time, dinner.Add(-90*time.Minute))
+	}
+
+	// staying in its own slot never conflicts with itself
+	if code := perform(t, router, http.MethodPatch, path, gin.H{"party_size": 3}, nil); code != http.StatusOK {
+		t.Errorf("changing the party size returned %d, want 200", code)
+	}
+}
+
+func TestLostMoveRace(t *testing.T) {
+	useMemoryStores(t)
+	table := storedTable(t, 4)
+
+	// the moved booking is the older one, yet it gives way to the booking
+	// written into its new slot at the same time
+	moved := storedReservation(t, table.Table_id, dinner.Add(-6*time.Hour), 90)
+	storedReservation(t, table.Table_id, dinner, 120)
+
+	moved.Start_time = ptr(dinner.Add(time.Hour))
+	moved.End_time = moved.Start_time.Add(90 * time.Minute)
+	if err := reservationStore.Update(context.Background(), moved); err != nil {
+		t.Fatal(err)
+	}
+
+	if lost, err := lostMoveRace(context.Background(), moved); err != nil || !lost {
+		t.Errorf("the moved booking won the race: %v, %v", lost, err)
+	}
+}
+
+func TestReservationsCannotStartInThePast(t *testing.T) {
+	useMemoryStores(t)
+	router := reservationRouter()
+	table := storedTable(t, 4)
+
+	if code := perform(t, router, http.MethodPost, "/reservations", booking(table.Table_id, time.Now().Add(-time.Hour), 60), nil); code != http.StatusBadRequest {
+		t.Errorf("booking in the past returned %d, want 400", code)
+	}
+
+	reservation := storedReservation(t, table.Table_id, dinner, 60)
+	path := "/reservations/" + reservation.Reservation_id
+	if code := perform(t, router, http.MethodPatch, path, gin.H{"start_time": time.Now().Add(-time.Hour)}, nil); code != http.StatusBadRequest {
+		t.Errorf("moving into the past returned %d, want 400", code)
+	}
+
+	// a booking under way can still be changed as long as it is not moved
+	started := storedReservation(t, table.Table_id, time.Now().Add(-30*time.Minute).Truncate(time.Minute), 120)
+	if code := perform(t, router, http.MethodPatch, "/reservations/"+started.Reservation_id, gin.H{"party_size": 3, "start_time": started.Start_time}, nil); code != http.StatusOK {
+		t.Errorf("changing a booking under way returned %d, want 200", code)
+	}
+}
+
+// racingReservationStore stands in for a concurrent host: its first blind
+// overlap checks miss the other booking, and after updates successful
+// updates every update fails.
+type racingReservationStore struct {
+	repository.ReservationStore
+	blind   int
+	updates int
+}
+
+func (s *racingReservationStore) ListOverlapping(ctx context.Context, tableId string, start, end time.Time, excludeId string) ([]models.Reservation, error) {
+	if s.blind > 0 {
+		s.blind--
+		return nil, nil
+	}
+	return s.ReservationStore.ListOverlapping(ctx, tableId, start, end, excludeId)
+}
+
+func (s *racingReservationStore) Update(ctx context.Context, reservation models.Reservation) error {
+	if s.updates == 0 {
+		return errors.New("store is down")
+	}
+	s.updates--
+	return s.ReservationStore.Update(ctx, reservation)
+}
+
+func TestCreateReservationRace(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		updates int
+		want    int
+	}{
+		{"withdrawn", 1, http.StatusConflict},
+		{"withdrawal fails", 0, http.StatusInternalServerError},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			stores := useMemoryStores(t)
+			table := storedTable(t, 4)
+			other := storedReservation(t, table.Table_id, dinner, 120)
+			reservationStore = &racingReservationStore{ReservationStore: stores.Reservations, blind: 1, updates: test.updates}
+
+			if code := perform(t, reservationRouter(), http.MethodPost, "/reservations", booking(table.Table_id, dinner, 120), nil); code != test.want {
+				t.Fatalf("losing the race returned %d, want %d", code, test.want)
+			}
+
+			live, err := stores.Reservations.ListOverlapping(context.Background(), table.Table_id, dinner, dinner.Add(time.Hour), other.Reservation_id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.want == http.StatusConflict && len(live) != 0 {
+				t.Errorf("the losing booking is still live")
+			}
+		})
+	}
+}
+
+func TestUpdateReservationRace(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		updates int
+		want    int
+	}{
+		{"moved back", 2, http.StatusConflict},
+		{"moving back fails", 1, http.StatusInternalServerError},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			stores := useMemoryStores(t)
+			table := storedTable(t, 4)
+			lunch := storedReservation(t, table.Table_id, dinner.Add(-6*time.Hour), 90)
+			storedReservation(t, table.Table_id, dinner, 120)
+			reservationStore = &racingReservationStore{ReservationStore: stores.Reservations, blind: 1, updates: test.updates}
+
+			if code := perform(t, reservationRouter(), http.MethodPatch, "/reservations/"+lunch.Reservation_id, gin.H{"start_time": dinner}, nil); code != test.want {
+				t.Fatalf("losing the race returned %d, want %d", code, test.want)
+			}
+
+			if test.want == http.StatusConflict {
+				stored, err := stores.Reservations.Get(context.Background(), lunch.Reservation_id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !stored.Start_time.Equal(*lunch.Start_time) {
+					t.Errorf("the booking starts at %s, want it moved back to %s", stored.Start_time, lunch.Start_time)
+				}
+			}
+		})
+	}
+}
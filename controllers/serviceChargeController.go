@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var serviceChargeStore repository.ServiceChargeStore
+
+func GetServiceCharges() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		allServiceCharges, err := serviceChargeStore.List(c)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing service charges"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, allServiceCharges)
+	}
+}
+
+func GetServiceCharge() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		serviceChargeId := ctx.Param("service_charge_id")
+
+		serviceCharge, err := serviceChargeStore.Get(c, serviceChargeId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "error occured while fetching the service charge"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, serviceCharge)
+	}
+}
+
+func CreateServiceCharge() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var serviceCharge models.ServiceCharge
+
+		if err := ctx.BindJSON(&serviceCharge); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := validate.Struct(serviceCharge)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if serviceCharge.Active == nil {
+			active := true
+			serviceCharge.Active = &active
+		}
+
+		serviceCharge.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		serviceCharge.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		serviceCharge.ID = primitive.NewObjectID()
+		serviceCharge.Service_charge_id = serviceCharge.ID.Hex()
+
+		if err := serviceChargeStore.Create(c, serviceCharge); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "service charge was not created"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, serviceCharge)
+	}
+}
+
+func UpdateServiceCharge() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		serviceChargeId := ctx.Param("service_charge_id")
+		var serviceCharge models.ServiceCharge
+
+		if err := ctx.BindJSON(&serviceCharge); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		foundServiceCharge, err := serviceChargeStore.Get(c, serviceChargeId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "service charge was not found"})
+			return
+		}
+
+		if serviceCharge.Name != nil {
+			foundServiceCharge.Name = serviceCharge.Name
+		}
+
+		if serviceCharge.Rate != nil {
+			foundServiceCharge.Rate = serviceCharge.Rate
+		}
+
+		if serviceCharge.Min_guests != nil {
+			foundServiceCharge.Min_guests = serviceCharge.Min_guests
+		}
+
+		if serviceCharge.Active != nil {
+			foundServiceCharge.Active = serviceCharge.Active
+		}
+
+		foundServiceCharge.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		validationErr := validate.Struct(foundServiceCharge)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if err := serviceChargeStore.Update(c, foundServiceCharge); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "service charge update failed"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, foundServiceCharge)
+	}
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/helpers"
+	"github.com/tokha04/go-restautant-management/repository"
+)
+
+var revocationStore repository.RevocationStore
+
+// Logout ends the session of the device making the request.
+func Logout() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		sessionId := ctx.GetString("session_id")
+
+		if err := endSession(c, sessionId, "logout"); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "error occured while ending the session"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "logged out", "revoked_sessions": 1})
+	}
+}
+
+// LogoutAll ends every session of the current user, on every device.
+func LogoutAll() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		count, err := endUserSessions(c, ctx.GetString("uid"), "logout from all devices")
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while ending the sessions"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "logged out from all devices", "revoked_sessions": count})
+	}
+}
+
+// KillUserSessions lets an admin lock a user out of every device at once.
+func KillUserSessions() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		userId := ctx.Param("user_id")
+
+		if _, err := userStore.Get(c, userId); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "user was not found"})
+			return
+		}
+
+		count, err := endUserSessions(c, userId, "killed by "+ctx.GetString("uid"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while ending the sessions"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "user sessions revoked", "revoked_sessions": count})
+	}
+}
+
+func endSession(c context.Context, sessionId, reason string) error {
+	expiresAt := time.Now().Add(helpers.RefreshTokenTTL)
+	if err := revocationStore.RevokeSession(c, sessionId, expiresAt); err != nil {
+		return err
+	}
+
+	return sessionStore.Revoke(c, sessionId, reason)
+}
+
+// endUserSessions revokes every known session of the user and also blocks
+// any token issued up to now, in case one was minted outside a session.
+func endUserSessions(c context.Context, userId, reason string) (int, error) {
+	now := time.Now()
+	if err := revocationStore.RevokeUser(c, userId, now, now.Add(helpers.RefreshTokenTTL)); err != nil {
+		return 0, err
+	}
+
+	sessions, err := sessionStore.ListActiveByUser(c, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, session := range sessions {
+		if err := endSession(c, session.Session_id, reason); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(sessions), nil
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var shiftStore repository.ShiftStore
+
+// isManager reports whether the authenticated user may see and act on other
+// staff members' shifts and tips.
+func isManager(ctx *gin.Context) bool {
+	role := ctx.GetString("role")
+	return role == models.RoleAdmin || role == models.RoleManager
+}
+
+func GetShifts() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, err := reportRange(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		filter := repository.ShiftFilter{User_id: ctx.Query("user_id"), From: from, To: to}
+		if !isManager(ctx) {
+			filter.User_id = ctx.GetString("uid")
+		}
+
+		allShifts, err := shiftStore.List(c, filter)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing shifts"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, allShifts)
+	}
+}
+
+// StartShift opens a shift for the authenticated user. A user has at most one
+// open shift.
+func StartShift() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		uid := ctx.GetString("uid")
+
+		open, err := shiftStore.GetOpenByUser(c, uid)
+		if err == nil {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "a shift is already open", "shift_id": open.Shift_id})
+			return
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while looking up the shift"})
+			return
+		}
+
+		var shift models.Shift
+		shift.ID = primitive.NewObjectID()
+		shift.Shift_id = shift.ID.Hex()
+		shift.User_id = uid
+		shift.Started_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := shiftStore.Create(c, shift); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "shift was not created"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, shift)
+	}
+}
+
+// EndShift closes a shift. Managers may close anyone's shift.
+func EndShift() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		shiftId := ctx.Param("shift_id")
+
+		shift, err := shiftStore.Get(c, shiftId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "shift was not found"})
+			return
+		}
+
+		if shift.User_id != ctx.GetString("uid") && !isManager(ctx) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "only managers can end another user's shift"})
+			return
+		}
+
+		if !shift.IsOpen() {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "shift has already ended", "ended_at": shift.Ended_at})
+			return
+		}
+
+		ended, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		shift.Ended_at = &ended
+
+		if err := shiftStore.Update(c, shift); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "shift update failed"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, shift)
+	}
+}
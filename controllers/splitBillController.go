@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/billing"
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SplitBillPack describes how an order is split. ITEMS takes one list of
+// order item ids per bill, EVEN the number of guests and CUSTOM the amount
+// every bill pays. SEAT needs nothing more: every seat gets its own bill.
+type SplitBillPack struct {
+	Order_id *string       `json:"order_id" validate:"required"`
+	Mode     *string       `json:"mode" validate:"required,eq=ITEMS|eq=EVEN|eq=CUSTOM|eq=SEAT"`
+	Bills    [][]string    `json:"bills"`
+	Guests   int           `json:"guests"`
+	Amounts  []money.Money `json:"amounts"`
+}
+
+func SplitInvoice() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var splitBillPack SplitBillPack
+
+		if err := ctx.BindJSON(&splitBillPack); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := validate.Struct(splitBillPack)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if status, body := checkDayOpen(c, time.Now()); body != nil {
+			ctx.JSON(status, body)
+			return
+		}
+
+		orderId := *splitBillPack.Order_id
+		if status, body := checkBillable(c, orderId); body != nil {
+			ctx.JSON(status, body)
+			return
+		}
+
+		summary, err := orderItemStore.ItemsByOrder(c, orderId)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing order items by orderId"})
+			return
+		}
+
+		calc, err := newCalculator(c, summary)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while calculating the invoice taxes"})
+			return
+		}
+
+		var whole models.Invoice
+		calc.Calculate(&whole, summary.Order_items)
+
+		var invoices []models.Invoice
+		switch *splitBillPack.Mode {
+		case models.SplitByItems:
+			invoices, err = calc.SplitByItems(summary.Order_items, splitBillPack.Bills)
+		case models.SplitEvenly:
+			invoices, err = billing.SplitEvenly(whole, splitBillPack.Guests)
+		case models.SplitByAmount:
+			invoices, err = billing.SplitByAmounts(whole, splitBillPack.Amounts)
+		case models.SplitBySeat:
+			invoices, err = calc.SplitBySeat(summary.Order_items)
+		}
+		if err != nil {
+			if errors.Is(err, billing.ErrInvalidSplit) {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "bill could not be split"})
+			return
+		}
+
+		paymentDueDate, _ := time.Parse(time.RFC3339, time.Now().AddDate(0, 0, 1).Format(time.RFC3339))
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		for i := range invoices {
+			status := models.InvoicePending
+			invoices[i].ID = primitive.NewObjectID()
+			invoices[i].Invoice_id = invoices[i].ID.Hex()
+			invoices[i].Order_id = orderId
+			invoices[i].Payment_status = &status
+			billing.Settle(&invoices[i], nil, nil)
+			invoices[i].Payment_due_date = paymentDueDate
+			invoices[i].Split_mode = *splitBillPack.Mode
+			invoices[i].Split_index = i + 1
+			invoices[i].Split_count = len(invoices)
+			invoices[i].Created_at = now
+			invoices[i].Updated_at = now
+		}
+
+		if status, body := claimInvoicing(c, orderId); body != nil {
+			ctx.JSON(status, body)
+			return
+		}
+
+		if err := numberInvoices(c, invoices); err != nil {
+			releaseInvoicing(c, orderId)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "invoice numbers could not be allocated"})
+			return
+		}
+
+		if err := invoiceStore.CreateMany(c, invoices); err != nil {
+			voidUnrecorded(c, invoices)
+			releaseInvoicing(c, orderId)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "invoices were not created"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, invoices)
+	}
+}
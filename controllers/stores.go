@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/tokha04/go-restautant-management/repository"
+)
+
+// UseStores points every handler in this package at the given backend. It has
+// to be called before the router starts serving requests.
+func UseStores(stores *repository.Stores) {
+	foodStore = stores.Foods
+	menuStore = stores.Menus
+	tableStore = stores.Tables
+	orderStore = stores.Orders
+	orderItemStore = stores.OrderItems
+	invoiceStore = stores.Invoices
+	userStore = stores.Users
+	sessionStore = stores.Sessions
+	revocationStore = stores.Revocations
+	reservationStore = stores.Reservations
+	taxRateStore = stores.TaxRates
+	paymentStore = stores.Payments
+	creditNoteStore = stores.CreditNotes
+	serviceChargeStore = stores.ServiceCharges
+	shiftStore = stores.Shifts
+	promotionStore = stores.Promotions
+	discountStore = stores.Discounts
+	counterStore = stores.Counters
+	salesStore = stores.Sales
+	drawerStore = stores.Drawers
+	businessDayStore = stores.BusinessDays
+}
+
+// storeErrorStatus maps a store error onto the HTTP status returned to the client.
+func storeErrorStatus(err error) int {
+	if errors.Is(err, repository.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, repository.ErrConflict) || errors.Is(err, repository.ErrDuplicate) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
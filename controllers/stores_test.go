@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/repository"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// useMemoryStores points the handlers and the authentication middleware at
+// a fresh in-memory backend.
+func useMemoryStores(t *testing.T) *repository.Stores {
+	t.Helper()
+	stores := repository.NewMemoryStores()
+	UseStores(stores)
+	middleware.UseRevocationStore(stores.Revocations)
+	return stores
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// perform sends body as JSON through router and decodes the JSON response
+// into out when it is not nil.
+func perform(t *testing.T, router *gin.Engine, method, path string, body, out any) int {
+	t.Helper()
+	return performAs(t, router, "", method, path, body, out)
+}
+
+// performAs is perform with the access token of a signed in user.
+func performAs(t *testing.T, router *gin.Engine, token, method, path string, body, out any) int {
+	t.Helper()
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("token", token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if out != nil && rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s returned %s: %v", method, path, rec.Body, err)
+		}
+	}
+	return rec.Code
+}
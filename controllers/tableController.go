@@ -2,34 +2,26 @@ package controllers
 
 import (
 	"context"
-	"log"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/tokha04/go-restautant-management/database"
 	"github.com/tokha04/go-restautant-management/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/tokha04/go-restautant-management/repository"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var tableCollection *mongo.Collection = database.OpenCollection(database.Client, "table")
+var tableStore repository.TableStore
 
 func GetTables() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-
-		res, err := tableCollection.Find(context.TODO(), bson.M{})
 		defer cancel()
+
+		allTables, err := tableStore.List(c)
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing table items"})
-		}
-
-		var allTables []bson.M
-		if err = res.All(c, &allTables); err != nil {
-			log.Fatal(err)
+			return
 		}
 
 		ctx.JSON(http.StatusOK, allTables)
@@ -39,13 +31,12 @@ func GetTables() gin.HandlerFunc {
 func GetTable() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 		tableId := ctx.Param("table_id")
-		var table models.Table
 
-		err := tableCollection.FindOne(c, bson.M{"order_id": tableId}).Decode(&table)
-		defer cancel()
+		table, err := tableStore.Get(c, tableId)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the table item"})
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "error occured while fetching the table item"})
 			return
 		}
 
@@ -56,6 +47,7 @@ func GetTable() gin.HandlerFunc {
 func CreateTable() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 		var table models.Table
 
 		if err := ctx.BindJSON(&table); err != nil {
@@ -74,20 +66,19 @@ func CreateTable() gin.HandlerFunc {
 		table.ID = primitive.NewObjectID()
 		table.Table_id = table.ID.Hex()
 
-		res, insertErr := tableCollection.InsertOne(c, table)
-		if insertErr != nil {
+		if err := tableStore.Create(c, table); err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "table item was not created"})
 			return
 		}
 
-		defer cancel()
-		ctx.JSON(http.StatusOK, res)
+		ctx.JSON(http.StatusOK, table)
 	}
 }
 
 func UpdateTable() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 		tableId := ctx.Param("table_id")
 		var table models.Table
 
@@ -96,39 +87,27 @@ func UpdateTable() gin.HandlerFunc {
 			return
 		}
 
-		var updateObj primitive.D
+		foundTable, err := tableStore.Get(c, tableId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "table was not found"})
+			return
+		}
 
 		if table.Number_of_guests != nil {
-			updateObj = append(updateObj, bson.E{"number_of_guests", table.Number_of_guests})
+			foundTable.Number_of_guests = table.Number_of_guests
 		}
 
 		if table.Table_number != nil {
-			updateObj = append(updateObj, bson.E{"table_number", table.Table_number})
+			foundTable.Table_number = table.Table_number
 		}
 
-		table.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		updateObj = append(updateObj, bson.E{"updated_at", table.Updated_at})
-
-		upsert := true
-		filter := bson.M{"table_id": tableId}
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
-		}
+		foundTable.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
-		res, err := orderCollection.UpdateOne(
-			c,
-			filter,
-			bson.D{
-				{"$set", updateObj},
-			},
-			&opt,
-		)
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "table update failed"})
+		if err := tableStore.Update(c, foundTable); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "table update failed"})
 			return
 		}
 
-		defer cancel()
-		ctx.JSON(http.StatusOK, res)
+		ctx.JSON(http.StatusOK, foundTable)
 	}
 }
@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var taxRateStore repository.TaxRateStore
+
+func GetTaxRates() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		allTaxRates, err := taxRateStore.List(c)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing tax rates"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, allTaxRates)
+	}
+}
+
+func GetTaxRate() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		taxRateId := ctx.Param("tax_rate_id")
+
+		taxRate, err := taxRateStore.Get(c, taxRateId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "error occured while fetching the tax rate"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, taxRate)
+	}
+}
+
+func CreateTaxRate() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var taxRate models.TaxRate
+
+		if err := ctx.BindJSON(&taxRate); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := validate.Struct(taxRate)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if msg, ok := checkTaxRateScope(c, taxRate); !ok {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+
+		if taxRate.Active == nil {
+			active := true
+			taxRate.Active = &active
+		}
+
+		taxRate.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		taxRate.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		taxRate.ID = primitive.NewObjectID()
+		taxRate.Tax_rate_id = taxRate.ID.Hex()
+
+		if err := taxRateStore.Create(c, taxRate); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "tax rate was not created"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, taxRate)
+	}
+}
+
+func UpdateTaxRate() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		taxRateId := ctx.Param("tax_rate_id")
+		var taxRate models.TaxRate
+
+		if err := ctx.BindJSON(&taxRate); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		foundTaxRate, err := taxRateStore.Get(c, taxRateId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "tax rate was not found"})
+			return
+		}
+
+		if taxRate.Name != nil {
+			foundTaxRate.Name = taxRate.Name
+		}
+
+		if taxRate.Rate != nil {
+			foundTaxRate.Rate = taxRate.Rate
+		}
+
+		if taxRate.Inclusive != nil {
+			foundTaxRate.Inclusive = taxRate.Inclusive
+		}
+
+		if taxRate.Category != nil {
+			foundTaxRate.Category = taxRate.Category
+		}
+
+		if taxRate.Food_id != nil {
+			foundTaxRate.Food_id = taxRate.Food_id
+		}
+
+		if taxRate.Active != nil {
+			foundTaxRate.Active = taxRate.Active
+		}
+
+		foundTaxRate.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		validationErr := validate.Struct(foundTaxRate)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if msg, ok := checkTaxRateScope(c, foundTaxRate); !ok {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+
+		if err := taxRateStore.Update(c, foundTaxRate); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "tax rate update failed"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, foundTaxRate)
+	}
+}
+
+// checkTaxRateScope makes sure a rate applies to either a food item or a
+// category, never both, and that the food item exists.
+func checkTaxRateScope(c context.Context, taxRate models.TaxRate) (string, bool) {
+	hasFood := taxRate.Food_id != nil && *taxRate.Food_id != ""
+	hasCategory := taxRate.Category != nil && *taxRate.Category != ""
+
+	if hasFood && hasCategory {
+		return "a tax rate applies to a food item or a category, not both", false
+	}
+
+	if hasFood {
+		if _, err := foodStore.Get(c, *taxRate.Food_id); err != nil {
+			return "food was not found", false
+		}
+	}
+
+	return "", true
+}
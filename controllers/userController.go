@@ -2,26 +2,28 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/tokha04/go-restautant-management/database"
 	"github.com/tokha04/go-restautant-management/helpers"
 	"github.com/tokha04/go-restautant-management/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/tokha04/go-restautant-management/repository"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var userCollection *mongo.Collection = database.OpenCollection(database.Client, "user")
+var userStore repository.UserStore
+var sessionStore repository.SessionStore
 
 func GetUsers() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
 		recordPerPage, err := strconv.Atoi(ctx.Query("recordPerPage"))
 		if err != nil || recordPerPage < 1 {
@@ -34,49 +36,37 @@ func GetUsers() gin.HandlerFunc {
 		}
 
 		startIndex := (page - 1) * recordPerPage
-		startIndex, err = strconv.Atoi(ctx.Query("startIndex"))
-
-		matchStage := bson.D{{"$match", bson.D{{}}}}
-		projectStage := bson.D{
-			{
-				"$project", bson.D{
-					{"_id", 0},
-					{"total_count", 1},
-					{"user_items", bson.D{{"$slice", []interface{}{"$data", startIndex, recordPerPage}}}},
-				},
-			},
-		}
-
-		res, err := userCollection.Aggregate(c, mongo.Pipeline{
-			matchStage, projectStage,
-		})
-		defer cancel()
+		if index, err := strconv.Atoi(ctx.Query("startIndex")); err == nil && index >= 0 {
+			startIndex = index
+		}
+
+		allUsers, total, err := userStore.List(c, startIndex, recordPerPage)
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing user items"})
+			return
 		}
 
-		var allUsers []bson.M
-		if err = res.All(c, &allUsers); err != nil {
-			log.Fatal(err)
+		for i := range allUsers {
+			allUsers[i].Password = nil
 		}
 
-		ctx.JSON(http.StatusOK, allUsers[0])
+		ctx.JSON(http.StatusOK, gin.H{"total_count": total, "user_items": allUsers})
 	}
 }
 
 func GetUser() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 		userId := ctx.Param("user_id")
-		var user models.User
 
-		err := userCollection.FindOne(c, bson.M{"user_id": userId}).Decode(&user)
-		defer cancel()
+		user, err := userStore.Get(c, userId)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the user item"})
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "error occured while fetching the user item"})
 			return
 		}
 
+		user.Password = nil
 		ctx.JSON(http.StatusOK, user)
 	}
 }
@@ -84,6 +74,7 @@ func GetUser() gin.HandlerFunc {
 func SignUp() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 		var user models.User
 
 		// convert JSON to struct
@@ -99,32 +90,26 @@ func SignUp() gin.HandlerFunc {
 			return
 		}
 
-		// check if email password is not used
-		count, err := userCollection.CountDocuments(c, bson.M{"email": user.Email})
-		defer cancel()
+		// check if email or phone number is not used
+		exists, err := userStore.ExistsByEmailOrPhone(c, *user.Email, *user.Phone)
 		if err != nil {
-			log.Panic(err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking for the email"})
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking for the email or phone number"})
 			return
 		}
 
-		// hash password
-		password := HashPassword(*user.Password)
-		user.Password = &password
-
-		// check if phone number is not used
-		count, err = userCollection.CountDocuments(c, bson.M{"phone": user.Phone})
-		defer cancel()
-		if err != nil {
-			log.Panic(err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking for the phone number"})
+		if exists {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "this email or phone number already exists"})
 			return
 		}
 
-		if count > 0 {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "this email or phone number already exists"})
-			return
-		}
+		// roles are granted by an admin; the first admin is seeded from the
+		// configuration by EnsureAdmin
+		role := models.RoleWaiter
+		user.Role = &role
+
+		// hash password
+		password := HashPassword(*user.Password)
+		user.Password = &password
 
 		// extra details
 		user.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
@@ -132,28 +117,31 @@ func SignUp() gin.HandlerFunc {
 		user.ID = primitive.NewObjectID()
 		user.User_id = user.ID.Hex()
 
-		// generate token and refresh token
-		token, refreshToken, _ := helpers.GenerateAllTokens(*user.Email, *user.First_name, *user.Last_name, user.User_id)
-		user.Token = &token
-		user.Refresh_token = &refreshToken
-
 		// insert a new user
-		res, insertErr := userCollection.InsertOne(c, user)
-		if insertErr != nil {
+		if err := userStore.Create(c, user); err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "user item was not created"})
 			return
 		}
 
-		defer cancel()
-		ctx.JSON(http.StatusOK, res)
+		// generate token and refresh token
+		token, refreshToken, err := startSession(c, user)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while generating tokens"})
+			return
+		}
+		user.Token = &token
+		user.Refresh_token = &refreshToken
+
+		user.Password = nil
+		ctx.JSON(http.StatusOK, user)
 	}
 }
 
 func Login() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 		var user models.User
-		var foundUser models.User
 
 		// convert JSON to struct
 		if err := ctx.BindJSON(&user); err != nil {
@@ -161,32 +149,232 @@ func Login() gin.HandlerFunc {
 			return
 		}
 
+		if user.Email == nil || user.Password == nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "email and password are required"})
+			return
+		}
+
 		// find a user
-		err := userCollection.FindOne(c, bson.M{"email": user.Email}).Decode(&foundUser)
-		defer cancel()
+		foundUser, err := userStore.GetByEmail(c, *user.Email)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "user not found, login seems to be incorrect"})
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "user not found, login seems to be incorrect"})
 			return
 		}
 
 		// verify the password
 		passwordIsValid, msg := VerifyPassword(*user.Password, *foundUser.Password)
-		defer cancel()
 		if passwordIsValid != true {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": msg})
 			return
 		}
 
-		// generate tokens
-		token, refreshToken, _ := helpers.GenerateAllTokens(*foundUser.Email, *foundUser.First_name, *foundUser.Last_name, foundUser.User_id)
-
-		// update tokens
-		helpers.UpdateAllTokens(token, refreshToken, foundUser.User_id)
+		// generate and save tokens
+		token, refreshToken, err := startSession(c, foundUser)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while generating tokens"})
+			return
+		}
 
+		foundUser.Token = &token
+		foundUser.Refresh_token = &refreshToken
+		foundUser.Password = nil
 		ctx.JSON(http.StatusOK, foundUser)
 	}
 }
 
+// RefreshToken exchanges a refresh token for a new token pair. The presented
+// token is rotated out; presenting it again revokes its whole session, since
+// that means it was stolen or replayed.
+func RefreshToken() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var body struct {
+			Refresh_token string `json:"refresh_token" validate:"required"`
+		}
+
+		if err := ctx.BindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := validate.Struct(body)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		claims, msg := helpers.ValidateToken(body.Refresh_token)
+		if msg != "" {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": msg})
+			return
+		}
+
+		if claims.Token_type != helpers.RefreshToken || claims.Session_id == "" {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "a refresh token is required"})
+			return
+		}
+
+		session, err := sessionStore.Get(c, claims.Session_id)
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "session was not found"})
+			return
+		}
+
+		if session.Revoked || session.User_id != claims.Uid {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "session has been revoked"})
+			return
+		}
+
+		currentHash := helpers.HashToken(body.Refresh_token)
+		if session.Refresh_token_hash != currentHash {
+			revokeReusedSession(c, session.Session_id)
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token was already used, the session has been revoked"})
+			return
+		}
+
+		user, err := userStore.Get(c, claims.Uid)
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "user was not found"})
+			return
+		}
+
+		token, refreshToken, err := helpers.GenerateAllTokens(*user.Email, *user.First_name, *user.Last_name, user.User_id, userRole(user), session.Session_id)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while generating tokens"})
+			return
+		}
+
+		expiresAt := time.Now().Add(helpers.RefreshTokenTTL)
+		err = sessionStore.Rotate(c, session.Session_id, currentHash, helpers.HashToken(refreshToken), expiresAt)
+		if errors.Is(err, repository.ErrStaleRefreshToken) {
+			// another request rotated the same token first
+			revokeReusedSession(c, session.Session_id)
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token was already used, the session has been revoked"})
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while rotating the refresh token"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"token": token, "refresh_token": refreshToken})
+	}
+}
+
+// startSession opens a new refresh token family for the user and returns its
+// first token pair.
+func startSession(c context.Context, user models.User) (string, string, error) {
+	var session models.Session
+	session.ID = primitive.NewObjectID()
+	session.Session_id = session.ID.Hex()
+	session.User_id = user.User_id
+
+	token, refreshToken, err := helpers.GenerateAllTokens(*user.Email, *user.First_name, *user.Last_name, user.User_id, userRole(user), session.Session_id)
+	if err != nil {
+		return "", "", err
+	}
+
+	session.Refresh_token_hash = helpers.HashToken(refreshToken)
+	session.Expires_at = time.Now().Add(helpers.RefreshTokenTTL)
+	session.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	session.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	if err := sessionStore.Create(c, session); err != nil {
+		return "", "", err
+	}
+
+	return token, refreshToken, nil
+}
+
+// revokeReusedSession ends the whole token family of a session whose refresh
+// token was replayed, including access tokens minted from a stolen token.
+func revokeReusedSession(c context.Context, sessionId string) {
+	if err := endSession(c, sessionId, "refresh token reuse"); err != nil {
+		log.Printf("could not revoke session %s after refresh token reuse: %v", sessionId, err)
+	}
+}
+
+func userRole(user models.User) string {
+	if user.Role == nil {
+		return ""
+	}
+	return *user.Role
+}
+
+func UpdateUserRole() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		userId := ctx.Param("user_id")
+
+		var body struct {
+			Role string `json:"role" validate:"required,eq=ADMIN|eq=MANAGER|eq=WAITER|eq=CHEF|eq=CASHIER"`
+		}
+
+		if err := ctx.BindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := validate.Struct(body)
+		if validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if err := userStore.UpdateRole(c, userId, body.Role); err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "user role update failed"})
+			return
+		}
+
+		// tokens carry the role they were issued with, so the user logs in
+		// again to pick up the new one
+		if _, err := endUserSessions(c, userId, "role changed to "+body.Role); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "role was changed but the user's sessions could not be ended"})
+			return
+		}
+
+		user, err := userStore.Get(c, userId)
+		if err != nil {
+			ctx.JSON(storeErrorStatus(err), gin.H{"error": "error occured while fetching the user item"})
+			return
+		}
+
+		user.Password = nil
+		ctx.JSON(http.StatusOK, user)
+	}
+}
+
+// EnsureAdmin makes the user with the given email an admin, creating the
+// account with the password if there is none. It bootstraps a fresh install
+// and recovers one that has lost its admins; signing up never grants a role.
+func EnsureAdmin(email, password string) (bool, error) {
+	var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	if _, err := userStore.GetByEmail(c, email); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return false, err
+	} else if err != nil && password == "" {
+		return false, fmt.Errorf("there is no user %s to make an admin, a password is needed to create one", email)
+	}
+
+	firstName, lastName := "Admin", "Admin"
+	role := models.RoleAdmin
+	user := models.User{First_name: &firstName, Last_name: &lastName, Email: &email, Role: &role}
+	if password != "" {
+		hashed := HashPassword(password)
+		user.Password = &hashed
+	}
+	user.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	user.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	user.ID = primitive.NewObjectID()
+	user.User_id = user.ID.Hex()
+
+	return userStore.EnsureAdmin(c, user)
+}
+
 func HashPassword(password string) string {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
 	if err != nil {
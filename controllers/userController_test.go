@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/helpers"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type tokenPair struct {
+	Token         string `json:"token"`
+	Refresh_token string `json:"refresh_token"`
+}
+
+// signedIn stores a user with the given role and opens a session for them.
+func signedIn(t *testing.T, role string) (models.User, tokenPair) {
+	t.Helper()
+	helpers.SECRET_KEY = "test-secret"
+
+	user := models.User{
+		ID:         primitive.NewObjectID(),
+		First_name: ptr("Ada"),
+		Last_name:  ptr("Lovelace"),
+		Role:       ptr(role),
+	}
+	user.User_id = user.ID.Hex()
+	user.Email = ptr(user.User_id + "@example.com")
+	if err := userStore.Create(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+
+	token, refreshToken, err := startSession(context.Background(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user, tokenPair{Token: token, Refresh_token: refreshToken}
+}
+
+// authenticated serves GET /whoami behind the authentication middleware,
+// answering with the role of the caller's token.
+func authenticated(router *gin.Engine) *gin.Engine {
+	router.GET("/whoami", middleware.Authentication(), func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"role": ctx.GetString("role")})
+	})
+	return router
+}
+
+func TestSignUpMakesWaiters(t *testing.T) {
+	useMemoryStores(t)
+	helpers.SECRET_KEY = "test-secret"
+	router := gin.New()
+	router.POST("/users/signup", SignUp())
+
+	var user models.User
+	code := perform(t, router, http.MethodPost, "/users/signup", gin.H{
+		"first_name": "Grace",
+		"last_name":  "Hopper",
+		"email":      "grace@example.com",
+		"phone":      "+10000000000",
+		"password":   "secret1",
+		"role":       models.RoleAdmin,
+	}, &user)
+	if code != http.StatusOK {
+		t.Fatalf("signing up returned %d, want 200", code)
+	}
+	if user.Role == nil || *user.Role != models.RoleWaiter {
+		t.Errorf("signed up as %v, want %s whatever was asked for", user.Role, models.RoleWaiter)
+	}
+	if user.Password != nil {
+		t.Errorf("the password hash was sent back")
+	}
+}
+
+func TestUpdateUserRoleEndsSessions(t *testing.T) {
+	useMemoryStores(t)
+	manager, pair := signedIn(t, models.RoleManager)
+	router := authenticated(gin.New())
+	router.PATCH("/users/:user_id/role", UpdateUserRole())
+
+	if code := performAs(t, router, pair.Token, http.MethodGet, "/whoami", nil, nil); code != http.StatusOK {
+		t.Fatalf("the manager's token got %d, want 200", code)
+	}
+
+	var updated models.User
+	if code := perform(t, router, http.MethodPatch, "/users/"+manager.User_id+"/role", gin.H{"role": models.RoleWaiter}, &updated); code != http.StatusOK {
+		t.Fatalf("changing the role returned %d, want 200", code)
+	}
+	if updated.Role == nil || *updated.Role != models.RoleWaiter {
+		t.Errorf("role is %v, want %s", updated.Role, models.RoleWaiter)
+	}
+
+	// the token still says MANAGER, so it must not be accepted any more
+	if code := performAs(t, router, pair.Token, http.MethodGet, "/whoami", nil, nil); code != http.StatusUnauthorized {
+		t.Errorf("the demoted manager's token got %d, want 401", code)
+	}
+	sessions, err := sessionStore.ListActiveByUser(context.Background(), manager.User_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 0 {
+		t.Errorf("the demoted manager still has %d live session(s)", len(sessions))
+	}
+}
+
+func refreshRouter() *gin.Engine {
+	router := gin.New()
+	router.POST("/users/refresh", RefreshToken())
+	return router
+}
+
+func sessionOf(t *testing.T, refreshToken string) models.Session {
+	t.Helper()
+	claims, msg := helpers.ValidateToken(refreshToken)
+	if msg != "" {
+		t.Fatal(msg)
+	}
+	session, err := sessionStore.Get(context.Background(), claims.Session_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return session
+}
+
+func TestRefreshTokenRotates(t *testing.T) {
+	useMemoryStores(t)
+	_, first := signedIn(t, models.RoleWaiter)
+	router := refreshRouter()
+
+	var second tokenPair
+	if code := perform(t, router, http.MethodPost, "/users/refresh", gin.H{"refresh_token": first.Refresh_token}, &second); code != http.StatusOK {
+		t.Fatalf("refreshing returned %d, want 200", code)
+	}
+	if second.Refresh_token == "" || second.Refresh_token == first.Refresh_token {
+		t.Fatalf("refreshing did not issue a new refresh token")
+	}
+
+	session := sessionOf(t, second.Refresh_token)
+	if session.Refresh_token_hash != helpers.HashToken(second.Refresh_token) {
+		t.Errorf("the session does not hold the new refresh token")
+	}
+	if session.Revoked {
+		t.Errorf("the session was revoked by a regular refresh")
+	}
+
+	var third tokenPair
+	if code := perform(t, router, http.MethodPost, "/users/refresh", gin.H{"refresh_token": second.Refresh_token}, &third); code != http.StatusOK {
+		t.Fatalf("refreshing with the new token returned %d, want 200", code)
+	}
+}
+
+func TestRefreshTokenReuseRevokesTheSession(t *testing.T) {
+	useMemoryStores(t)
+	_, first := signedIn(t, models.RoleWaiter)
+	router := refreshRouter()
+
+	var second tokenPair
+	if code := perform(t, router, http.MethodPost, "/users/refresh", gin.H{"refresh_token": first.Refresh_token}, &second); code != http.StatusOK {
+		t.Fatalf("refreshing returned %d, want 200", code)
+	}
+
+	// the first token was used already; whoever holds it may have stolen it
+	if code := perform(t, router, http.MethodPost, "/users/refresh", gin.H{"refresh_token": first.Refresh_token}, nil); code != http.StatusUnauthorized {
+		t.Fatalf("reusing a refresh token returned %d, want 401", code)
+	}
+
+	session := sessionOf(t, second.Refresh_token)
+	if !session.Revoked {
+		t.Fatalf("reusing a refresh token did not revoke the session")
+	}
+
+	// the whole family is gone, the token issued last included
+	if code := perform(t, router, http.MethodPost, "/users/refresh", gin.H{"refresh_token": second.Refresh_token}, nil); code != http.StatusUnauthorized {
+		t.Errorf("refreshing a revoked session returned %d, want 401", code)
+	}
+}
+
+func TestRefreshTokenReuseRevokesAccessTokens(t *testing.T) {
+	useMemoryStores(t)
+	_, first := signedIn(t, models.RoleWaiter)
+	router := authenticated(refreshRouter())
+
+	// the thief refreshes first and gets a working access token
+	var stolen tokenPair
+	if code := perform(t, router, http.MethodPost, "/users/refresh", gin.H{"refresh_token": first.Refresh_token}, &stolen); code != http.StatusOK {
+		t.Fatalf("refreshing returned %d, want 200", code)
+	}
+	if code := performAs(t, router, stolen.Token, http.MethodGet, "/whoami", nil, nil); code != http.StatusOK {
+		t.Fatalf("the refreshed access token got %d, want 200", code)
+	}
+
+	// the victim replays the same refresh token
+	if code := perform(t, router, http.MethodPost, "/users/refresh", gin.H{"refresh_token": first.Refresh_token}, nil); code != http.StatusUnauthorized {
+		t.Fatalf("reusing a refresh token returned %d, want 401", code)
+	}
+
+	for name, token := range map[string]string{"stolen access token": stolen.Token, "original access token": first.Token} {
+		if code := performAs(t, router, token, http.MethodGet, "/whoami", nil, nil); code != http.StatusUnauthorized {
+			t.Errorf("the %s got %d after the reuse, want 401", name, code)
+		}
+	}
+}
+
+func TestRefreshTokenRejectsAccessTokens(t *testing.T) {
+	useMemoryStores(t)
+	_, pair := signedIn(t, models.RoleWaiter)
+
+	if code := perform(t, refreshRouter(), http.MethodPost, "/users/refresh", gin.H{"refresh_token": pair.Token}, nil); code != http.StatusUnauthorized {
+		t.Errorf("refreshing with an access token returned %d, want 401", code)
+	}
+	if sessionOf(t, pair.Refresh_token).Revoked {
+		t.Errorf("refreshing with an access token revoked the session")
+	}
+}
+
+func TestRotateOnlyOnce(t *testing.T) {
+	useMemoryStores(t)
+	_, pair := signedIn(t, models.RoleWaiter)
+	session := sessionOf(t, pair.Refresh_token)
+	current := helpers.HashToken(pair.Refresh_token)
+	expiresAt := time.Now().Add(helpers.RefreshTokenTTL)
+
+	// two requests that both read the session before either rotated it
+	if err := sessionStore.Rotate(context.Background(), session.Session_id, current, "next", expiresAt); err != nil {
+		t.Fatal(err)
+	}
+	err := sessionStore.Rotate(context.Background(), session.Session_id, current, "other", expiresAt)
+	if !errors.Is(err, repository.ErrStaleRefreshToken) {
+		t.Fatalf("rotating a stale token returned %v, want ErrStaleRefreshToken", err)
+	}
+
+	if err := sessionStore.Revoke(context.Background(), session.Session_id, "test"); err != nil {
+		t.Fatal(err)
+	}
+	err = sessionStore.Rotate(context.Background(), session.Session_id, "next", "after", expiresAt)
+	if !errors.Is(err, repository.ErrStaleRefreshToken) {
+		t.Errorf("rotating a revoked session returned %v, want ErrStaleRefreshToken", err)
+	}
+}
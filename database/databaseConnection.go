@@ -2,34 +2,251 @@ package database
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
-func DBinstance() *mongo.Client {
-	MongoDb := "mongodb://localhost:27017"
-	fmt.Print(MongoDb)
+// Config holds the MongoDB connection settings. ConfigFromEnv fills it from
+// MONGODB_* environment variables so every deployment can point at its own
+// cluster without touching the source.
+type Config struct {
+	URI                    string
+	Database               string
+	Username               string
+	Password               string
+	AuthSource             string
+	MaxPoolSize            uint64
+	MinPoolSize            uint64
+	ConnectTimeout         time.Duration
+	ServerSelectionTimeout time.Duration
+	TLSCAFiles             []string
+	ConnectRetries         int
+	RetryBackoff           time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		URI:                    "mongodb://localhost:27017",
+		Database:               "restaurant",
+		MaxPoolSize:            100,
+		ConnectTimeout:         10 * time.Second,
+		ServerSelectionTimeout: 10 * time.Second,
+		ConnectRetries:         5,
+		RetryBackoff:           time.Second,
+	}
+}
+
+// ConfigFromEnv starts from DefaultConfig and overrides every setting that has
+// an environment variable. TLS CA files are a comma separated list.
+func ConfigFromEnv() (Config, error) {
+	cfg := DefaultConfig()
+
+	if v := os.Getenv("MONGODB_URI"); v != "" {
+		cfg.URI = v
+	}
+	if v := os.Getenv("MONGODB_DATABASE"); v != "" {
+		cfg.Database = v
+	}
+	cfg.Username = os.Getenv("MONGODB_USERNAME")
+	cfg.Password = os.Getenv("MONGODB_PASSWORD")
+	cfg.AuthSource = os.Getenv("MONGODB_AUTH_SOURCE")
+
+	if v := os.Getenv("MONGODB_TLS_CA_FILES"); v != "" {
+		for _, file := range strings.Split(v, ",") {
+			if file = strings.TrimSpace(file); file != "" {
+				cfg.TLSCAFiles = append(cfg.TLSCAFiles, file)
+			}
+		}
+	}
+
+	var err error
+	if cfg.MaxPoolSize, err = envUint("MONGODB_MAX_POOL_SIZE", cfg.MaxPoolSize); err != nil {
+		return cfg, err
+	}
+	if cfg.MinPoolSize, err = envUint("MONGODB_MIN_POOL_SIZE", cfg.MinPoolSize); err != nil {
+		return cfg, err
+	}
+	if cfg.ConnectTimeout, err = envDuration("MONGODB_CONNECT_TIMEOUT", cfg.ConnectTimeout); err != nil {
+		return cfg, err
+	}
+	if cfg.ServerSelectionTimeout, err = envDuration("MONGODB_SERVER_SELECTION_TIMEOUT", cfg.ServerSelectionTimeout); err != nil {
+		return cfg, err
+	}
+	if cfg.RetryBackoff, err = envDuration("MONGODB_RETRY_BACKOFF", cfg.RetryBackoff); err != nil {
+		return cfg, err
+	}
+
+	retries, err := envUint("MONGODB_CONNECT_RETRIES", uint64(cfg.ConnectRetries))
+	if err != nil {
+		return cfg, err
+	}
+	cfg.ConnectRetries = int(retries)
+
+	if cfg.MinPoolSize > cfg.MaxPoolSize && cfg.MaxPoolSize != 0 {
+		return cfg, fmt.Errorf("MONGODB_MIN_POOL_SIZE (%d) is larger than MONGODB_MAX_POOL_SIZE (%d)", cfg.MinPoolSize, cfg.MaxPoolSize)
+	}
+
+	return cfg, nil
+}
+
+func envUint(key string, fallback uint64) (uint64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a non-negative integer, got %q", key, v)
+	}
+	return n, nil
+}
+
+func envDuration(key string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration such as 10s, got %q", key, v)
+	}
+	return d, nil
+}
+
+func (cfg Config) clientOptions() (*options.ClientOptions, error) {
+	opts := options.Client().
+		ApplyURI(cfg.URI).
+		SetMaxPoolSize(cfg.MaxPoolSize).
+		SetMinPoolSize(cfg.MinPoolSize).
+		SetConnectTimeout(cfg.ConnectTimeout).
+		SetServerSelectionTimeout(cfg.ServerSelectionTimeout)
+
+	if cfg.Username != "" || cfg.AuthSource != "" {
+		var credential options.Credential
+		if opts.Auth != nil {
+			credential = *opts.Auth
+		}
+		if cfg.Username != "" {
+			credential.Username = cfg.Username
+			credential.Password = cfg.Password
+		}
+		if cfg.AuthSource != "" {
+			credential.AuthSource = cfg.AuthSource
+		}
+		if credential.Username == "" {
+			return nil, fmt.Errorf("MONGODB_AUTH_SOURCE is set but no username was given in MONGODB_URI or MONGODB_USERNAME")
+		}
+		opts.SetAuth(credential)
+	}
+
+	if len(cfg.TLSCAFiles) > 0 {
+		pool := x509.NewCertPool()
+		for _, file := range cfg.TLSCAFiles {
+			pem, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("could not read TLS CA file %s: %w", file, err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("TLS CA file %s does not contain any PEM certificates", file)
+			}
+		}
+		opts.SetTLSConfig(&tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12})
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid MongoDB settings: %w", err)
+	}
+
+	return opts, nil
+}
+
+// Connect opens a client and pings the primary, retrying with exponential
+// backoff so the API can start while the cluster is still coming up.
+func Connect(ctx context.Context, cfg Config) (*mongo.Client, error) {
+	opts, err := cfg.clientOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := cfg.ConnectRetries + 1
+	backoff := cfg.RetryBackoff
+
+	for attempt := 1; ; attempt++ {
+		client, err := ping(ctx, cfg, opts)
+		if err == nil {
+			log.Printf("connected to mongodb at %s (database %q)", redactURI(cfg.URI), cfg.Database)
+			return client, nil
+		}
+
+		if attempt >= attempts {
+			return nil, fmt.Errorf("could not connect to mongodb at %s after %d attempt(s): %w", redactURI(cfg.URI), attempt, err)
+		}
+
+		log.Printf("mongodb connection attempt %d/%d failed: %v; retrying in %s", attempt, attempts, err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("gave up connecting to mongodb at %s: %w", redactURI(cfg.URI), ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func ping(ctx context.Context, cfg Config, opts *options.ClientOptions) (*mongo.Client, error) {
+	c, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(MongoDb))
+	client, err := mongo.Connect(c, opts)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	fmt.Println("connected to mongodb")
-	return client
+	if err := client.Ping(c, readpref.Primary()); err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+
+	return client, nil
 }
 
-var Client *mongo.Client = DBinstance()
+// uriCredentials matches everything between the scheme and the last @ of a
+// connection string, which is where its user name and password are.
+var uriCredentials = regexp.MustCompile(`^([^:/]+://).*@`)
+
+// redactURI hides the password so connection strings can be logged. A
+// password with an unescaped /, # or % makes the URI unparseable; the
+// credentials are then cut out whole.
+func redactURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uriCredentials.ReplaceAllString(uri, "${1}xxxxx@")
+	}
+	if u.User == nil {
+		return uri
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	}
+	return u.String()
+}
 
-func OpenCollection(client *mongo.Client, collectionName string) *mongo.Collection {
-	var collection *mongo.Collection = client.Database("restaurant").Collection(collectionName)
+func OpenCollection(client *mongo.Client, databaseName string, collectionName string) *mongo.Collection {
+	var collection *mongo.Collection = client.Database(databaseName).Collection(collectionName)
 
 	return collection
 }
@@ -1,17 +1,22 @@
 package helpers
 
 import (
-	"context"
-	"log"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"os"
 	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
-	"github.com/tokha04/go-restautant-management/database"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	AccessToken  = "access"
+	RefreshToken = "refresh"
+
+	AccessTokenTTL  = 24 * time.Hour
+	RefreshTokenTTL = 168 * time.Hour
 )
 
 type SignedDetails struct {
@@ -19,72 +24,64 @@ type SignedDetails struct {
 	First_name string
 	Last_name  string
 	Uid        string
+	Role       string
+	Session_id string
+	Token_type string
 	jwt.StandardClaims
 }
 
-var userCollection *mongo.Collection = database.OpenCollection(database.Client, "user")
-
 var SECRET_KEY string = os.Getenv("SECRET_KEY")
 
-func GenerateAllTokens(email string, firstName string, lastName string, uid string) (signedToken string, signedRefreshToken string, err error) {
+// GenerateAllTokens signs an access and a refresh token for the given
+// session. Both carry a unique id so two pairs issued in the same second
+// never collide.
+func GenerateAllTokens(email string, firstName string, lastName string, uid string, role string, sessionId string) (signedToken string, signedRefreshToken string, err error) {
+	now := time.Now().Local()
+
 	claims := &SignedDetails{
 		Email:      email,
 		First_name: firstName,
 		Last_name:  lastName,
 		Uid:        uid,
+		Role:       role,
+		Session_id: sessionId,
+		Token_type: AccessToken,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(24)).Unix(),
+			Id:        primitive.NewObjectID().Hex(),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(AccessTokenTTL).Unix(),
 		},
 	}
 
 	refreshClaims := &SignedDetails{
+		Uid:        uid,
+		Session_id: sessionId,
+		Token_type: RefreshToken,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(168)).Unix(),
+			Id:        primitive.NewObjectID().Hex(),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(RefreshTokenTTL).Unix(),
 		},
 	}
 
 	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(SECRET_KEY))
-	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims).SignedString([]byte(SECRET_KEY))
 	if err != nil {
-		log.Panic(err)
 		return
 	}
 
-	return token, refreshToken, err
-}
-
-func UpdateAllTokens(signedToken string, signedRefreshToken string, userId string) {
-	var c, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-
-	var updateObj primitive.D
-
-	updateObj = append(updateObj, bson.E{"token", signedToken})
-	updateObj = append(updateObj, bson.E{"refresh_token", signedRefreshToken})
-
-	Updated_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-	updateObj = append(updateObj, bson.E{"updated_at", Updated_at})
-
-	upsert := true
-	filter := bson.M{"user_id": userId}
-	opt := options.UpdateOptions{
-		Upsert: &upsert,
-	}
-
-	_, err := userCollection.UpdateOne(
-		c,
-		filter,
-		bson.D{
-			{"$set", updateObj},
-		},
-		&opt,
-	)
-	defer cancel()
+	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims).SignedString([]byte(SECRET_KEY))
 	if err != nil {
-		log.Panic(err)
 		return
 	}
 
-	return
+	return token, refreshToken, err
+}
+
+// HashToken returns the value stored server side for a refresh token, so a
+// leaked database does not leak usable tokens.
+func HashToken(signedToken string) string {
+	sum := sha256.Sum256([]byte(signedToken))
+	return hex.EncodeToString(sum[:])
 }
 
 func ValidateToken(signedToken string) (claims *SignedDetails, msg string) {
@@ -92,22 +89,27 @@ func ValidateToken(signedToken string) (claims *SignedDetails, msg string) {
 		signedToken,
 		&SignedDetails{},
 		func(t *jwt.Token) (interface{}, error) {
+			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+			}
 			return []byte(SECRET_KEY), nil
 		},
 	)
+	if err != nil {
+		msg = err.Error()
+		return
+	}
 
 	// token is invalid
 	claims, ok := token.Claims.(*SignedDetails)
-	if !ok {
+	if !ok || !token.Valid {
 		msg = "token is invalid"
-		msg = err.Error()
 		return
 	}
 
 	// token is expired
 	if claims.ExpiresAt < time.Now().Local().Unix() {
 		msg = "token is expired"
-		msg = err.Error()
 		return
 	}
 
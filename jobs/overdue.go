@@ -0,0 +1,48 @@
+// Package jobs holds the background jobs the API runs on a schedule.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/tokha04/go-restautant-management/notify"
+	"github.com/tokha04/go-restautant-management/repository"
+	"github.com/tokha04/go-restautant-management/scheduler"
+)
+
+// OverdueInvoices marks unpaid invoices past their due date as OVERDUE and
+// notifies staff about each of them. Marking is conditional, so when several
+// API processes run the job only one of them notifies per invoice.
+func OverdueInvoices(invoices repository.InvoiceStore, notifier notify.Notifier) scheduler.Job {
+	return func(ctx context.Context) error {
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		due, err := invoices.ListOverdue(ctx, now)
+		if err != nil {
+			return fmt.Errorf("listing overdue invoices: %w", err)
+		}
+
+		marked := 0
+		for _, candidate := range due {
+			invoice, err := invoices.MarkOverdue(ctx, candidate.Invoice_id, now)
+			if errors.Is(err, repository.ErrConflict) {
+				// paid, voided or marked by another process meanwhile
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("marking invoice %s overdue: %w", candidate.Invoice_id, err)
+			}
+
+			notifier.Notify(notify.Event{Type: notify.EventInvoiceOverdue, Subject: invoice.Invoice_id, Data: invoice, At: now})
+			marked++
+		}
+
+		if marked > 0 {
+			log.Printf("jobs: marked %d invoice(s) overdue", marked)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,77 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/notify"
+	"github.com/tokha04/go-restautant-management/repository"
+)
+
+// recorder is a notifier that keeps what it is told.
+type recorder struct {
+	events []notify.Event
+}
+
+func (r *recorder) Notify(event notify.Event) {
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) Subscribe() (<-chan notify.Event, func()) {
+	return nil, func() {}
+}
+
+func TestOverdueInvoices(t *testing.T) {
+	stores := repository.NewMemoryStores()
+	yesterday := time.Now().Add(-24 * time.Hour)
+	tomorrow := time.Now().Add(24 * time.Hour)
+
+	for _, invoice := range []models.Invoice{
+		{Invoice_id: "late", Payment_status: ptr(models.InvoicePending), Payment_due_date: yesterday},
+		{Invoice_id: "part", Payment_status: ptr(models.InvoicePartiallyPaid), Payment_due_date: yesterday},
+		{Invoice_id: "paid", Payment_status: ptr(models.InvoicePaid), Payment_due_date: yesterday},
+		{Invoice_id: "voided", Payment_status: ptr(models.InvoiceVoided), Payment_due_date: yesterday},
+		{Invoice_id: "due", Payment_status: ptr(models.InvoicePending), Payment_due_date: tomorrow},
+	} {
+		if err := stores.Invoices.Create(context.Background(), invoice); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notifier := &recorder{}
+	job := OverdueInvoices(stores.Invoices, notifier)
+
+	// a second run finds nothing left to mark
+	for range 2 {
+		if err := job(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notified := map[string]bool{}
+	for _, event := range notifier.events {
+		if event.Type != notify.EventInvoiceOverdue {
+			t.Errorf("got a %s event, want %s", event.Type, notify.EventInvoiceOverdue)
+		}
+		notified[event.Subject] = true
+	}
+	if len(notifier.events) != 2 || !notified["late"] || !notified["part"] {
+		t.Errorf("staff were told about %v, want the late unpaid invoices once each", notified)
+	}
+
+	for id, want := range map[string]string{"late": models.InvoiceOverdue, "paid": models.InvoicePaid, "due": models.InvoicePending} {
+		invoice, err := stores.Invoices.Get(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *invoice.Payment_status != want {
+			t.Errorf("invoice %s is %s, want %s", id, *invoice.Payment_status, want)
+		}
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
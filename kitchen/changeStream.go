@@ -0,0 +1,104 @@
+package kitchen
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// changeStreamFeed turns MongoDB change stream notifications on the order item
+// collection into events, so every API process sees writes made by any of
+// them. Publish is a no-op because the write itself shows up on the stream.
+type changeStreamFeed struct {
+	*Broker
+}
+
+type changeEvent struct {
+	OperationType string           `bson:"operationType"`
+	FullDocument  models.OrderItem `bson:"fullDocument"`
+}
+
+// NewChangeStreamFeed opens a change stream on the collection. It fails when
+// the server does not support change streams (a standalone mongod), in which
+// case callers should fall back to NewBroker.
+func NewChangeStreamFeed(ctx context.Context, collection *mongo.Collection) (Feed, error) {
+	stream, err := watch(ctx, collection, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := &changeStreamFeed{Broker: NewBroker()}
+	go feed.run(ctx, collection, stream)
+
+	return feed, nil
+}
+
+func (f *changeStreamFeed) Publish(event Event) {}
+
+func watch(ctx context.Context, collection *mongo.Collection, resumeToken bson.Raw) (*mongo.ChangeStream, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"operationType": bson.M{"$in": []string{"insert", "update", "replace"}}}},
+	}
+
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if resumeToken != nil {
+		opts.SetResumeAfter(resumeToken)
+	}
+
+	return collection.Watch(ctx, pipeline, opts)
+}
+
+func (f *changeStreamFeed) run(ctx context.Context, collection *mongo.Collection, stream *mongo.ChangeStream) {
+	backoff := time.Second
+
+	for {
+		for stream.Next(ctx) {
+			backoff = time.Second
+
+			var change changeEvent
+			if err := stream.Decode(&change); err != nil {
+				log.Printf("kitchen: could not decode change event: %v", err)
+				continue
+			}
+
+			event := Event{Type: EventUpdated, Order_item: change.FullDocument}
+			switch {
+			case change.OperationType == "insert":
+				event.Type = EventCreated
+			case change.FullDocument.Kitchen_status == models.KitchenCancelled:
+				event.Type = EventCancelled
+			}
+			f.Broker.Publish(event)
+		}
+
+		resumeToken := stream.ResumeToken()
+		err := stream.Err()
+		stream.Close(context.Background())
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		for {
+			log.Printf("kitchen: change stream stopped (%v), reopening in %s", err, backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+
+			stream, err = watch(ctx, collection, resumeToken)
+			if err == nil {
+				break
+			}
+			if backoff < time.Minute {
+				backoff *= 2
+			}
+		}
+	}
+}
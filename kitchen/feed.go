@@ -0,0 +1,92 @@
+package kitchen
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tokha04/go-restautant-management/models"
+)
+
+const (
+	EventCreated   = "CREATED"
+	EventUpdated   = "UPDATED"
+	EventCancelled = "CANCELLED"
+)
+
+// Event tells kitchen screens that an order item was added, changed or
+// cancelled.
+type Event struct {
+	Type       string           `json:"type"`
+	Order_item models.OrderItem `json:"order_item"`
+	At         time.Time        `json:"at"`
+}
+
+// Feed fans order item events out to every subscribed kitchen screen.
+type Feed interface {
+	// Publish announces a change made by this process.
+	Publish(event Event)
+	// Subscribe returns a channel of events and a function that must be
+	// called once the subscriber goes away. The channel is closed when the
+	// subscriber falls too far behind; it should then reload the pending
+	// items and subscribe again.
+	Subscribe() (<-chan Event, func())
+}
+
+const subscriberBuffer = 64
+
+// Broker is an in-process Feed. It is all a single API process needs.
+type Broker struct {
+	mu          sync.RWMutex
+	subscribers map[chan Event]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{subscribers: map[chan Event]struct{}{}}
+}
+
+func (b *Broker) Publish(event Event) {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	slow := []chan Event{}
+
+	b.mu.RLock()
+	for ch := range b.subscribers {
+		// a screen that stopped reading must not stall the others
+		select {
+		case ch <- event:
+		default:
+			slow = append(slow, ch)
+		}
+	}
+	b.mu.RUnlock()
+
+	// a screen that missed an event would silently lose a ticket, so it is
+	// cut off instead and reconnects
+	for _, ch := range slow {
+		b.drop(ch)
+	}
+}
+
+func (b *Broker) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() { b.drop(ch) }
+}
+
+// drop unsubscribes ch and closes it, unless that was already done.
+func (b *Broker) drop(ch chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[ch]; !ok {
+		return
+	}
+	delete(b.subscribers, ch)
+	close(ch)
+}
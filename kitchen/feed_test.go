@@ -0,0 +1,102 @@
+package kitchen
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/tokha04/go-restautant-management/models"
+)
+
+func event(orderItemId string) Event {
+	return Event{Type: EventCreated, Order_item: models.OrderItem{Order_item_id: orderItemId}}
+}
+
+func TestBrokerFansOut(t *testing.T) {
+	broker := NewBroker()
+	first, unsubscribeFirst := broker.Subscribe()
+	defer unsubscribeFirst()
+	second, unsubscribeSecond := broker.Subscribe()
+	defer unsubscribeSecond()
+
+	broker.Publish(event("a"))
+
+	for i, ch := range []<-chan Event{first, second} {
+		got := <-ch
+		if got.Order_item.Order_item_id != "a" {
+			t.Errorf("subscriber %d got %+v, want item a", i, got)
+		}
+		if got.At.IsZero() {
+			t.Errorf("subscriber %d got an event without a time", i)
+		}
+	}
+}
+
+func TestBrokerUnsubscribe(t *testing.T) {
+	broker := NewBroker()
+	ch, unsubscribe := broker.Subscribe()
+
+	unsubscribe()
+	if _, ok := <-ch; ok {
+		t.Fatalf("the channel is still open after unsubscribing")
+	}
+
+	// unsubscribing again and publishing afterwards must not panic
+	unsubscribe()
+	broker.Publish(event("a"))
+}
+
+func TestBrokerCutsOffSlowSubscribers(t *testing.T) {
+	broker := NewBroker()
+	slow, unsubscribeSlow := broker.Subscribe()
+	defer unsubscribeSlow()
+	fast, unsubscribeFast := broker.Subscribe()
+	defer unsubscribeFast()
+
+	for i := 0; i < subscriberBuffer; i++ {
+		broker.Publish(event("a"))
+		<-fast
+	}
+	// the slow screen's buffer is full now
+	broker.Publish(event("b"))
+
+	if got := <-fast; got.Order_item.Order_item_id != "b" {
+		t.Errorf("the subscriber that kept up got %+v, want item b", got)
+	}
+
+	// the slow screen gets what fit in its buffer, then its channel closes
+	// instead of an event going missing
+	count := 0
+	for range slow {
+		count++
+	}
+	if count != subscriberBuffer {
+		t.Errorf("the slow subscriber got %d events before being cut off, want %d", count, subscriberBuffer)
+	}
+}
+
+func TestBrokerConcurrentUse(t *testing.T) {
+	broker := NewBroker()
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			ch, unsubscribe := broker.Subscribe()
+			for range 4 {
+				select {
+				case <-ch:
+				default:
+				}
+			}
+			unsubscribe()
+		}()
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				broker.Publish(event("a"))
+			}
+		}()
+	}
+	wg.Wait()
+}
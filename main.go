@@ -1,26 +1,196 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"log"
 	"os"
+	"strconv"
+	"time"
+	_ "time/tzdata"
 
 	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/controllers"
 	"github.com/tokha04/go-restautant-management/database"
+	"github.com/tokha04/go-restautant-management/jobs"
+	"github.com/tokha04/go-restautant-management/kitchen"
 	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/money"
+	"github.com/tokha04/go-restautant-management/notify"
+	"github.com/tokha04/go-restautant-management/payments"
+	"github.com/tokha04/go-restautant-management/receipt"
+	"github.com/tokha04/go-restautant-management/repository"
 	"github.com/tokha04/go-restautant-management/routes"
-	"go.mongodb.org/mongo-driver/mongo"
+	"github.com/tokha04/go-restautant-management/scheduler"
 )
 
-var foodCollection *mongo.Collection = database.OpenCollection(database.Client, "food")
-
 func main() {
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8000"
 	}
 
+	// STORE_BACKEND=memory runs the API without a MongoDB instance
+	var stores *repository.Stores
+	if os.Getenv("STORE_BACKEND") == "memory" {
+		stores = repository.NewMemoryStores()
+	} else {
+		cfg, err := database.ConfigFromEnv()
+		if err != nil {
+			log.Fatalf("invalid database configuration: %v", err)
+		}
+
+		client, err := database.Connect(context.Background(), cfg)
+		if err != nil {
+			log.Fatalf("database unavailable: %v", err)
+		}
+		defer client.Disconnect(context.Background())
+
+		db := client.Database(cfg.Database)
+
+		migrated, err := repository.MigrateMoney(context.Background(), db)
+		if err != nil {
+			log.Fatalf("could not migrate prices to minor units: %v", err)
+		}
+		if migrated > 0 {
+			log.Printf("migrated %d document(s) to minor unit prices", migrated)
+		}
+
+		backfilled, err := repository.BackfillRoles(context.Background(), db)
+		if err != nil {
+			log.Fatalf("could not give existing users a role: %v", err)
+		}
+		if backfilled > 0 {
+			log.Printf("gave %d existing user(s) the waiter role", backfilled)
+		}
+
+		removed, err := repository.RemoveStoredTokens(context.Background(), db)
+		if err != nil {
+			log.Fatalf("could not remove stored tokens: %v", err)
+		}
+		if removed > 0 {
+			log.Printf("removed the tokens stored on %d user(s)", removed)
+		}
+
+		if err := repository.EnsureIndexes(context.Background(), db); err != nil {
+			log.Fatalf("could not create indexes: %v", err)
+		}
+
+		stores = repository.NewMongoStores(db)
+
+		// with a replica set every API process can follow the same kitchen
+		// feed; a standalone server only supports the in-process broker
+		feed, err := kitchen.NewChangeStreamFeed(context.Background(), db.Collection("orderItem"))
+		if err != nil {
+			log.Printf("kitchen display uses the in-process broker, change streams are unavailable: %v", err)
+		} else {
+			controllers.UseKitchenFeed(feed)
+		}
+	}
+	controllers.UseStores(stores)
+	middleware.UseRevocationStore(stores.Revocations)
+
+	// ADMIN_EMAIL is made an admin on start, and created with ADMIN_PASSWORD
+	// if no user has it; that is how the first admin comes to exist
+	if adminEmail := os.Getenv("ADMIN_EMAIL"); adminEmail != "" {
+		created, err := controllers.EnsureAdmin(adminEmail, os.Getenv("ADMIN_PASSWORD"))
+		if err != nil {
+			log.Fatalf("could not set up the admin: %v", err)
+		}
+		if created {
+			log.Printf("created admin %s", adminEmail)
+		}
+	} else if admins, err := stores.Users.CountByRole(context.Background(), "ADMIN"); err == nil && admins == 0 {
+		log.Printf("there is no admin yet, set ADMIN_EMAIL and ADMIN_PASSWORD to create one")
+	}
+
+	// without PAYMENT_PROVIDER only cash is taken: the mock approves cards
+	// without charging them, so it is only used when asked for by name.
+	// PAYMENTS_WEBHOOK_SECRET is shared with the processor; the mock signs its
+	// own webhooks, so it falls back to a per-process secret
+	webhookSecret := []byte(os.Getenv("PAYMENTS_WEBHOOK_SECRET"))
+	switch provider := os.Getenv("PAYMENT_PROVIDER"); provider {
+	case "":
+		log.Printf("warning: PAYMENT_PROVIDER is not set, card payments are disabled and only cash is taken; use PAYMENT_PROVIDER=mock to take card payments without charging anyone")
+		controllers.UsePayments(payments.DisabledProvider{}, webhookSecret)
+	case "mock":
+		log.Printf("card payments go to the mock provider and are never charged")
+		if len(webhookSecret) == 0 {
+			webhookSecret = make([]byte, 32)
+			if _, err := rand.Read(webhookSecret); err != nil {
+				log.Fatalf("could not generate a webhook secret: %v", err)
+			}
+		}
+		webhookUrl := os.Getenv("PAYMENTS_MOCK_WEBHOOK_URL")
+		if webhookUrl == "" {
+			webhookUrl = "http://localhost:" + port + "/payments/webhook"
+		}
+		controllers.UsePayments(payments.NewMockProvider(webhookUrl, webhookSecret), webhookSecret)
+	default:
+		log.Fatalf("unknown payment provider %q", provider)
+	}
+
+	// COMP_APPROVAL_THRESHOLD is the value above which comps and voids need
+	// a manager's approval
+	if threshold := os.Getenv("COMP_APPROVAL_THRESHOLD"); threshold != "" {
+		amount, err := money.Parse(threshold, money.DefaultCurrency)
+		if err != nil {
+			log.Fatalf("invalid COMP_APPROVAL_THRESHOLD: %v", err)
+		}
+		controllers.UseApprovalThreshold(amount)
+	}
+
+	// RESTAURANT_TIMEZONE is an IANA zone such as "Asia/Almaty" that business
+	// days and menu hours follow; the server's zone is used without it
+	if timezone := os.Getenv("RESTAURANT_TIMEZONE"); timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			log.Fatalf("invalid RESTAURANT_TIMEZONE: %v", err)
+		}
+		controllers.UseTimezone(loc)
+	}
+
+	// the restaurant details printed on receipts
+	controllers.UseRestaurant(receipt.Header{
+		Name:    os.Getenv("RESTAURANT_NAME"),
+		Address: os.Getenv("RESTAURANT_ADDRESS"),
+		Phone:   os.Getenv("RESTAURANT_PHONE"),
+		Tax_id:  os.Getenv("RESTAURANT_TAX_ID"),
+		Footer:  os.Getenv("RECEIPT_FOOTER"),
+	})
+
+	// INVOICE_PREFIX tells apart restaurants sharing a database and
+	// FISCAL_YEAR_START_MONTH (1-12) is when invoice numbers start over
+	fiscalYearStart := time.January
+	if month := os.Getenv("FISCAL_YEAR_START_MONTH"); month != "" {
+		m, err := strconv.Atoi(month)
+		if err != nil || m < 1 || m > 12 {
+			log.Fatalf("invalid FISCAL_YEAR_START_MONTH %q, expected 1 to 12", month)
+		}
+		fiscalYearStart = time.Month(m)
+	}
+	controllers.UseInvoiceNumbering(os.Getenv("INVOICE_PREFIX"), fiscalYearStart)
+
+	notifier := notify.NewBroker()
+	controllers.UseNotifier(notifier)
+
+	// OVERDUE_SCHEDULE is a cron expression, e.g. "*/5 * * * *", or an
+	// interval such as "@every 1m"
+	overdueSchedule := os.Getenv("OVERDUE_SCHEDULE")
+	if overdueSchedule == "" {
+		overdueSchedule = "*/5 * * * *"
+	}
+	jobScheduler := scheduler.New()
+	if err := jobScheduler.Add("overdue-invoices", overdueSchedule, jobs.OverdueInvoices(stores.Invoices, notifier)); err != nil {
+		log.Fatalf("invalid OVERDUE_SCHEDULE: %v", err)
+	}
+	jobScheduler.Start(context.Background())
+	defer jobScheduler.Stop()
+
 	router := gin.New()
 	router.Use(gin.Logger())
 	routes.UserRoutes(router)
+	routes.PaymentWebhookRoutes(router)
 	router.Use(middleware.Authentication())
 
 	routes.FoodRoutes(router)
@@ -29,6 +199,18 @@ func main() {
 	routes.OrderRoutes(router)
 	routes.OrderItemRoutes(router)
 	routes.InvoiceRoutes(router)
+	routes.KitchenRoutes(router)
+	routes.ReservationRoutes(router)
+	routes.TaxRateRoutes(router)
+	routes.PaymentRoutes(router)
+	routes.ServiceChargeRoutes(router)
+	routes.ShiftRoutes(router)
+	routes.ReportRoutes(router)
+	routes.PromotionRoutes(router)
+	routes.DiscountRoutes(router)
+	routes.NotificationRoutes(router)
+	routes.DrawerRoutes(router)
+	routes.BusinessDayRoutes(router)
 
 	router.Run(":" + port)
 }
@@ -1,33 +1,96 @@
 package middleware
 
 import (
+	"context"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/tokha04/go-restautant-management/helpers"
+	"github.com/tokha04/go-restautant-management/repository"
 )
 
+var revocationStore repository.RevocationStore
+
+// UseRevocationStore sets the store Authentication consults for logged out or
+// killed sessions. Without one, tokens are only checked for signature and
+// expiry.
+func UseRevocationStore(store repository.RevocationStore) {
+	revocationStore = store
+}
+
 func Authentication() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		clientToken := ctx.Request.Header.Get("token")
 		if clientToken == "" {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "no authorization header provided"})
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "no authorization header provided"})
 			ctx.Abort()
 			return
 		}
 
 		claims, err := helpers.ValidateToken(clientToken)
 		if err != "" {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err})
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err})
 			ctx.Abort()
 			return
 		}
 
+		if claims.Token_type != helpers.AccessToken {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "an access token is required"})
+			ctx.Abort()
+			return
+		}
+
+		if revocationStore != nil {
+			var c, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			revoked, err := revocationStore.IsRevoked(c, claims.Session_id, claims.Uid, claims.IssuedAt)
+			if err != nil {
+				log.Printf("could not check token revocation: %v", err)
+				ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "could not verify the session"})
+				ctx.Abort()
+				return
+			}
+
+			if revoked {
+				ctx.JSON(http.StatusUnauthorized, gin.H{"error": "session has been revoked, please log in again"})
+				ctx.Abort()
+				return
+			}
+		}
+
 		ctx.Set("email", claims.Email)
 		ctx.Set("first_name", claims.First_name)
 		ctx.Set("last_name", claims.Last_name)
 		ctx.Set("uid", claims.Uid)
+		ctx.Set("role", claims.Role)
+		ctx.Set("session_id", claims.Session_id)
 
 		ctx.Next()
 	}
 }
+
+// Authorize lets the request through only when the authenticated user holds
+// one of the given roles. It must run after Authentication.
+func Authorize(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		role := ctx.GetString("role")
+
+		for _, allowed := range roles {
+			if role == allowed {
+				ctx.Next()
+				return
+			}
+		}
+
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"error":         "your role is not allowed to perform this action",
+			"code":          "FORBIDDEN",
+			"role":          role,
+			"allowed_roles": roles,
+		})
+		ctx.Abort()
+	}
+}
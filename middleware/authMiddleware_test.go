@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/helpers"
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/repository"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	helpers.SECRET_KEY = "test-secret"
+}
+
+func tokens(t *testing.T, uid, role, sessionId string) (string, string) {
+	t.Helper()
+	token, refreshToken, err := helpers.GenerateAllTokens("ada@example.com", "Ada", "Lovelace", uid, role, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token, refreshToken
+}
+
+func protectedRouter(roles ...string) *gin.Engine {
+	router := gin.New()
+	router.Use(Authentication())
+	router.GET("/any", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"uid": ctx.GetString("uid"), "role": ctx.GetString("role"), "session_id": ctx.GetString("session_id")})
+	})
+	router.GET("/restricted", Authorize(roles...), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+	return router
+}
+
+func get(router *gin.Engine, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("token", token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuthentication(t *testing.T) {
+	UseRevocationStore(nil)
+	router := protectedRouter(models.RoleManager)
+	token, refreshToken := tokens(t, "u1", models.RoleWaiter, "s1")
+
+	rec := get(router, "/any", token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("an access token got %d, want 200", rec.Code)
+	}
+	if body := rec.Body.String(); body != `{"role":"WAITER","session_id":"s1","uid":"u1"}` {
+		t.Errorf("the handler saw %s, want the token's claims", body)
+	}
+
+	helpers.SECRET_KEY = "another-secret"
+	forged, _ := tokens(t, "u1", models.RoleAdmin, "s1")
+	helpers.SECRET_KEY = "test-secret"
+
+	for name, token := range map[string]string{
+		"no token":            "",
+		"garbage":             "not-a-token",
+		"refresh token":       refreshToken,
+		"signed with another": forged,
+	} {
+		if rec := get(router, "/any", token); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s got %d, want 401", name, rec.Code)
+		}
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	UseRevocationStore(nil)
+	router := protectedRouter(models.RoleAdmin, models.RoleManager)
+
+	tests := []struct {
+		role string
+		want int
+	}{
+		{models.RoleAdmin, http.StatusOK},
+		{models.RoleManager, http.StatusOK},
+		{models.RoleWaiter, http.StatusForbidden},
+		{models.RoleChef, http.StatusForbidden},
+		// tokens issued before roles existed carry none
+		{"", http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		token, _ := tokens(t, "u1", test.role, "s1")
+		if rec := get(router, "/restricted", token); rec.Code != test.want {
+			t.Errorf("role %q got %d, want %d", test.role, rec.Code, test.want)
+		}
+	}
+
+	// Authorize never lets an unauthenticated request through
+	if rec := get(router, "/restricted", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("no token got %d, want 401", rec.Code)
+	}
+}
+
+func TestAuthenticationRefusesRevokedTokens(t *testing.T) {
+	revocations := repository.NewMemoryStores().Revocations
+	UseRevocationStore(revocations)
+	defer UseRevocationStore(nil)
+	router := protectedRouter()
+	c := context.Background()
+
+	loggedOut, _ := tokens(t, "u1", models.RoleWaiter, "s1")
+	otherDevice, _ := tokens(t, "u1", models.RoleWaiter, "s2")
+	if err := revocations.RevokeSession(c, "s1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if rec := get(router, "/any", loggedOut); rec.Code != http.StatusUnauthorized {
+		t.Errorf("a logged out session got %d, want 401", rec.Code)
+	}
+	if rec := get(router, "/any", otherDevice); rec.Code != http.StatusOK {
+		t.Errorf("another session of the user got %d, want 200", rec.Code)
+	}
+
+	// killing every session of a user also catches tokens issued in the
+	// same second as the revocation
+	killed, _ := tokens(t, "u2", models.RoleWaiter, "s3")
+	if err := revocations.RevokeUser(c, "u2", time.Now(), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if rec := get(router, "/any", killed); rec.Code != http.StatusUnauthorized {
+		t.Errorf("a killed user's token got %d, want 401", rec.Code)
+	}
+}
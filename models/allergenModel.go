@@ -0,0 +1,64 @@
+package models
+
+import "slices"
+
+// The 14 allergens EU food law requires to be declared.
+const (
+	AllergenCelery      = "CELERY"
+	AllergenGluten      = "GLUTEN"
+	AllergenCrustaceans = "CRUSTACEANS"
+	AllergenEggs        = "EGGS"
+	AllergenFish        = "FISH"
+	AllergenLupin       = "LUPIN"
+	AllergenMilk        = "MILK"
+	AllergenMolluscs    = "MOLLUSCS"
+	AllergenMustard     = "MUSTARD"
+	AllergenTreeNuts    = "TREE_NUTS"
+	AllergenPeanuts     = "PEANUTS"
+	AllergenSesame      = "SESAME"
+	AllergenSoya        = "SOYA"
+	AllergenSulphites   = "SULPHITES"
+)
+
+var Allergens = []string{
+	AllergenCelery, AllergenGluten, AllergenCrustaceans, AllergenEggs,
+	AllergenFish, AllergenLupin, AllergenMilk, AllergenMolluscs,
+	AllergenMustard, AllergenTreeNuts, AllergenPeanuts, AllergenSesame,
+	AllergenSoya, AllergenSulphites,
+}
+
+const (
+	DietVegan      = "VEGAN"
+	DietVegetarian = "VEGETARIAN"
+	DietHalal      = "HALAL"
+	DietGlutenFree = "GLUTEN_FREE"
+)
+
+var DietaryTags = []string{DietVegan, DietVegetarian, DietHalal, DietGlutenFree}
+
+// SeatAllergies are the allergies of the guest in one seat of an order.
+type SeatAllergies struct {
+	Seat      int      `json:"seat" validate:"min=1,max=99"`
+	Allergens []string `json:"allergens" validate:"required,min=1,dive,allergen"`
+}
+
+// AllergenWarning flags an order item containing allergens someone at the
+// table is allergic to.
+type AllergenWarning struct {
+	Order_item_id string   `json:"order_item_id"`
+	Food_id       string   `json:"food_id"`
+	Seat          int      `json:"seat,omitempty"`
+	Allergens     []string `json:"allergens"`
+}
+
+// CommonAllergens lists the allergens found in both lists, in the order of
+// the first.
+func CommonAllergens(allergens, allergies []string) []string {
+	common := []string{}
+	for _, allergen := range allergens {
+		if slices.Contains(allergies, allergen) && !slices.Contains(common, allergen) {
+			common = append(common, allergen)
+		}
+	}
+	return common
+}
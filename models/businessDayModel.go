@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BusinessDay is a trading day, in the restaurant's local time. Once it is
+// closed its invoices can no longer be changed and nothing more can be
+// billed, paid or refunded on it; corrections go on a later day.
+type BusinessDay struct {
+	ID            primitive.ObjectID `bson:"_id"`
+	Business_date string             `json:"business_date"`
+	Closed_by     string             `json:"closed_by,omitempty"`
+	Closed_at     *time.Time         `json:"closed_at"`
+	Report        CashReport         `json:"report"`
+}
+
+func (day BusinessDay) IsClosed() bool {
+	return day.Closed_at != nil
+}
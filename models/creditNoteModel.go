@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/tokha04/go-restautant-management/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreditNote reduces what was billed on an invoice, e.g. when a payment is
+// refunded. Its tax breakdown is the invoice's, in proportion to the credited
+// amount, so the tax returns can be corrected.
+type CreditNote struct {
+	ID             primitive.ObjectID `bson:"_id"`
+	Credit_note_id string             `json:"credit_note_id"`
+	Invoice_id     string             `json:"invoice_id"`
+	Order_id       string             `json:"order_id"`
+	Payment_id     string             `json:"payment_id"`
+	Method         string             `json:"method,omitempty"`
+	Drawer_id      string             `json:"drawer_id,omitempty"`
+	Reason         string             `json:"reason"`
+	Subtotal       money.Money        `json:"subtotal"`
+	Tax_total      money.Money        `json:"tax_total"`
+	Total          money.Money        `json:"total"`
+	Tax_breakdown  []TaxSummary       `json:"tax_breakdown"`
+	Created_by     string             `json:"created_by"`
+	Created_at     time.Time          `json:"created_at"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/tokha04/go-restautant-management/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// A discount comes from a promotion, or is a comp (the house gives part or
+// all of the item or bill away) or a void (the item is taken off the bill).
+const (
+	DiscountPromotion = "PROMOTION"
+	DiscountComp      = "COMP"
+	DiscountVoid      = "VOID"
+)
+
+// Discount is a discount applied to an order. Discounts are never deleted;
+// removing one records who removed it and why, so every discount stays on
+// the audit trail. Value is what the discount was worth when it was applied.
+type Discount struct {
+	ID             primitive.ObjectID `bson:"_id"`
+	Discount_id    string             `json:"discount_id"`
+	Order_id       string             `json:"order_id"`
+	Order_item_id  string             `json:"order_item_id,omitempty"`
+	Type           string             `json:"type"`
+	Name           string             `json:"name"`
+	Promotion_id   string             `json:"promotion_id,omitempty"`
+	Code           string             `json:"code,omitempty"`
+	Kind           string             `json:"kind"`
+	Percent        float64            `json:"percent,omitempty"`
+	Amount         money.Money        `json:"amount"`
+	Value          money.Money        `json:"value"`
+	Reason         string             `json:"reason,omitempty"`
+	Created_by     string             `json:"created_by"`
+	Approved_by    string             `json:"approved_by,omitempty"`
+	Created_at     time.Time          `json:"created_at"`
+	Removed_by     string             `json:"removed_by,omitempty"`
+	Removed_reason string             `json:"removed_reason,omitempty"`
+	Removed_at     *time.Time         `json:"removed_at,omitempty"`
+}
+
+func (discount Discount) IsActive() bool {
+	return discount.Removed_at == nil
+}
+
+// Scope returns ScopeItem for item discounts and ScopeBill otherwise.
+func (discount Discount) Scope() string {
+	if discount.Order_item_id != "" {
+		return ScopeItem
+	}
+	return ScopeBill
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+
+	"github.com/tokha04/go-restautant-management/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Cash put into or taken out of a drawer other than through payments.
+// Paid-outs settle small expenses, such as a delivery, from the drawer.
+const (
+	CashIn  = "CASH_IN"
+	CashOut = "CASH_OUT"
+	PaidOut = "PAID_OUT"
+)
+
+// Reports are X reports while the drawer or day is open and Z reports once it
+// is closed. Only Z reports are kept.
+const (
+	ReportX = "X"
+	ReportZ = "Z"
+)
+
+// Drawer is a register's cash drawer from the moment it is opened with a
+// float until its cash is counted. Payments taken and refunds given by the
+// staff member who opened it go through it.
+//
+// Amounts only known once the cash is counted are left out of the stored
+// document until then, because money decodes a stored null as zero.
+type Drawer struct {
+	ID            primitive.ObjectID `bson:"_id"`
+	Drawer_id     string             `json:"drawer_id"`
+	Register      string             `json:"register" validate:"required,max=50"`
+	Opening_float money.Money        `json:"opening_float"`
+	Movements     []CashMovement     `json:"movements"`
+	Opened_by     string             `json:"opened_by"`
+	Opened_at     time.Time          `json:"opened_at"`
+	Closed_by     string             `json:"closed_by,omitempty"`
+	Closed_at     *time.Time         `json:"closed_at"`
+	Counted_cash  *money.Money       `json:"counted_cash" bson:"counted_cash,omitempty"`
+	Report        *CashReport        `json:"report,omitempty"`
+}
+
+func (drawer Drawer) IsOpen() bool {
+	return drawer.Closed_at == nil
+}
+
+type CashMovement struct {
+	Movement_id string      `json:"movement_id"`
+	Type        string      `json:"type" validate:"required,eq=CASH_IN|eq=CASH_OUT|eq=PAID_OUT"`
+	Amount      money.Money `json:"amount"`
+	Reason      string      `json:"reason" validate:"required_if=Type PAID_OUT,max=250"`
+	Created_by  string      `json:"created_by"`
+	Created_at  time.Time   `json:"created_at"`
+}
+
+// CashReport reconciles the cash expected in the drawer with what was
+// counted, and totals card payments, refunds, discounts and tips over the
+// same period. Cash tips are expected in the drawer until they are paid out.
+type CashReport struct {
+	Kind           string        `json:"kind"`
+	From           time.Time     `json:"from"`
+	To             time.Time     `json:"to"`
+	Opening_float  money.Money   `json:"opening_float"`
+	Cash_sales     money.Money   `json:"cash_sales"`
+	Cash_tips      money.Money   `json:"cash_tips"`
+	Cash_refunds   money.Money   `json:"cash_refunds"`
+	Cash_in        money.Money   `json:"cash_in"`
+	Cash_out       money.Money   `json:"cash_out"`
+	Paid_out       money.Money   `json:"paid_out"`
+	Expected_cash  money.Money   `json:"expected_cash"`
+	Counted_cash   *money.Money  `json:"counted_cash" bson:"counted_cash,omitempty"`
+	Over_short     *money.Money  `json:"over_short" bson:"over_short,omitempty"`
+	Card_sales     money.Money   `json:"card_sales"`
+	Card_tips      money.Money   `json:"card_tips"`
+	Card_refunds   money.Money   `json:"card_refunds"`
+	Refund_total   money.Money   `json:"refund_total"`
+	Discount_total money.Money   `json:"discount_total"`
+	Tip_total      money.Money   `json:"tip_total"`
+	Payment_count  int           `json:"payment_count"`
+	Refund_count   int           `json:"refund_count"`
+	Drawers        []DrawerTally `json:"drawers,omitempty"`
+	Generated_at   time.Time     `json:"generated_at"`
+}
+
+// DrawerTally is one drawer's line in a business day report.
+type DrawerTally struct {
+	Drawer_id     string       `json:"drawer_id"`
+	Register      string       `json:"register"`
+	Expected_cash money.Money  `json:"expected_cash"`
+	Counted_cash  *money.Money `json:"counted_cash" bson:"counted_cash,omitempty"`
+	Over_short    *money.Money `json:"over_short" bson:"over_short,omitempty"`
+}
@@ -3,16 +3,50 @@ package models
 import (
 	"time"
 
+	"github.com/tokha04/go-restautant-management/money"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Food struct {
-	ID         primitive.ObjectID `bson:"_id"`
-	Name       *string            `json:"name" validate:"required,min=2,max=100"`
-	Price      *float64           `json:"price" validate:"required"`
-	Food_image *string            `json:"food_image" validate:"required"`
-	Created_at time.Time          `json:"created_at" validate:"required"`
-	Updated_at time.Time          `json:"updated_at"`
-	Food_id    string             `json:"food_id"`
-	Menu_id    *string            `json:"menu_id" validate:"required"`
+	ID              primitive.ObjectID `bson:"_id"`
+	Name            *string            `json:"name" validate:"required,min=2,max=100"`
+	Price           *money.Money       `json:"price" validate:"required,gte=0"`
+	Food_image      *string            `json:"food_image" validate:"required"`
+	Created_at      time.Time          `json:"created_at" validate:"required"`
+	Updated_at      time.Time          `json:"updated_at"`
+	Food_id         string             `json:"food_id"`
+	Menu_id         *string            `json:"menu_id" validate:"required"`
+	Variants        []FoodVariant      `json:"variants" validate:"dive"`
+	Modifier_groups []ModifierGroup    `json:"modifier_groups" validate:"dive"`
+	Allergens       []string           `json:"allergens" validate:"dive,allergen"`
+	Dietary_tags    []string           `json:"dietary_tags" validate:"dive,dietary"`
+}
+
+// FoodVariant prices the food in one size, S, M or L, and/or one named
+// variant such as "gluten free". A variant without a size is priced the
+// same in every size, and the unnamed variant is the food ordered plainly;
+// without one that is Price.
+type FoodVariant struct {
+	Name  string       `json:"name" validate:"max=50"`
+	Size  string       `json:"size" validate:"omitempty,eq=S|eq=M|eq=L"`
+	Price *money.Money `json:"price" validate:"required,gte=0"`
+}
+
+// ModifierGroup is a set of options ordered with the food, such as "choose
+// a side" or "extras". At least Min and at most Max of them are picked; a
+// Max of 0 leaves the number open.
+type ModifierGroup struct {
+	Name      string     `json:"name" validate:"required,max=50"`
+	Min       int        `json:"min" validate:"gte=0"`
+	Max       int        `json:"max" validate:"gte=0"`
+	Modifiers []Modifier `json:"modifiers" validate:"required,min=1,dive"`
+}
+
+// Modifier is one option of a group and what it adds to the price, which
+// may be nothing, as for "no onions", or a reduction. Allergens are those
+// it adds to the food.
+type Modifier struct {
+	Name        string      `json:"name" validate:"required,max=50"`
+	Price_delta money.Money `json:"price_delta"`
+	Allergens   []string    `json:"allergens,omitempty" validate:"dive,allergen"`
 }
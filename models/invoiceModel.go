@@ -3,16 +3,147 @@ package models
 import (
 	"time"
 
+	"github.com/tokha04/go-restautant-management/money"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	InvoicePending       = "PENDING"
+	InvoicePartiallyPaid = "PARTIALLY_PAID"
+	InvoicePaid          = "PAID"
+	InvoiceFailed        = "FAILED"
+	InvoiceRefunded      = "REFUNDED"
+	// InvoiceOverdue invoices were not paid by their due date. They stay
+	// overdue until they are paid in full.
+	InvoiceOverdue = "OVERDUE"
+	// InvoiceVoided invoices were cancelled. Invoices are never deleted, so
+	// their numbers stay accounted for.
+	InvoiceVoided = "VOIDED"
+)
+
+// PaymentMixed is the invoice payment method when it was paid with more than
+// one method.
+const PaymentMixed = "MIXED"
+
+// An order can be billed on one invoice or split into several sub-invoices
+// that are paid independently.
+const (
+	SplitByItems  = "ITEMS"
+	SplitEvenly   = "EVEN"
+	SplitByAmount = "CUSTOM"
+	SplitBySeat   = "SEAT"
+)
+
 type Invoice struct {
 	ID               primitive.ObjectID `bson:"_id"`
 	Invoice_id       string             `json:"invoice_id"`
+	Invoice_number   string             `json:"invoice_number"`
+	Fiscal_year      int                `json:"fiscal_year,omitempty"`
 	Order_id         string             `json:"order_id"`
-	Payment_method   *string            `json:"payment_method" validate:"eq=CARD|eq=CASH|eq="`
-	Payment_status   *string            `json:"payment_status" validate:"required,eq=PENDING|eq=PAID"`
+	Payment_method   *string            `json:"payment_method" validate:"omitempty,eq=CARD|eq=CASH|eq=MIXED"`
+	Payment_status   *string            `json:"payment_status" validate:"required,eq=PENDING|eq=PARTIALLY_PAID|eq=PAID|eq=FAILED|eq=REFUNDED|eq=VOIDED|eq=OVERDUE"`
 	Payment_due_date time.Time          `json:"payment_due_date"`
+	Lines            []InvoiceLine      `json:"lines"`
+	Discounts        []InvoiceDiscount  `json:"discounts"`
+	Discount_total   money.Money        `json:"discount_total"`
+	Tax_breakdown    []TaxSummary       `json:"tax_breakdown"`
+	Subtotal         money.Money        `json:"subtotal"`
+	Tax_total        money.Money        `json:"tax_total"`
+	Service_charges  []InvoiceCharge    `json:"service_charges"`
+	Service_total    money.Money        `json:"service_total"`
+	Total            money.Money        `json:"total"`
+	Tip_total        money.Money        `json:"tip_total"`
+	Amount_paid      money.Money        `json:"amount_paid"`
+	Amount_refunded  money.Money        `json:"amount_refunded"`
+	Amount_credited  money.Money        `json:"amount_credited"`
+	Balance          money.Money        `json:"balance"`
+	Split_mode       string             `json:"split_mode,omitempty"`
+	Split_index      int                `json:"split_index,omitempty"`
+	Split_count      int                `json:"split_count,omitempty"`
+	Voided_at        *time.Time         `json:"voided_at,omitempty"`
+	Voided_by        string             `json:"voided_by,omitempty"`
+	Void_reason      string             `json:"void_reason,omitempty"`
 	Created_at       time.Time          `json:"created_at"`
 	Updated_at       time.Time          `json:"updated_at"`
 }
+
+func (invoice Invoice) IsOverdue() bool {
+	return invoice.Payment_status != nil && *invoice.Payment_status == InvoiceOverdue
+}
+
+func (invoice Invoice) IsVoided() bool {
+	return invoice.Payment_status != nil && *invoice.Payment_status == InvoiceVoided
+}
+
+// StoredStatus returns the payment status, or "" for an invoice stored
+// without one.
+func (invoice Invoice) StoredStatus() string {
+	if invoice.Payment_status == nil {
+		return ""
+	}
+	return *invoice.Payment_status
+}
+
+// InvoiceLine is one billed order item. Amount is what the guest pays for the
+// line after discounts and including every tax; Net_amount excludes the
+// taxes.
+type InvoiceLine struct {
+	Order_item_id string          `json:"order_item_id"`
+	Food_id       string          `json:"food_id"`
+	Food_name     string          `json:"food_name"`
+	Quantity      string          `json:"quantity"`
+	Variant       string          `json:"variant,omitempty"`
+	Count         int             `json:"count"`
+	Seat          int             `json:"seat,omitempty"`
+	Modifiers     []OrderModifier `json:"modifiers,omitempty"`
+	Unit_price    money.Money     `json:"unit_price"`
+	Discount      money.Money     `json:"discount"`
+	Net_amount    money.Money     `json:"net_amount"`
+	Tax_amount    money.Money     `json:"tax_amount"`
+	Amount        money.Money     `json:"amount"`
+	Taxes         []LineTax       `json:"taxes"`
+}
+
+// Units is how many of the item the line bills. Lines billed before orders
+// had counts are one each.
+func (line InvoiceLine) Units() int {
+	if line.Count < 1 {
+		return 1
+	}
+	return line.Count
+}
+
+type LineTax struct {
+	Tax_rate_id string      `json:"tax_rate_id"`
+	Name        string      `json:"name"`
+	Rate        float64     `json:"rate"`
+	Inclusive   bool        `json:"inclusive"`
+	Amount      money.Money `json:"amount"`
+}
+
+// InvoiceCharge is a service charge added to the bill.
+type InvoiceCharge struct {
+	Service_charge_id string      `json:"service_charge_id"`
+	Name              string      `json:"name"`
+	Rate              float64     `json:"rate"`
+	Amount            money.Money `json:"amount"`
+}
+
+// InvoiceDiscount is what one discount took off the bill.
+type InvoiceDiscount struct {
+	Discount_id   string      `json:"discount_id"`
+	Type          string      `json:"type"`
+	Name          string      `json:"name"`
+	Order_item_id string      `json:"order_item_id,omitempty"`
+	Amount        money.Money `json:"amount"`
+}
+
+// TaxSummary totals one tax rate over the whole invoice.
+type TaxSummary struct {
+	Tax_rate_id    string      `json:"tax_rate_id"`
+	Name           string      `json:"name"`
+	Rate           float64     `json:"rate"`
+	Inclusive      bool        `json:"inclusive"`
+	Taxable_amount money.Money `json:"taxable_amount"`
+	Tax_amount     money.Money `json:"tax_amount"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/tokha04/go-restautant-management/money"
+)
+
+// InvoiceViewFormat is the invoice as guests and staff see it, with the table
+// it was served at. Receipts are rendered from it.
+type InvoiceViewFormat struct {
+	Invoice_id       string
+	Invoice_number   string
+	Payment_method   string
+	Order_id         string
+	Payment_status   *string
+	Payment_due      money.Money
+	Table_number     int
+	Payment_due_date time.Time
+	Created_at       time.Time
+	Discounts        []InvoiceDiscount
+	Discount_total   money.Money
+	Subtotal         money.Money
+	Tax_total        money.Money
+	Service_charges  []InvoiceCharge
+	Service_total    money.Money
+	Total            money.Money
+	Tip_total        money.Money
+	Amount_paid      money.Money
+	Amount_refunded  money.Money
+	Amount_credited  money.Money
+	Balance          money.Money
+	Tax_breakdown    []TaxSummary
+	Split_mode       string
+	Split_index      int
+	Split_count      int
+	Order_details    []InvoiceLine
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"slices"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -12,7 +13,119 @@ type Menu struct {
 	Category   string             `json:"category" validate:"required"`
 	Start_date *time.Time         `json:"start_date"`
 	End_date   *time.Time         `json:"end_date"`
+	Dayparts   []Daypart          `json:"dayparts" validate:"dive"`
+	Seasons    []Season           `json:"seasons" validate:"dive"`
 	Created_at time.Time          `json:"created_at"`
 	Updated_at time.Time          `json:"updated_at"`
 	Menu_id    string             `json:"menu_id"`
 }
+
+// Daypart is a time of day a menu is served on some days of the week, such
+// as breakfast from 07:00 to 11:00 on weekdays. Start and End are HH:MM in
+// the restaurant's time zone; an End not after Start runs past midnight and
+// belongs to the day it starts on. No Days means every day.
+type Daypart struct {
+	Name  string   `json:"name" validate:"required,max=50"`
+	Days  []string `json:"days" validate:"dive,eq=MON|eq=TUE|eq=WED|eq=THU|eq=FRI|eq=SAT|eq=SUN"`
+	Start string   `json:"start" validate:"required,datetime=15:04"`
+	End   string   `json:"end" validate:"required,datetime=15:04"`
+}
+
+// Season is a stretch of every year a menu is served in, From and To being
+// MM-DD dates, both included. A season whose To comes before its From runs
+// over the new year.
+type Season struct {
+	Name string `json:"name" validate:"max=50"`
+	From string `json:"from" validate:"required,datetime=01-02"`
+	To   string `json:"to" validate:"required,datetime=01-02"`
+}
+
+// AvailableMenu is a menu being served, the daypart it is served in if it
+// has dayparts, and the food that can be ordered from it.
+type AvailableMenu struct {
+	Menu
+	Daypart string `json:"daypart,omitempty"`
+	Foods   []Food `json:"foods"`
+}
+
+// CurrentMenus is what can be ordered at a time in the restaurant.
+type CurrentMenus struct {
+	At       time.Time       `json:"at"`
+	Timezone string          `json:"timezone"`
+	Menus    []AvailableMenu `json:"menus"`
+}
+
+var weekdays = [...]string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+
+// ActiveDaypart reports whether the menu is served at t, which has to be in
+// the restaurant's time zone, and in which daypart. A menu is served between
+// its start and end dates, in one of its seasons and in one of its dayparts;
+// whichever of these it has none of does not restrict it.
+func (menu Menu) ActiveDaypart(t time.Time) (string, bool) {
+	if menu.Start_date != nil && t.Before(*menu.Start_date) {
+		return "", false
+	}
+	if menu.End_date != nil && !t.Before(*menu.End_date) {
+		return "", false
+	}
+
+	if len(menu.Seasons) > 0 {
+		inSeason := false
+		for _, season := range menu.Seasons {
+			if season.Contains(t) {
+				inSeason = true
+				break
+			}
+		}
+		if !inSeason {
+			return "", false
+		}
+	}
+
+	if len(menu.Dayparts) == 0 {
+		return "", true
+	}
+	for _, daypart := range menu.Dayparts {
+		if daypart.Contains(t) {
+			return daypart.Name, true
+		}
+	}
+	return "", false
+}
+
+func (menu Menu) IsActive(t time.Time) bool {
+	_, active := menu.ActiveDaypart(t)
+	return active
+}
+
+func (daypart Daypart) Contains(t time.Time) bool {
+	start, end := clockMinutes(daypart.Start), clockMinutes(daypart.End)
+	now := t.Hour()*60 + t.Minute()
+
+	if start < end {
+		return daypart.servedOn(t.Weekday()) && now >= start && now < end
+	}
+	// past midnight, the daypart is still the previous day's
+	if now >= start {
+		return daypart.servedOn(t.Weekday())
+	}
+	return now < end && daypart.servedOn((t.Weekday()+6)%7)
+}
+
+func (daypart Daypart) servedOn(day time.Weekday) bool {
+	return len(daypart.Days) == 0 || slices.Contains(daypart.Days, weekdays[day])
+}
+
+func (season Season) Contains(t time.Time) bool {
+	today := t.Format("01-02")
+	if season.From <= season.To {
+		return today >= season.From && today <= season.To
+	}
+	return today >= season.From || today <= season.To
+}
+
+// clockMinutes turns a validated HH:MM time into minutes past midnight.
+func clockMinutes(clock string) int {
+	t, _ := time.Parse("15:04", clock)
+	return t.Hour()*60 + t.Minute()
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// 2024-06-03 is a Monday.
+func at(date, clock string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", date+" "+clock)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestDaypartContains(t *testing.T) {
+	breakfast := Daypart{Name: "Breakfast", Days: []string{"MON", "TUE", "WED", "THU", "FRI"}, Start: "07:00", End: "11:00"}
+	lateNight := Daypart{Name: "Late night", Days: []string{"FRI", "SAT"}, Start: "22:00", End: "02:00"}
+	allDay := Daypart{Name: "All day", Start: "00:00", End: "00:00"}
+
+	tests := []struct {
+		name    string
+		daypart Daypart
+		at      time.Time
+		want    bool
+	}{
+		{"at the start", breakfast, at("2024-06-03", "07:00"), true},
+		{"before the start", breakfast, at("2024-06-03", "06:59"), false},
+		{"at the end", breakfast, at("2024-06-03", "11:00"), false},
+		{"on a day it is not served", breakfast, at("2024-06-08", "08:00"), false},
+		{"past midnight on its own day", lateNight, at("2024-06-07", "23:30"), true},
+		{"past midnight the day after", lateNight, at("2024-06-08", "01:30"), true},
+		{"past midnight after the last day", lateNight, at("2024-06-09", "01:30"), true},
+		{"past midnight after a day it is not served", lateNight, at("2024-06-07", "01:30"), false},
+		{"past midnight after the end", lateNight, at("2024-06-08", "02:00"), false},
+		{"before it starts on its day", lateNight, at("2024-06-07", "21:59"), false},
+		{"every day", allDay, at("2024-06-09", "03:00"), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.daypart.Contains(test.at); got != test.want {
+				t.Errorf("%s contains %s = %v, want %v", test.daypart.Name, test.at.Format("Mon 15:04"), got, test.want)
+			}
+		})
+	}
+}
+
+func TestSeasonContains(t *testing.T) {
+	summer := Season{Name: "Summer", From: "06-01", To: "08-31"}
+	winter := Season{Name: "Winter", From: "12-01", To: "02-28"}
+
+	tests := []struct {
+		season Season
+		date   string
+		want   bool
+	}{
+		{summer, "2024-06-01", true},
+		{summer, "2024-08-31", true},
+		{summer, "2024-05-31", false},
+		{summer, "2024-09-01", false},
+		{winter, "2024-12-01", true},
+		{winter, "2024-12-31", true},
+		{winter, "2025-01-15", true},
+		{winter, "2025-02-28", true},
+		{winter, "2025-03-01", false},
+		{winter, "2024-11-30", false},
+	}
+
+	for _, test := range tests {
+		if got := test.season.Contains(at(test.date, "12:00")); got != test.want {
+			t.Errorf("%s contains %s = %v, want %v", test.season.Name, test.date, got, test.want)
+		}
+	}
+}
+
+func TestMenuActiveDaypart(t *testing.T) {
+	start := at("2024-06-01", "00:00")
+	end := at("2024-09-01", "00:00")
+	menu := Menu{
+		Start_date: &start,
+		End_date:   &end,
+		Seasons:    []Season{{From: "06-01", To: "07-31"}},
+		Dayparts: []Daypart{
+			{Name: "Lunch", Start: "11:30", End: "15:00"},
+			{Name: "Dinner", Start: "18:00", End: "23:00"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		at          time.Time
+		wantDaypart string
+		wantActive  bool
+	}{
+		{"lunch", at("2024-06-03", "12:00"), "Lunch", true},
+		{"dinner", at("2024-07-31", "22:59"), "Dinner", true},
+		{"between dayparts", at("2024-06-03", "16:00"), "", false},
+		{"out of season", at("2024-08-05", "12:00"), "", false},
+		{"before the start date", at("2023-06-05", "12:00"), "", false},
+		{"after the end date", at("2025-06-05", "12:00"), "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			daypart, active := menu.ActiveDaypart(test.at)
+			if daypart != test.wantDaypart || active != test.wantActive {
+				t.Errorf("ActiveDaypart() = %q, %v, want %q, %v", daypart, active, test.wantDaypart, test.wantActive)
+			}
+		})
+	}
+
+	// a menu without dates, seasons or dayparts is always served
+	if daypart, active := (Menu{}).ActiveDaypart(at("2024-06-03", "04:00")); daypart != "" || !active {
+		t.Errorf("an unrestricted menu returned %q, %v, want it served", daypart, active)
+	}
+}
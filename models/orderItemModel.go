@@ -3,16 +3,61 @@ package models
 import (
 	"time"
 
+	"github.com/tokha04/go-restautant-management/money"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	KitchenPending   = "PENDING"
+	KitchenPrepared  = "PREPARED"
+	KitchenCancelled = "CANCELLED"
+)
+
 type OrderItem struct {
-	ID            primitive.ObjectID `bson:"_id"`
-	Quantity      *string            `json:"quantity" validate:"required,eq=S|eq=M|eq=L"`
-	Unit_price    *float64           `json:"unit_price" validate:"required"`
-	Created_at    time.Time          `json:"created_at"`
-	Updated_at    time.Time          `json:"updated_at"`
-	Food_id       *string            `json:"food_id" validate:"required"`
-	Order_item_id string             `json:"order_item_id"`
-	Order_id      string             `json:"order_id" validate:"required"`
+	ID             primitive.ObjectID `bson:"_id"`
+	Quantity       *string            `json:"quantity" validate:"required,eq=S|eq=M|eq=L"`
+	Count          *int               `json:"count" validate:"omitempty,min=1,max=99"`
+	Notes          *string            `json:"notes,omitempty" validate:"omitempty,max=250"`
+	Seat           *int               `json:"seat,omitempty" validate:"omitempty,min=1,max=99"`
+	Modifiers      []OrderModifier    `json:"modifiers" validate:"dive"`
+	Allergens      []string           `json:"allergens"`
+	Variant        *string            `json:"variant,omitempty" validate:"omitempty,max=50"`
+	Unit_price     *money.Money       `json:"unit_price" validate:"required,gte=0"`
+	Created_at     time.Time          `json:"created_at"`
+	Updated_at     time.Time          `json:"updated_at"`
+	Food_id        *string            `json:"food_id" validate:"required"`
+	Order_item_id  string             `json:"order_item_id"`
+	Order_id       string             `json:"order_id" validate:"required"`
+	Kitchen_status string             `json:"kitchen_status"`
+	Prepared_at    *time.Time         `json:"prepared_at,omitempty"`
+	Prepared_by    string             `json:"prepared_by,omitempty"`
+	Cancelled_at   *time.Time         `json:"cancelled_at,omitempty"`
+}
+
+// OrderModifier is a modifier chosen for an order item, named by its group
+// and itself. Its price and allergens are the food's at the time of
+// ordering.
+type OrderModifier struct {
+	Group       string      `json:"group" validate:"required"`
+	Name        string      `json:"name" validate:"required"`
+	Price_delta money.Money `json:"price_delta"`
+	Allergens   []string    `json:"allergens,omitempty"`
+}
+
+// Units is how many of the item were ordered. Items stored before orders
+// had counts are one each.
+func (orderItem OrderItem) Units() int {
+	if orderItem.Count == nil || *orderItem.Count < 1 {
+		return 1
+	}
+	return *orderItem.Count
+}
+
+// CurrentKitchenStatus treats items stored before the kitchen display existed
+// as still pending.
+func (orderItem OrderItem) CurrentKitchenStatus() string {
+	if orderItem.Kitchen_status == "" {
+		return KitchenPending
+	}
+	return orderItem.Kitchen_status
 }
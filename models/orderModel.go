@@ -6,11 +6,107 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	OrderOpen          = "OPEN"
+	OrderSentToKitchen = "SENT_TO_KITCHEN"
+	OrderReady         = "READY"
+	OrderServed        = "SERVED"
+	OrderPaid          = "PAID"
+	OrderClosed        = "CLOSED"
+	OrderCancelled     = "CANCELLED"
+)
+
+// orderTransitions lists, for every status, the statuses an order may move to.
+var orderTransitions = map[string][]string{
+	OrderOpen:          {OrderSentToKitchen, OrderCancelled},
+	OrderSentToKitchen: {OrderReady, OrderCancelled},
+	OrderReady:         {OrderServed, OrderCancelled},
+	OrderServed:        {OrderPaid, OrderClosed},
+	OrderPaid:          {OrderClosed},
+}
+
+// NextOrderStatuses returns the statuses an order in status from may move to.
+func NextOrderStatuses(from string) []string {
+	return append([]string{}, orderTransitions[from]...)
+}
+
+// CanTransitionOrder reports whether an order in status from may move to status to.
+func CanTransitionOrder(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+type OrderStatusChange struct {
+	From       string    `json:"from,omitempty"`
+	To         string    `json:"to"`
+	Changed_by string    `json:"changed_by"`
+	Changed_at time.Time `json:"changed_at"`
+}
+
+// Order is a table's order. Allergies are those of the whole table and
+// Seat_allergies those of the guests in given seats; Allergen_warning is set
+// while an item on the order contains something one of them is allergic to.
+// Invoiced is set while the order has live invoices, and is what stops two
+// cashiers invoicing it at once.
 type Order struct {
-	ID         primitive.ObjectID `bson:"_id"`
-	Order_date time.Time          `json:"order_date" validate:"required"`
-	Created_at time.Time          `json:"created_at" validate:"required"`
-	Updated_at time.Time          `json:"updated_at"`
-	Order_id   string             `json:"order_id"`
-	Table_id   *string            `json:"table_id" validate:"required"`
+	ID                primitive.ObjectID  `bson:"_id"`
+	Order_date        time.Time           `json:"order_date" validate:"required"`
+	Created_at        time.Time           `json:"created_at" validate:"required"`
+	Updated_at        time.Time           `json:"updated_at"`
+	Order_id          string              `json:"order_id"`
+	Table_id          *string             `json:"table_id" validate:"required"`
+	Server_id         *string             `json:"server_id"`
+	Status            string              `json:"status"`
+	Status_history    []OrderStatusChange `json:"status_history"`
+	Invoiced          bool                `json:"invoiced"`
+	Allergies         []string            `json:"allergies" validate:"dive,allergen"`
+	Seat_allergies    []SeatAllergies     `json:"seat_allergies" validate:"dive"`
+	Allergen_warning  bool                `json:"allergen_warning"`
+	Allergen_warnings []AllergenWarning   `json:"allergen_warnings"`
+}
+
+// AllergenWarnings checks the order's items against the allergies noted
+// for it. An item without a seat may go to anyone, so it is checked against
+// every guest's allergies.
+func (order Order) AllergenWarnings(orderItems []OrderItem) []AllergenWarning {
+	warnings := []AllergenWarning{}
+	for _, orderItem := range orderItems {
+		if orderItem.CurrentKitchenStatus() == KitchenCancelled {
+			continue
+		}
+
+		allergies := append([]string{}, order.Allergies...)
+		for _, seat := range order.Seat_allergies {
+			if orderItem.Seat == nil || *orderItem.Seat == seat.Seat {
+				allergies = append(allergies, seat.Allergens...)
+			}
+		}
+
+		common := CommonAllergens(orderItem.Allergens, allergies)
+		if len(common) == 0 {
+			continue
+		}
+
+		warning := AllergenWarning{Order_item_id: orderItem.Order_item_id, Allergens: common}
+		if orderItem.Food_id != nil {
+			warning.Food_id = *orderItem.Food_id
+		}
+		if orderItem.Seat != nil {
+			warning.Seat = *orderItem.Seat
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
+
+// CurrentStatus treats orders stored before statuses existed as open.
+func (order Order) CurrentStatus() string {
+	if order.Status == "" {
+		return OrderOpen
+	}
+	return order.Status
 }
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCanTransitionOrder(t *testing.T) {
+	statuses := []string{OrderOpen, OrderSentToKitchen, OrderReady, OrderServed, OrderPaid, OrderClosed, OrderCancelled}
+	allowed := map[[2]string]bool{
+		{OrderOpen, OrderSentToKitchen}:      true,
+		{OrderOpen, OrderCancelled}:          true,
+		{OrderSentToKitchen, OrderReady}:     true,
+		{OrderSentToKitchen, OrderCancelled}: true,
+		{OrderReady, OrderServed}:            true,
+		{OrderReady, OrderCancelled}:         true,
+		{OrderServed, OrderPaid}:             true,
+		{OrderServed, OrderClosed}:           true,
+		{OrderPaid, OrderClosed}:             true,
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			want := allowed[[2]string{from, to}]
+			if got := CanTransitionOrder(from, to); got != want {
+				t.Errorf("CanTransitionOrder(%s, %s) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+
+	// orders stored without a status go through CurrentStatus first
+	if CanTransitionOrder("", OrderSentToKitchen) {
+		t.Errorf("CanTransitionOrder(\"\", %s) = true, want false", OrderSentToKitchen)
+	}
+}
+
+func TestNextOrderStatuses(t *testing.T) {
+	tests := map[string][]string{
+		OrderOpen:      {OrderSentToKitchen, OrderCancelled},
+		OrderServed:    {OrderPaid, OrderClosed},
+		OrderClosed:    {},
+		OrderCancelled: {},
+	}
+
+	for from, want := range tests {
+		if got := NextOrderStatuses(from); !slices.Equal(got, want) {
+			t.Errorf("NextOrderStatuses(%s) = %v, want %v", from, got, want)
+		}
+	}
+
+	// the result is a copy the caller may change
+	next := NextOrderStatuses(OrderOpen)
+	next[0] = OrderClosed
+	if !CanTransitionOrder(OrderOpen, OrderSentToKitchen) || CanTransitionOrder(OrderOpen, OrderClosed) {
+		t.Errorf("changing the returned statuses changed the lifecycle")
+	}
+}
+
+func TestOrderCurrentStatus(t *testing.T) {
+	if got := (Order{}).CurrentStatus(); got != OrderOpen {
+		t.Errorf("an order stored without a status is %s, want %s", got, OrderOpen)
+	}
+	if got := (Order{Status: OrderServed}).CurrentStatus(); got != OrderServed {
+		t.Errorf("CurrentStatus() = %s, want %s", got, OrderServed)
+	}
+}
+
+func TestOrderAllergenWarnings(t *testing.T) {
+	seat := func(n int) *int { return &n }
+	order := Order{
+		Allergies:      []string{AllergenPeanuts},
+		Seat_allergies: []SeatAllergies{{Seat: 2, Allergens: []string{AllergenMilk, AllergenEggs}}},
+	}
+
+	tests := []struct {
+		name      string
+		orderItem OrderItem
+		want      []string
+	}{
+		{"safe for everyone", OrderItem{Allergens: []string{AllergenCelery}}, nil},
+		{"the table's allergy", OrderItem{Seat: seat(1), Allergens: []string{AllergenPeanuts}}, []string{AllergenPeanuts}},
+		{"the seat's allergy", OrderItem{Seat: seat(2), Allergens: []string{AllergenEggs, AllergenMilk}}, []string{AllergenEggs, AllergenMilk}},
+		{"another seat's allergy", OrderItem{Seat: seat(3), Allergens: []string{AllergenMilk}}, nil},
+		{"shared by the table", OrderItem{Allergens: []string{AllergenMilk, AllergenPeanuts}}, []string{AllergenMilk, AllergenPeanuts}},
+		{"cancelled", OrderItem{Kitchen_status: KitchenCancelled, Allergens: []string{AllergenPeanuts}}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.orderItem.Order_item_id = "item"
+			warnings := order.AllergenWarnings([]OrderItem{test.orderItem})
+			if test.want == nil {
+				if len(warnings) != 0 {
+					t.Errorf("AllergenWarnings() = %+v, want none", warnings)
+				}
+				return
+			}
+			if len(warnings) != 1 || warnings[0].Order_item_id != "item" || !slices.Equal(warnings[0].Allergens, test.want) {
+				t.Errorf("AllergenWarnings() = %+v, want %v on the item", warnings, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package models
+
+import "github.com/tokha04/go-restautant-management/money"
+
+// OrderSummary is the per-order roll-up of order items joined with their food
+// and table, as produced by OrderItemStore.ItemsByOrder. Total_count counts
+// units, so five cokes ordered on one item count five, and each line's
+// Amount is its count times its unit price.
+type OrderSummary struct {
+	Order_id     string      `json:"order_id"`
+	Table_id     string      `json:"table_id"`
+	Table_number int         `json:"table_number"`
+	Guests       int         `json:"guests"`
+	Payment_due  money.Money `json:"payment_due"`
+	Total_count  int         `json:"total_count"`
+	Order_items  []OrderLine `json:"order_items"`
+}
+
+type OrderLine struct {
+	Order_item_id string          `json:"order_item_id"`
+	Food_id       string          `json:"food_id"`
+	Food_name     string          `json:"food_name"`
+	Food_image    string          `json:"food_image"`
+	Menu_id       string          `json:"menu_id"`
+	Menu_category string          `json:"menu_category"`
+	Quantity      string          `json:"quantity"`
+	Variant       string          `json:"variant,omitempty"`
+	Count         int             `json:"count"`
+	Notes         string          `json:"notes,omitempty"`
+	Seat          int             `json:"seat,omitempty"`
+	Modifiers     []OrderModifier `json:"modifiers,omitempty"`
+	Unit_price    money.Money     `json:"unit_price"`
+	Amount        money.Money     `json:"amount"`
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"time"
+
+	"github.com/tokha04/go-restautant-management/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	PaymentPending           = "PENDING"
+	PaymentAuthorized        = "AUTHORIZED"
+	PaymentCaptured          = "CAPTURED"
+	PaymentFailed            = "FAILED"
+	PaymentRefunded          = "REFUNDED"
+	PaymentPartiallyRefunded = "PARTIALLY_REFUNDED"
+	PaymentVoided            = "VOIDED"
+)
+
+const (
+	PaymentCard = "CARD"
+	PaymentCash = "CASH"
+)
+
+// Payment is one attempt to settle an invoice. Every attempt is kept, failed
+// ones included, so the history of an invoice can be audited. The tip is
+// charged on top of Amount and belongs to Tip_recipient; it does not count
+// towards the invoice balance.
+type Payment struct {
+	ID             primitive.ObjectID `bson:"_id"`
+	Payment_id     string             `json:"payment_id"`
+	Invoice_id     string             `json:"invoice_id"`
+	Provider       string             `json:"provider"`
+	Reference      string             `json:"reference"`
+	Method         *string            `json:"method" validate:"required,eq=CARD|eq=CASH"`
+	Card_token     string             `json:"card_token,omitempty" bson:"-"`
+	Amount         money.Money        `json:"amount"`
+	Refunded       money.Money        `json:"refunded"`
+	Tip            money.Money        `json:"tip"`
+	Tip_recipient  string             `json:"tip_recipient"`
+	Shift_id       string             `json:"shift_id,omitempty"`
+	Drawer_id      string             `json:"drawer_id,omitempty"`
+	Status         string             `json:"status"`
+	Failure_reason string             `json:"failure_reason,omitempty"`
+	Events         []PaymentEvent     `json:"events"`
+	Created_by     string             `json:"created_by"`
+	Created_at     time.Time          `json:"created_at"`
+	Updated_at     time.Time          `json:"updated_at"`
+}
+
+// PaymentEvent records a step of the payment, either a call to the provider
+// or a webhook received from it.
+type PaymentEvent struct {
+	Event_id string    `json:"event_id,omitempty"`
+	Type     string    `json:"type"`
+	Status   string    `json:"status"`
+	Message  string    `json:"message,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// IsCaptured reports whether the payment's money was taken, whether or not
+// it was refunded since.
+func (payment Payment) IsCaptured() bool {
+	switch payment.Status {
+	case PaymentCaptured, PaymentPartiallyRefunded, PaymentRefunded:
+		return true
+	}
+	return false
+}
+
+// Refundable returns how much of the payment can still be refunded.
+func (payment Payment) Refundable() money.Money {
+	if !payment.IsCaptured() {
+		return money.Zero()
+	}
+	return payment.Amount.Sub(payment.Refunded)
+}
+
+// HasEvent reports whether the webhook event was already applied.
+func (payment Payment) HasEvent(eventId string) bool {
+	for _, event := range payment.Events {
+		if eventId != "" && event.Event_id == eventId {
+			return true
+		}
+	}
+	return false
+}
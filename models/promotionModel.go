@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/tokha04/go-restautant-management/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DiscountPercent = "PERCENT"
+	DiscountFixed   = "FIXED"
+)
+
+// A discount applies either to one order item or to the whole bill.
+const (
+	ScopeItem = "ITEM"
+	ScopeBill = "BILL"
+)
+
+// Promotion is a reusable discount. With a Code it is a coupon guests redeem;
+// Max_uses caps how many orders may redeem it. Item promotions may be limited
+// to one food.
+type Promotion struct {
+	ID           primitive.ObjectID `bson:"_id"`
+	Promotion_id string             `json:"promotion_id"`
+	Name         *string            `json:"name" validate:"required,min=2,max=100"`
+	Code         *string            `json:"code" validate:"omitempty,min=3,max=32,alphanum"`
+	Kind         *string            `json:"kind" validate:"required,eq=PERCENT|eq=FIXED"`
+	Scope        *string            `json:"scope" validate:"required,eq=ITEM|eq=BILL"`
+	Percent      *float64           `json:"percent" validate:"required_if=Kind PERCENT,omitempty,gt=0,max=100"`
+	Amount       *money.Money       `json:"amount" validate:"required_if=Kind FIXED,omitempty,gt=0"`
+	Food_id      *string            `json:"food_id"`
+	Valid_from   *time.Time         `json:"valid_from"`
+	Valid_to     *time.Time         `json:"valid_to"`
+	Max_uses     *int               `json:"max_uses" validate:"omitempty,min=1"`
+	Uses         int                `json:"uses"`
+	Active       *bool              `json:"active"`
+	Created_at   time.Time          `json:"created_at"`
+	Updated_at   time.Time          `json:"updated_at"`
+}
+
+// ValidAt reports whether the promotion can be redeemed at t. It does not
+// look at the usage limit.
+func (promotion Promotion) ValidAt(t time.Time) bool {
+	if promotion.Active != nil && !*promotion.Active {
+		return false
+	}
+	if promotion.Valid_from != nil && t.Before(*promotion.Valid_from) {
+		return false
+	}
+	if promotion.Valid_to != nil && !t.Before(*promotion.Valid_to) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ReservationBooked    = "BOOKED"
+	ReservationCancelled = "CANCELLED"
+)
+
+type Reservation struct {
+	ID               primitive.ObjectID `bson:"_id"`
+	Reservation_id   string             `json:"reservation_id"`
+	Table_id         *string            `json:"table_id" validate:"required"`
+	Guest_name       *string            `json:"guest_name" validate:"required,min=2,max=100"`
+	Phone            *string            `json:"phone" validate:"required"`
+	Party_size       *int               `json:"party_size" validate:"required,min=1"`
+	Start_time       *time.Time         `json:"start_time" validate:"required"`
+	Duration_minutes *int               `json:"duration_minutes" validate:"required,min=15,max=720"`
+	End_time         time.Time          `json:"end_time"`
+	Notes            *string            `json:"notes"`
+	Status           string             `json:"status"`
+	Created_at       time.Time          `json:"created_at"`
+	Updated_at       time.Time          `json:"updated_at"`
+}
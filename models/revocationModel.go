@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	RevokedSession = "SESSION"
+	RevokedUser    = "USER"
+)
+
+// Revocation blocks access tokens before they expire. A SESSION entry blocks
+// every token of one session; a USER entry blocks every token of the user
+// issued up to Issued_before (unix seconds). Entries are removed once they
+// expire, when every token they block has expired as well.
+type Revocation struct {
+	ID            primitive.ObjectID `bson:"_id"`
+	Kind          string             `json:"kind"`
+	Key           string             `json:"key"`
+	Issued_before int64              `json:"issued_before,omitempty"`
+	Expires_at    time.Time          `json:"expires_at"`
+	Created_at    time.Time          `json:"created_at"`
+}
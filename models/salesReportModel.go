@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/tokha04/go-restautant-management/money"
+)
+
+// Sales can be grouped by any of these. Days and hours are in the
+// restaurant's local time; hours are the hour of the day, whatever the date.
+const (
+	SalesByDay     = "day"
+	SalesByHour    = "hour"
+	SalesByMenu    = "menu"
+	SalesByFood    = "food"
+	SalesByTable   = "table"
+	SalesByUser    = "user"
+	SalesByPayment = "payment_method"
+)
+
+// SalesGroups lists every grouping a sales report accepts.
+var SalesGroups = []string{SalesByDay, SalesByHour, SalesByMenu, SalesByFood, SalesByTable, SalesByUser, SalesByPayment}
+
+// PaymentNone groups the sales of invoices nothing was paid on yet.
+const PaymentNone = "UNPAID"
+
+// SalesRow totals the invoice lines of one group. Gross is what the lines
+// were priced at, Discounts what came off them, Net what is left without
+// taxes and Tax the taxes on it. Covers counts the guests of every order
+// with a line in the group once. The shares of an evenly or custom split
+// order have no lines; they count with their invoice totals and no items.
+type SalesRow struct {
+	Key       string      `json:"key"`
+	Label     string      `json:"label"`
+	Invoices  int         `json:"invoices"`
+	Items     int         `json:"items"`
+	Covers    int         `json:"covers"`
+	Gross     money.Money `json:"gross"`
+	Discounts money.Money `json:"discounts"`
+	Tax       money.Money `json:"tax"`
+	Net       money.Money `json:"net"`
+}
+
+// SalesReport covers the invoices created in [From, To), voided ones
+// excepted. Totals is the report over every line, not the sum of the rows:
+// an order appears in several rows when grouped by food, but its guests are
+// only counted once.
+type SalesReport struct {
+	From     time.Time  `json:"from"`
+	To       time.Time  `json:"to"`
+	Group_by string     `json:"group_by"`
+	Rows     []SalesRow `json:"rows"`
+	Totals   SalesRow   `json:"totals"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ServiceCharge is an automatic charge in percent of the bill, e.g. 12.5% for
+// parties of Min_guests or more. The table's Number_of_guests is the party
+// size. Service charges are not taxed.
+type ServiceCharge struct {
+	ID                primitive.ObjectID `bson:"_id"`
+	Service_charge_id string             `json:"service_charge_id"`
+	Name              *string            `json:"name" validate:"required,min=2,max=100"`
+	Rate              *float64           `json:"rate" validate:"required,gt=0,max=100"`
+	Min_guests        *int               `json:"min_guests" validate:"omitempty,min=1"`
+	Active            *bool              `json:"active"`
+	Created_at        time.Time          `json:"created_at"`
+	Updated_at        time.Time          `json:"updated_at"`
+}
+
+func (charge ServiceCharge) IsActive() bool {
+	return charge.Active == nil || *charge.Active
+}
+
+// AppliesTo reports whether the charge is due for a party of guests.
+func (charge ServiceCharge) AppliesTo(guests int) bool {
+	if !charge.IsActive() {
+		return false
+	}
+	return charge.Min_guests == nil || guests >= *charge.Min_guests
+}
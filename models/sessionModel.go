@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is one refresh token family. Every login starts a new session and
+// each refresh rotates its token; only the hash of the current refresh token
+// is kept.
+type Session struct {
+	ID                 primitive.ObjectID `bson:"_id"`
+	Session_id         string             `json:"session_id"`
+	User_id            string             `json:"user_id"`
+	Refresh_token_hash string             `json:"-"`
+	Revoked            bool               `json:"revoked"`
+	Revoked_reason     string             `json:"revoked_reason,omitempty"`
+	Revoked_at         *time.Time         `json:"revoked_at,omitempty"`
+	Expires_at         time.Time          `json:"expires_at"`
+	Created_at         time.Time          `json:"created_at"`
+	Updated_at         time.Time          `json:"updated_at"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Shift is the time a staff member is on duty. Tips taken while a shift is
+// open are credited to it.
+type Shift struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	Shift_id   string             `json:"shift_id"`
+	User_id    string             `json:"user_id"`
+	Started_at time.Time          `json:"started_at"`
+	Ended_at   *time.Time         `json:"ended_at"`
+}
+
+func (shift Shift) IsOpen() bool {
+	return shift.Ended_at == nil
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TaxRate is a VAT or sales tax rate in percent. A rate bound to a food item
+// beats one bound to a menu category, which beats a rate bound to neither.
+// Rates on the same level stack. Inclusive rates are already part of the
+// menu price; exclusive rates are added on top.
+type TaxRate struct {
+	ID          primitive.ObjectID `bson:"_id"`
+	Tax_rate_id string             `json:"tax_rate_id"`
+	Name        *string            `json:"name" validate:"required,min=2,max=100"`
+	Rate        *float64           `json:"rate" validate:"required,min=0,max=100"`
+	Inclusive   *bool              `json:"inclusive" validate:"required"`
+	Category    *string            `json:"category"`
+	Food_id     *string            `json:"food_id"`
+	Active      *bool              `json:"active"`
+	Created_at  time.Time          `json:"created_at"`
+	Updated_at  time.Time          `json:"updated_at"`
+}
+
+func (rate TaxRate) IsActive() bool {
+	return rate.Active == nil || *rate.Active
+}
@@ -6,16 +6,28 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	RoleAdmin   = "ADMIN"
+	RoleManager = "MANAGER"
+	RoleWaiter  = "WAITER"
+	RoleChef    = "CHEF"
+	RoleCashier = "CASHIER"
+)
+
+// User is a member of staff. Token and Refresh_token are only handed out
+// when signing in and are never stored; sessions keep a hash of the refresh
+// token instead.
 type User struct {
 	ID            primitive.ObjectID `bson:"_id"`
 	First_name    *string            `json:"first_name" validate:"required,min=2,max=100"`
 	Last_name     *string            `json:"last_name" validate:"required,min=2,max=100"`
 	Password      *string            `json:"password" validate:"required,min=6"`
-	Email         *string            `json:"email" validate:"email, required"`
+	Email         *string            `json:"email" validate:"required,email"`
 	Avatar        *string            `json:"avatar"`
 	Phone         *string            `json:"phone" validate:"required"`
-	Token         *string            `json:"token"`
-	Refresh_token *string            `json:"refresh_token"`
+	Token         *string            `json:"token" bson:"-"`
+	Refresh_token *string            `json:"refresh_token" bson:"-"`
+	Role          *string            `json:"role" validate:"omitempty,eq=ADMIN|eq=MANAGER|eq=WAITER|eq=CHEF|eq=CASHIER"`
 	Created_at    time.Time          `json:"created_at"`
 	Updated_at    time.Time          `json:"updated_at"`
 	User_id       string             `json:"user_id"`
@@ -0,0 +1,342 @@
+// Package money represents amounts as integer minor units of a currency, so
+// prices, taxes and totals add up to the cent.
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// DefaultCurrency is the ISO 4217 code of the currency the restaurant bills
+// in. It is read from the CURRENCY environment variable.
+var DefaultCurrency = defaultCurrency()
+
+func defaultCurrency() string {
+	if v := strings.TrimSpace(os.Getenv("CURRENCY")); v != "" {
+		return strings.ToUpper(v)
+	}
+	return "USD"
+}
+
+// exponents lists the currencies whose minor unit is not a hundredth.
+var exponents = map[string]int{
+	"BHD": 3, "CLP": 0, "ISK": 0, "JOD": 3, "JPY": 0,
+	"KRW": 0, "KWD": 3, "OMR": 3, "TND": 3, "VND": 0,
+}
+
+// Exponent returns the number of decimals of the currency's minor unit.
+func Exponent(currency string) int {
+	if exp, ok := exponents[currency]; ok {
+		return exp
+	}
+	return 2
+}
+
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// Money is an amount in the minor unit of its currency, e.g. cents.
+type Money struct {
+	Minor    int64
+	Currency string
+}
+
+// New returns minor units of the default currency.
+func New(minor int64) Money {
+	return Money{Minor: minor, Currency: DefaultCurrency}
+}
+
+// Zero returns no money in the default currency.
+func Zero() Money {
+	return New(0)
+}
+
+// FromFloat converts a decimal amount such as 11.5 into money, rounding half
+// away from zero to the minor unit. It exists for legacy data only.
+func FromFloat(amount float64, currency string) Money {
+	scale := math.Pow10(Exponent(currency))
+	return Money{Minor: int64(math.Round(amount * scale)), Currency: currency}
+}
+
+// Parse reads a decimal string such as "11.50". More decimals than the
+// currency has are rejected rather than rounded.
+func Parse(amount string, currency string) (Money, error) {
+	amount = strings.TrimSpace(amount)
+	exp := Exponent(currency)
+
+	negative := strings.HasPrefix(amount, "-")
+	amount = strings.TrimPrefix(amount, "-")
+
+	whole, fraction, _ := strings.Cut(amount, ".")
+	if whole == "" && fraction == "" {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	if len(fraction) > exp {
+		return Money{}, fmt.Errorf("%w: %q has more than %d decimals", ErrInvalidAmount, amount, exp)
+	}
+	fraction += strings.Repeat("0", exp-len(fraction))
+
+	digits := whole + fraction
+	if digits == "" {
+		digits = "0"
+	}
+	minor, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || strings.ContainsAny(digits, "+-") {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+
+	if negative {
+		minor = -minor
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+// String formats the amount with the currency's decimals, e.g. "11.50".
+func (m Money) String() string {
+	exp := Exponent(m.Currency)
+
+	minor := m.Minor
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+
+	if exp == 0 {
+		return sign + strconv.FormatInt(minor, 10)
+	}
+
+	scale := int64(math.Pow10(exp))
+	return fmt.Sprintf("%s%d.%0*d", sign, minor/scale, exp, minor%scale)
+}
+
+func (m Money) currencyWith(other Money) string {
+	if m.Currency == "" {
+		return other.Currency
+	}
+	return m.Currency
+}
+
+func (m Money) Add(other Money) Money {
+	return Money{Minor: m.Minor + other.Minor, Currency: m.currencyWith(other)}
+}
+
+func (m Money) Sub(other Money) Money {
+	return Money{Minor: m.Minor - other.Minor, Currency: m.currencyWith(other)}
+}
+
+// Mul multiplies the amount by a whole quantity.
+func (m Money) Mul(quantity int64) Money {
+	return Money{Minor: m.Minor * quantity, Currency: m.Currency}
+}
+
+// rateScale is the precision rates and divisors are fixed to before they are
+// applied, a millionth, so amounts are only ever multiplied and divided as
+// integers.
+const rateScale = 1_000_000
+
+func fixed(x float64) int64 {
+	return int64(math.Round(x * rateScale))
+}
+
+// mulDiv returns a*b/c rounded half away from zero, without overflowing.
+func mulDiv(a, b, c int64) int64 {
+	n := new(big.Int).Mul(big.NewInt(a), big.NewInt(b))
+	d := big.NewInt(c)
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+
+	// the quotient is truncated; a remainder of at least half the divisor
+	// moves it one further from zero
+	twice := new(big.Int).Lsh(new(big.Int).Abs(r), 1)
+	if twice.Cmp(new(big.Int).Abs(d)) >= 0 {
+		if (n.Sign() < 0) != (d.Sign() < 0) {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q.Int64()
+}
+
+// Percent returns rate percent of the amount, rounded half away from zero.
+func (m Money) Percent(rate float64) Money {
+	return Money{Minor: mulDiv(m.Minor, fixed(rate), 100*rateScale), Currency: m.Currency}
+}
+
+// Div divides the amount by divisor, which must not be zero, rounded half
+// away from zero.
+func (m Money) Div(divisor float64) Money {
+	return Money{Minor: mulDiv(m.Minor, rateScale, fixed(divisor)), Currency: m.Currency}
+}
+
+func (m Money) IsZero() bool {
+	return m.Minor == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Minor < 0
+}
+
+// Allocate divides the amount in proportion to weights using the largest
+// remainder method, so the parts always add up to the amount exactly. Cents
+// left over after the proportional split go to the earliest parts. A
+// negative amount, such as a refund, is split like its positive.
+func (m Money) Allocate(weights []int64) []Money {
+	if m.Minor < 0 {
+		parts := Money{Minor: -m.Minor, Currency: m.Currency}.Allocate(weights)
+		for i := range parts {
+			parts[i].Minor = -parts[i].Minor
+		}
+		return parts
+	}
+
+	parts := make([]Money, len(weights))
+	for i := range parts {
+		parts[i].Currency = m.Currency
+	}
+
+	var weightSum int64
+	for _, weight := range weights {
+		weightSum += weight
+	}
+	if weightSum == 0 {
+		return parts
+	}
+
+	remainders := make([]int64, len(weights))
+	allocated := int64(0)
+	for i, weight := range weights {
+		parts[i].Minor = m.Minor * weight / weightSum
+		remainders[i] = m.Minor * weight % weightSum
+		allocated += parts[i].Minor
+	}
+
+	for left := m.Minor - allocated; left > 0; left-- {
+		largest := 0
+		for i := range remainders {
+			if remainders[i] > remainders[largest] {
+				largest = i
+			}
+		}
+		parts[largest].Minor++
+		remainders[largest] = -1
+	}
+
+	return parts
+}
+
+// Sum adds up amounts, returning zero in the default currency for none.
+func Sum(amounts ...Money) Money {
+	total := Zero()
+	for i, amount := range amounts {
+		if i == 0 {
+			total.Currency = amount.Currency
+		}
+		total = total.Add(amount)
+	}
+	return total
+}
+
+type jsonMoney struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON writes the amount as a decimal string so clients never see a
+// binary float.
+func (m Money) MarshalJSON() ([]byte, error) {
+	currency := m.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	return json.Marshal(jsonMoney{Amount: Money{Minor: m.Minor, Currency: currency}.String(), Currency: currency})
+}
+
+// UnmarshalJSON accepts {"amount": "11.50", "currency": "USD"} as well as a
+// bare number or string in the default currency. Amounts in any other
+// currency than the default are rejected.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	amount, currency := "", DefaultCurrency
+	switch {
+	case bytes.HasPrefix(data, []byte("{")):
+		var doc struct {
+			Amount   json.RawMessage `json:"amount"`
+			Currency string          `json:"currency"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		if doc.Currency != "" {
+			currency = strings.ToUpper(doc.Currency)
+		}
+		amount = strings.Trim(string(bytes.TrimSpace(doc.Amount)), `"`)
+	case bytes.HasPrefix(data, []byte(`"`)):
+		amount = strings.Trim(string(data), `"`)
+	default:
+		amount = string(data)
+	}
+
+	if currency != DefaultCurrency {
+		return fmt.Errorf("%w: amounts must be in %s, got %s", ErrInvalidAmount, DefaultCurrency, currency)
+	}
+
+	parsed, err := Parse(amount, currency)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+// UnmarshalBSONValue reads the {minor, currency} document written for Money.
+// Prices stored as plain numbers before amounts were kept in minor units are
+// read as the default currency, so documents that have not been migrated yet
+// still load.
+func (m *Money) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	raw := bson.RawValue{Type: t, Value: data}
+
+	switch t {
+	case bson.TypeEmbeddedDocument:
+		var doc struct {
+			Minor    int64
+			Currency string
+		}
+		if err := raw.Unmarshal(&doc); err != nil {
+			return err
+		}
+		*m = Money{Minor: doc.Minor, Currency: doc.Currency}
+	case bson.TypeDouble:
+		*m = FromFloat(raw.Double(), DefaultCurrency)
+	case bson.TypeInt32:
+		*m = FromFloat(float64(raw.Int32()), DefaultCurrency)
+	case bson.TypeInt64:
+		*m = FromFloat(float64(raw.Int64()), DefaultCurrency)
+	case bson.TypeDecimal128:
+		parsed, err := Parse(raw.Decimal128().String(), DefaultCurrency)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+	case bson.TypeNull, bson.TypeUndefined:
+		*m = Money{}
+	default:
+		return fmt.Errorf("%w: cannot decode BSON %s into money", ErrInvalidAmount, t)
+	}
+
+	return nil
+}
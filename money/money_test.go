@@ -0,0 +1,130 @@
+package money
+
+import "testing"
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  int64
+		weights []int64
+		want    []int64
+	}{
+		{"even", 900, []int64{1, 1, 1}, []int64{300, 300, 300}},
+		{"leftover cent goes first", 1000, []int64{1, 1, 1}, []int64{334, 333, 333}},
+		{"largest remainder wins", 100, []int64{1, 2, 3}, []int64{17, 33, 50}},
+		{"negative", -1000, []int64{1, 1, 1}, []int64{-334, -333, -333}},
+		{"negative by largest remainder", -100, []int64{1, 2, 3}, []int64{-17, -33, -50}},
+		{"zero weight takes nothing", 1000, []int64{0, 1, 1}, []int64{0, 500, 500}},
+		{"no weights", 1000, []int64{0, 0}, []int64{0, 0}},
+		{"zero", 0, []int64{1, 1}, []int64{0, 0}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parts := New(test.amount).Allocate(test.weights)
+			if len(parts) != len(test.want) {
+				t.Fatalf("got %d parts, want %d", len(parts), len(test.want))
+			}
+
+			var sum int64
+			for i, part := range parts {
+				if part.Minor != test.want[i] {
+					t.Errorf("part %d = %d, want %d", i, part.Minor, test.want[i])
+				}
+				if part.Currency != DefaultCurrency {
+					t.Errorf("part %d is in %q, want %q", i, part.Currency, DefaultCurrency)
+				}
+				sum += part.Minor
+			}
+
+			weightSum := int64(0)
+			for _, weight := range test.weights {
+				weightSum += weight
+			}
+			if weightSum > 0 && sum != test.amount {
+				t.Errorf("parts add up to %d, want %d", sum, test.amount)
+			}
+		})
+	}
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		amount int64
+		rate   float64
+		want   int64
+	}{
+		{1000, 20, 200},
+		{1050, 12.5, 131},
+		{5, 10, 1},
+		{4, 10, 0},
+		{-5, 10, -1},
+		{-1050, 12.5, -131},
+		{1999, 7.25, 145},
+		{1000, 0, 0},
+		// large enough to overflow a*b in int64
+		{900_000_000_000_000, 50, 450_000_000_000_000},
+	}
+
+	for _, test := range tests {
+		if got := New(test.amount).Percent(test.rate); got.Minor != test.want {
+			t.Errorf("%d.Percent(%v) = %d, want %d", test.amount, test.rate, got.Minor, test.want)
+		}
+	}
+}
+
+func TestDiv(t *testing.T) {
+	tests := []struct {
+		amount  int64
+		divisor float64
+		want    int64
+	}{
+		{1200, 1.2, 1000},
+		{1000, 3, 333},
+		{1001, 2, 501},
+		{-1001, 2, -501},
+		{-1000, 3, -333},
+		{1125, 1.125, 1000},
+		{1000, 1.07, 935},
+	}
+
+	for _, test := range tests {
+		if got := New(test.amount).Div(test.divisor); got.Minor != test.want {
+			t.Errorf("%d.Div(%v) = %d, want %d", test.amount, test.divisor, got.Minor, test.want)
+		}
+	}
+}
+
+func TestParseAndString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		out  string
+	}{
+		{"11.50", 1150, "11.50"},
+		{"11.5", 1150, "11.50"},
+		{"11", 1100, "11.00"},
+		{".05", 5, "0.05"},
+		{"-3.20", -320, "-3.20"},
+	}
+
+	for _, test := range tests {
+		m, err := Parse(test.in, DefaultCurrency)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.in, err)
+			continue
+		}
+		if m.Minor != test.want {
+			t.Errorf("Parse(%q) = %d, want %d", test.in, m.Minor, test.want)
+		}
+		if m.String() != test.out {
+			t.Errorf("Parse(%q).String() = %q, want %q", test.in, m.String(), test.out)
+		}
+	}
+
+	for _, in := range []string{"", "1.234", "abc", "1.-5"} {
+		if _, err := Parse(in, DefaultCurrency); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", in)
+		}
+	}
+}
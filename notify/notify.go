@@ -0,0 +1,78 @@
+// Package notify fans out events staff should act on, such as an invoice
+// falling overdue, to whoever is listening.
+package notify
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	EventInvoiceOverdue = "INVOICE_OVERDUE"
+)
+
+// Event is one notification. Subject is the id of the document it is about
+// and Data carries the document itself.
+type Event struct {
+	Type    string      `json:"type"`
+	Subject string      `json:"subject"`
+	Data    interface{} `json:"data"`
+	At      time.Time   `json:"at"`
+}
+
+// Notifier delivers events to every subscriber.
+type Notifier interface {
+	Notify(event Event)
+	// Subscribe returns a channel of events and a function that must be
+	// called once the subscriber goes away.
+	Subscribe() (<-chan Event, func())
+}
+
+const subscriberBuffer = 64
+
+// Broker is an in-process Notifier.
+type Broker struct {
+	mu          sync.RWMutex
+	subscribers map[chan Event]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{subscribers: map[chan Event]struct{}{}}
+}
+
+func (b *Broker) Notify(event Event) {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers {
+		// a subscriber that stopped reading must not stall the others
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func (b *Broker) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+
+	return ch, unsubscribe
+}
@@ -0,0 +1,36 @@
+package payments
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tokha04/go-restautant-management/money"
+)
+
+// ErrDisabled is returned for every call to DisabledProvider.
+var ErrDisabled = errors.New("card payments are disabled")
+
+// DisabledProvider stands in when no card processor is configured, so the
+// restaurant can still take cash. Every card operation fails with
+// ErrDisabled.
+type DisabledProvider struct{}
+
+func (DisabledProvider) Name() string {
+	return "disabled"
+}
+
+func (DisabledProvider) Authorize(ctx context.Context, req AuthorizeRequest) (Result, error) {
+	return Result{}, ErrDisabled
+}
+
+func (DisabledProvider) Capture(ctx context.Context, reference string, amount money.Money) (Result, error) {
+	return Result{}, ErrDisabled
+}
+
+func (DisabledProvider) Refund(ctx context.Context, reference string, amount money.Money) (Result, error) {
+	return Result{}, ErrDisabled
+}
+
+func (DisabledProvider) Void(ctx context.Context, reference string) (Result, error) {
+	return Result{}, ErrDisabled
+}
@@ -0,0 +1,189 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Card tokens understood by the mock provider. Any other token is approved.
+const (
+	MockTokenDeclined = "tok_declined"
+	MockTokenPending  = "tok_pending"
+)
+
+// MockProvider is a local stand-in for a card processor. A declined token
+// fails at once; a pending token is settled by a signed webhook posted to
+// Webhook_url after Webhook_delay, which exercises the same path a real
+// processor would use.
+type MockProvider struct {
+	Webhook_url   string
+	Webhook_delay time.Duration
+	Secret        []byte
+
+	mu       sync.Mutex
+	payments map[string]*mockPayment
+	client   *http.Client
+}
+
+type mockPayment struct {
+	amount   money.Money
+	captured money.Money
+	refunded money.Money
+	status   string
+}
+
+func NewMockProvider(webhookUrl string, secret []byte) *MockProvider {
+	return &MockProvider{
+		Webhook_url:   webhookUrl,
+		Webhook_delay: 2 * time.Second,
+		Secret:        secret,
+		payments:      map[string]*mockPayment{},
+		client:        &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *MockProvider) Name() string {
+	return "mock"
+}
+
+func (p *MockProvider) Authorize(ctx context.Context, req AuthorizeRequest) (Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	reference := "mock_" + primitive.NewObjectID().Hex()
+
+	switch req.Card_token {
+	case MockTokenDeclined:
+		p.payments[reference] = &mockPayment{amount: req.Amount, status: models.PaymentFailed}
+		return Result{Reference: reference, Status: models.PaymentFailed, Message: "card declined"}, nil
+	case MockTokenPending:
+		p.payments[reference] = &mockPayment{amount: req.Amount, status: models.PaymentPending}
+		go p.settleLater(reference)
+		return Result{Reference: reference, Status: models.PaymentPending, Message: "awaiting confirmation"}, nil
+	}
+
+	p.payments[reference] = &mockPayment{amount: req.Amount, status: models.PaymentAuthorized}
+	return Result{Reference: reference, Status: models.PaymentAuthorized}, nil
+}
+
+func (p *MockProvider) Capture(ctx context.Context, reference string, amount money.Money) (Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, ok := p.payments[reference]
+	if !ok {
+		return Result{}, ErrUnknownPayment
+	}
+	if payment.status != models.PaymentAuthorized {
+		return Result{Reference: reference, Status: payment.status, Message: "only authorized payments can be captured"}, nil
+	}
+	if amount.Minor > payment.amount.Minor {
+		return Result{Reference: reference, Status: payment.status, Message: "capture exceeds the authorized amount"}, nil
+	}
+
+	payment.captured = amount
+	payment.status = models.PaymentCaptured
+	return Result{Reference: reference, Status: payment.status}, nil
+}
+
+func (p *MockProvider) Refund(ctx context.Context, reference string, amount money.Money) (Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, ok := p.payments[reference]
+	if !ok {
+		return Result{}, ErrUnknownPayment
+	}
+	if payment.status != models.PaymentCaptured {
+		return Result{Reference: reference, Status: payment.status, Message: "only captured payments can be refunded"}, nil
+	}
+	if payment.refunded.Add(amount).Minor > payment.captured.Minor {
+		return Result{Reference: reference, Status: payment.status, Message: "refund exceeds the captured amount"}, nil
+	}
+
+	payment.refunded = payment.refunded.Add(amount)
+	if payment.refunded.Minor == payment.captured.Minor {
+		payment.status = models.PaymentRefunded
+	}
+	return Result{Reference: reference, Status: models.PaymentRefunded}, nil
+}
+
+func (p *MockProvider) Void(ctx context.Context, reference string) (Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, ok := p.payments[reference]
+	if !ok {
+		return Result{}, ErrUnknownPayment
+	}
+	if payment.status != models.PaymentAuthorized && payment.status != models.PaymentPending {
+		return Result{Reference: reference, Status: payment.status, Message: "only uncaptured payments can be voided"}, nil
+	}
+
+	payment.status = models.PaymentVoided
+	return Result{Reference: reference, Status: payment.status}, nil
+}
+
+// settleLater confirms a pending payment through the webhook, the way an
+// asynchronous processor would.
+func (p *MockProvider) settleLater(reference string) {
+	time.Sleep(p.Webhook_delay)
+
+	p.mu.Lock()
+	payment := p.payments[reference]
+	if payment.status != models.PaymentPending {
+		p.mu.Unlock()
+		return
+	}
+	payment.status = models.PaymentCaptured
+	payment.captured = payment.amount
+	p.mu.Unlock()
+
+	if p.Webhook_url == "" {
+		return
+	}
+
+	event := Event{
+		Event_id:  "evt_" + primitive.NewObjectID().Hex(),
+		Type:      EventPaymentSucceeded,
+		Reference: reference,
+	}
+	if err := p.post(event); err != nil {
+		log.Printf("mock provider could not deliver webhook for %s: %v", reference, err)
+	}
+}
+
+func (p *MockProvider) post(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.Webhook_url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(p.Secret, body, time.Now()))
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", res.Status)
+	}
+	return nil
+}
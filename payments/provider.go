@@ -0,0 +1,41 @@
+// Package payments talks to card processors. Controllers only see Provider,
+// so a real processor can replace the mock without touching the billing
+// flow.
+package payments
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tokha04/go-restautant-management/money"
+)
+
+// ErrUnknownPayment is returned for a reference the provider never issued.
+var ErrUnknownPayment = errors.New("unknown payment reference")
+
+// Provider is a card processor. Each call returns the provider's view of the
+// payment; a declined card is a Result with a failed status, not an error.
+// Errors are reserved for the provider being unreachable or rejecting the
+// request itself.
+type Provider interface {
+	Name() string
+	Authorize(ctx context.Context, req AuthorizeRequest) (Result, error)
+	Capture(ctx context.Context, reference string, amount money.Money) (Result, error)
+	Refund(ctx context.Context, reference string, amount money.Money) (Result, error)
+	Void(ctx context.Context, reference string) (Result, error)
+}
+
+type AuthorizeRequest struct {
+	Payment_id string
+	Invoice_id string
+	Amount     money.Money
+	Card_token string
+}
+
+// Result uses the models.Payment* statuses. A PENDING result is settled
+// later by a webhook.
+type Result struct {
+	Reference string
+	Status    string
+	Message   string
+}
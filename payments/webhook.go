@@ -0,0 +1,85 @@
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries "t=<unix seconds>,v1=<hex HMAC-SHA256>" where the
+// MAC covers "<t>.<body>".
+const SignatureHeader = "X-Payment-Signature"
+
+// SignatureTolerance bounds how old a signed webhook may be, which limits
+// replays of captured requests.
+const SignatureTolerance = 5 * time.Minute
+
+const (
+	EventPaymentSucceeded = "payment.succeeded"
+	EventPaymentFailed    = "payment.failed"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Event is the body of a webhook sent by a provider.
+type Event struct {
+	Event_id  string `json:"event_id"`
+	Type      string `json:"type"`
+	Reference string `json:"reference"`
+	Message   string `json:"message"`
+}
+
+// Sign returns the signature header value for body sent at t.
+func Sign(secret []byte, body []byte, t time.Time) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, mac(secret, timestamp, body))
+}
+
+// VerifySignature checks the signature header of a webhook body received at
+// now.
+func VerifySignature(secret []byte, header string, body []byte, now time.Time) error {
+	if len(secret) == 0 {
+		return fmt.Errorf("%w: no webhook secret is configured", ErrInvalidSignature)
+	}
+
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+	if timestamp == "" || signature == "" {
+		return fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: malformed timestamp", ErrInvalidSignature)
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > SignatureTolerance || age < -SignatureTolerance {
+		return fmt.Errorf("%w: timestamp outside the tolerance", ErrInvalidSignature)
+	}
+
+	expected := mac(secret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return fmt.Errorf("%w: signature mismatch", ErrInvalidSignature)
+	}
+	return nil
+}
+
+func mac(secret []byte, timestamp string, body []byte) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,35 @@
+package payments
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	secret := []byte("secret")
+	body := []byte(`{"event_id":"evt_1"}`)
+	now := time.Now()
+
+	if err := VerifySignature(secret, Sign(secret, body, now), body, now); err != nil {
+		t.Fatalf("a valid signature was refused: %v", err)
+	}
+
+	invalid := map[string]struct {
+		secret []byte
+		header string
+		body   []byte
+	}{
+		"other secret":    {secret, Sign([]byte("other"), body, now), body},
+		"changed body":    {secret, Sign(secret, body, now), []byte(`{"event_id":"evt_2"}`)},
+		"too old":         {secret, Sign(secret, body, now.Add(-SignatureTolerance-time.Second)), body},
+		"from the future": {secret, Sign(secret, body, now.Add(SignatureTolerance+time.Second)), body},
+		"malformed":       {secret, "v1=abc", body},
+		"no secret":       {nil, Sign(nil, body, now), body},
+	}
+	for name, test := range invalid {
+		if err := VerifySignature(test.secret, test.header, test.body, now); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: got %v, want ErrInvalidSignature", name, err)
+		}
+	}
+}
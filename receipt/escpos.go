@@ -0,0 +1,63 @@
+package receipt
+
+import "bytes"
+
+// ESC/POS commands understood by common thermal receipt printers.
+var (
+	escInit        = []byte{0x1b, '@'}
+	escAlignLeft   = []byte{0x1b, 'a', 0}
+	escAlignCenter = []byte{0x1b, 'a', 1}
+	escBoldOn      = []byte{0x1b, 'E', 1}
+	escBoldOff     = []byte{0x1b, 'E', 0}
+	escSizeNormal  = []byte{0x1d, '!', 0x00}
+	escSizeLarge   = []byte{0x1d, '!', 0x11}
+	// feed three lines and make a partial cut
+	escFeedCut = []byte{0x1d, 'V', 66, 3}
+)
+
+// ESCPOS renders the receipt as a byte stream for an ESC/POS printer, width
+// characters wide. Printers start in code page 437, so characters outside
+// ASCII are printed as '?'.
+func ESCPOS(r Receipt, width int) []byte {
+	var b bytes.Buffer
+	b.Write(escInit)
+
+	for _, row := range layout(r, width) {
+		if row.center {
+			b.Write(escAlignCenter)
+		}
+		if row.bold {
+			b.Write(escBoldOn)
+		}
+		if row.large {
+			b.Write(escSizeLarge)
+		}
+
+		b.WriteString(ascii(row.text))
+		b.WriteByte('\n')
+
+		if row.large {
+			b.Write(escSizeNormal)
+		}
+		if row.bold {
+			b.Write(escBoldOff)
+		}
+		if row.center {
+			b.Write(escAlignLeft)
+		}
+	}
+
+	b.Write(escFeedCut)
+	return b.Bytes()
+}
+
+func ascii(text string) string {
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		if r < 0x20 || r > 0x7e {
+			r = '?'
+		}
+		out = append(out, byte(r))
+	}
+	return string(out)
+}
@@ -0,0 +1,126 @@
+package receipt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// The PDF receipt is an A4 page set in Courier, one of the fonts every PDF
+// reader provides, so nothing has to be embedded.
+const (
+	pdfPageWidth  = 595.0
+	pdfPageHeight = 842.0
+	pdfMargin     = 56.0
+	pdfFontSize   = 10.0
+	pdfLargeSize  = 16.0
+	pdfLeading    = 13.0
+	// PDFWidth is the number of Courier characters a PDF line holds.
+	PDFWidth = 80
+)
+
+// PDF renders the receipt as a PDF document, breaking it over as many pages
+// as it needs.
+func PDF(r Receipt) []byte {
+	charWidth := pdfFontSize * 0.6
+	left := (pdfPageWidth - PDFWidth*charWidth) / 2
+	usable := pdfPageHeight - 2*pdfMargin
+	perPage := int(usable / pdfLeading)
+
+	pages := []string{}
+	var content strings.Builder
+	y := pdfPageHeight - pdfMargin
+	lines := 0
+
+	for _, row := range layout(r, PDFWidth) {
+		if lines == perPage {
+			pages = append(pages, content.String())
+			content.Reset()
+			y = pdfPageHeight - pdfMargin
+			lines = 0
+		}
+
+		font, size := "F1", pdfFontSize
+		if row.bold {
+			font = "F2"
+		}
+		if row.large {
+			size = pdfLargeSize
+		}
+
+		text := strings.TrimRight(row.text, " ")
+		x := left
+		if row.center {
+			x = (pdfPageWidth - float64(len([]rune(text)))*size*0.6) / 2
+		}
+
+		fmt.Fprintf(&content, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfString(text))
+		y -= pdfLeading
+		if row.large {
+			y -= pdfLargeSize - pdfFontSize
+		}
+		lines++
+	}
+	pages = append(pages, content.String())
+
+	return pdfDocument(pages)
+}
+
+// pdfDocument writes the catalog, the fonts and one page object with its
+// content stream per page, followed by the cross-reference table.
+func pdfDocument(pages []string) []byte {
+	var b bytes.Buffer
+	offsets := []int{}
+	object := func(body string) {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// objects 1 to 4 are fixed; each page then takes a page object and a
+	// content stream, starting at object 5
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, content := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pdfPageWidth, pdfPageHeight, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return b.Bytes()
+}
+
+// pdfString escapes text for a PDF string literal in WinAnsi encoding.
+// Characters outside Latin-1 are printed as '?'.
+func pdfString(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0xff || (r >= 0x7f && r < 0xa0):
+			b.WriteByte('?')
+		case r < 0x80:
+			b.WriteRune(r)
+		default:
+			fmt.Fprintf(&b, "\\%03o", r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,274 @@
+// Package receipt renders invoices as printable receipts: plain text for
+// thermal printers, ESC/POS byte streams to drive them directly, and PDF.
+package receipt
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+)
+
+// ThermalWidth is the number of characters a line holds on 80mm paper with
+// the printer's default font.
+const ThermalWidth = 48
+
+// Header identifies the restaurant at the top of every receipt.
+type Header struct {
+	Name    string
+	Address string
+	Phone   string
+	Tax_id  string
+	Footer  string
+}
+
+// Receipt is everything printed on a receipt: the invoice and the payments
+// made against it.
+type Receipt struct {
+	Header     Header
+	Invoice    models.InvoiceViewFormat
+	Payments   []models.Payment
+	Printed_at time.Time
+}
+
+// row is one printed line. Large rows are printed at double width and
+// height where the output supports it, so they hold half as many characters.
+type row struct {
+	text   string
+	center bool
+	bold   bool
+	large  bool
+}
+
+// layout lays the receipt out in lines of at most width characters. Every
+// output format prints the same rows.
+func layout(r Receipt, width int) []row {
+	invoice := r.Invoice
+	rows := []row{}
+	add := func(lines ...string) {
+		for _, line := range lines {
+			rows = append(rows, row{text: line})
+		}
+	}
+	centered := func(text string, bold bool) {
+		for _, line := range wrap(text, width) {
+			rows = append(rows, row{text: line, center: true, bold: bold})
+		}
+	}
+	rule := func() { add(strings.Repeat("-", width)) }
+
+	if r.Header.Name != "" {
+		for _, line := range wrap(r.Header.Name, width/2) {
+			rows = append(rows, row{text: line, center: true, bold: true, large: true})
+		}
+	}
+	for _, line := range strings.Split(r.Header.Address, "\n") {
+		if line != "" {
+			centered(line, false)
+		}
+	}
+	if r.Header.Phone != "" {
+		centered("Tel: "+r.Header.Phone, false)
+	}
+	if r.Header.Tax_id != "" {
+		centered("Tax ID: "+r.Header.Tax_id, false)
+	}
+	rule()
+
+	if invoice.Invoice_number != "" {
+		add(pair("Invoice", invoice.Invoice_number, width)...)
+	} else {
+		add(pair("Invoice", invoice.Invoice_id, width)...)
+	}
+	add(pair("Order", invoice.Order_id, width)...)
+	if invoice.Table_number != 0 {
+		add(pair("Table", fmt.Sprint(invoice.Table_number), width)...)
+	}
+	if !invoice.Created_at.IsZero() {
+		add(pair("Date", invoice.Created_at.Format("2006-01-02 15:04"), width)...)
+	}
+	if invoice.Split_count > 1 {
+		add(pair("Bill", fmt.Sprintf("%d of %d", invoice.Split_index, invoice.Split_count), width)...)
+	}
+	rule()
+
+	for _, line := range invoice.Order_details {
+		name := line.Food_name
+		if name == "" {
+			name = line.Food_id
+		}
+		if line.Quantity != "" {
+			name += " (" + line.Quantity + ")"
+		}
+		if line.Units() > 1 {
+			name = fmt.Sprintf("%d x %s", line.Units(), name)
+		}
+		add(pair(name, line.Unit_price.Mul(int64(line.Units())).String(), width)...)
+		for _, modifier := range line.Modifiers {
+			add(pair("  "+modifier.Name, "", width)...)
+		}
+
+		for _, discount := range invoice.Discounts {
+			if discount.Order_item_id == line.Order_item_id {
+				add(pair("  "+discount.Name, "-"+discount.Amount.String(), width)...)
+			}
+		}
+	}
+	for _, discount := range invoice.Discounts {
+		if discount.Order_item_id == "" {
+			add(pair(discount.Name, "-"+discount.Amount.String(), width)...)
+		}
+	}
+	rule()
+
+	// the subtotal is net of discounts and taxes; taxes and service charges
+	// add up to the total from it
+	add(pair("Subtotal", invoice.Subtotal.String(), width)...)
+	for _, charge := range invoice.Service_charges {
+		add(pair(fmt.Sprintf("%s %s%%", charge.Name, rate(charge.Rate)), charge.Amount.String(), width)...)
+	}
+	for _, tax := range invoice.Tax_breakdown {
+		label := fmt.Sprintf("%s %s%%", tax.Name, rate(tax.Rate))
+		if tax.Inclusive {
+			label += " incl."
+		}
+		add(pair(label, tax.Tax_amount.String(), width)...)
+	}
+	for _, line := range pair("TOTAL", amount(invoice.Total), width) {
+		rows = append(rows, row{text: line, bold: true})
+	}
+	rule()
+
+	if len(r.Payments) > 0 {
+		add("Payments")
+		for _, payment := range r.Payments {
+			if !payment.IsCaptured() {
+				continue
+			}
+			method := ""
+			if payment.Method != nil {
+				method = *payment.Method
+			}
+			add(pair("  "+method+" "+payment.Reference, payment.Amount.String(), width)...)
+			if payment.Tip.Minor > 0 {
+				add(pair("    Tip", payment.Tip.String(), width)...)
+			}
+			if payment.Refunded.Minor > 0 {
+				add(pair("    Refunded", "-"+payment.Refunded.String(), width)...)
+			}
+		}
+	}
+	add(pair("Paid", invoice.Amount_paid.String(), width)...)
+	if invoice.Tip_total.Minor > 0 {
+		add(pair("Tips", invoice.Tip_total.String(), width)...)
+	}
+	if invoice.Amount_refunded.Minor > 0 {
+		add(pair("Refunded", invoice.Amount_refunded.String(), width)...)
+	}
+	if invoice.Amount_credited.Minor > 0 {
+		add(pair("Credited", invoice.Amount_credited.String(), width)...)
+	}
+	for _, line := range pair("Balance due", amount(invoice.Balance), width) {
+		rows = append(rows, row{text: line, bold: true})
+	}
+	if invoice.Payment_status != nil {
+		add(pair("Status", *invoice.Payment_status, width)...)
+	}
+	rule()
+
+	footer := r.Header.Footer
+	if footer == "" {
+		footer = "Thank you!"
+	}
+	centered(footer, false)
+	if !r.Printed_at.IsZero() {
+		centered("Printed "+r.Printed_at.Format("2006-01-02 15:04"), false)
+	}
+
+	return rows
+}
+
+// pair prints label on the left and value on the right of a line, wrapping
+// a long label so the value always ends the last line. Leading spaces indent
+// every line of the label.
+func pair(label, value string, width int) []string {
+	trimmed := strings.TrimLeft(label, " ")
+	indent := label[:len(label)-len(trimmed)]
+
+	room := width - utf8.RuneCountInString(value) - 1 - len(indent)
+	if room < 1 {
+		return append(wrap(label, width), pad(value, width))
+	}
+
+	lines := wrap(trimmed, room)
+	for i := range lines {
+		lines[i] = indent + lines[i]
+	}
+	last := lines[len(lines)-1]
+	lines[len(lines)-1] = last + strings.Repeat(" ", width-utf8.RuneCountInString(last)-utf8.RuneCountInString(value)) + value
+	return lines
+}
+
+// wrap breaks text into lines of at most width characters, at spaces where
+// it can.
+func wrap(text string, width int) []string {
+	if width < 1 {
+		width = 1
+	}
+
+	lines := []string{}
+	line := []rune{}
+	for _, word := range strings.Fields(text) {
+		runes := []rune(word)
+		for len(runes) > width {
+			if len(line) > 0 {
+				lines = append(lines, string(line))
+				line = nil
+			}
+			lines = append(lines, string(runes[:width]))
+			runes = runes[width:]
+		}
+
+		switch {
+		case len(line) == 0:
+			line = runes
+		case len(line)+1+len(runes) <= width:
+			line = append(append(line, ' '), runes...)
+		default:
+			lines = append(lines, string(line))
+			line = runes
+		}
+	}
+	if len(line) > 0 || len(lines) == 0 {
+		lines = append(lines, string(line))
+	}
+
+	return lines
+}
+
+// pad right-aligns text in width characters.
+func pad(text string, width int) string {
+	if n := utf8.RuneCountInString(text); n < width {
+		return strings.Repeat(" ", width-n) + text
+	}
+	return text
+}
+
+func center(text string, width int) string {
+	n := utf8.RuneCountInString(text)
+	if n >= width {
+		return text
+	}
+	return strings.Repeat(" ", (width-n)/2) + text
+}
+
+func amount(m money.Money) string {
+	return m.Currency + " " + m.String()
+}
+
+func rate(r float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.3f", r), "0"), ".")
+}
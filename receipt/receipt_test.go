@@ -0,0 +1,176 @@
+package receipt
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func paidReceipt() Receipt {
+	return Receipt{
+		Header: Header{Name: "Café Lovelace", Address: "1 Analytical Row\nLondon", Phone: "+44 20 0000 0000", Tax_id: "GB123"},
+		Invoice: models.InvoiceViewFormat{
+			Invoice_id:     "inv",
+			Invoice_number: "INV-2026-000042",
+			Order_id:       "order",
+			Payment_status: ptr(models.InvoicePaid),
+			Table_number:   7,
+			Created_at:     time.Date(2026, 10, 17, 19, 30, 0, 0, time.UTC),
+			Order_details: []models.InvoiceLine{
+				{Order_item_id: "i1", Food_name: "Slow roasted pork belly with apple and black pudding", Count: 2, Unit_price: money.New(1450)},
+				{Order_item_id: "i2", Food_name: "Crème brûlée", Unit_price: money.New(650), Modifiers: []models.OrderModifier{{Name: "Extra berries"}}},
+			},
+			Discounts:       []models.InvoiceDiscount{{Discount_id: "d1", Order_item_id: "i2", Name: "Comp", Amount: money.New(650)}},
+			Subtotal:        money.New(2636),
+			Service_charges: []models.InvoiceCharge{{Service_charge_id: "s", Name: "Service", Rate: 12.5, Amount: money.New(363)}},
+			Tax_breakdown:   []models.TaxSummary{{Tax_rate_id: "vat", Name: "VAT", Rate: 10, Taxable_amount: money.New(2636), Tax_amount: money.New(264)}},
+			Total:           money.New(3263),
+			Amount_paid:     money.New(3263),
+			Tip_total:       money.New(500),
+			Balance:         money.Zero(),
+		},
+		Payments: []models.Payment{
+			{Method: ptr(models.PaymentCard), Reference: "mock_1", Status: models.PaymentCaptured, Amount: money.New(3263), Tip: money.New(500)},
+			{Method: ptr(models.PaymentCard), Reference: "mock_0", Status: models.PaymentFailed, Amount: money.New(3263)},
+		},
+		Printed_at: time.Date(2026, 10, 17, 21, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestText(t *testing.T) {
+	text := string(Text(paidReceipt(), ThermalWidth))
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+
+	for _, line := range lines {
+		if n := utf8.RuneCountInString(line); n > ThermalWidth {
+			t.Errorf("line %q is %d characters, want at most %d", line, n, ThermalWidth)
+		}
+	}
+
+	for _, want := range []string{
+		"Café Lovelace",
+		"Tax ID: GB123",
+		"INV-2026-000042",
+		"Table",
+		"2 x Slow roasted pork belly",
+		"  Extra berries",
+		"  Comp",
+		"Service 12.5%",
+		"VAT 10%",
+		"USD 32.63",
+		"CARD mock_1",
+		"Printed 2026-10-17 21:00",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("receipt does not show %q:\n%s", want, text)
+		}
+	}
+
+	// failed payments are left off
+	if strings.Contains(text, "mock_0") {
+		t.Errorf("receipt shows a failed payment:\n%s", text)
+	}
+
+	// amounts end every line they are on
+	for _, line := range lines {
+		if strings.HasPrefix(line, "TOTAL") && !strings.HasSuffix(line, "USD 32.63") {
+			t.Errorf("total line is %q, want the total right-aligned", line)
+		}
+	}
+}
+
+func TestPair(t *testing.T) {
+	tests := []struct {
+		label, value string
+		width        int
+		want         []string
+	}{
+		{"Subtotal", "26.36", 20, []string{"Subtotal       26.36"}},
+		{"Slow roasted pork belly", "29.00", 20, []string{"Slow roasted", "pork belly     29.00"}},
+		{"  Extra berries", "", 20, []string{"  Extra berries     "}},
+		{"Total", "a value wider than the line", 10, []string{"Total", "a value wider than the line"}},
+	}
+
+	for _, test := range tests {
+		if got := pair(test.label, test.value, test.width); strings.Join(got, "|") != strings.Join(test.want, "|") {
+			t.Errorf("pair(%q, %q, %d) = %q, want %q", test.label, test.value, test.width, got, test.want)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  []string
+	}{
+		{"", 10, []string{""}},
+		{"one two three", 7, []string{"one two", "three"}},
+		{"antidisestablishment", 8, []string{"antidise", "stablish", "ment"}},
+		{"a  b", 1, []string{"a", "b"}},
+	}
+
+	for _, test := range tests {
+		if got := wrap(test.text, test.width); strings.Join(got, "|") != strings.Join(test.want, "|") {
+			t.Errorf("wrap(%q, %d) = %q, want %q", test.text, test.width, got, test.want)
+		}
+	}
+}
+
+func TestESCPOS(t *testing.T) {
+	out := ESCPOS(paidReceipt(), ThermalWidth)
+
+	if !bytes.HasPrefix(out, escInit) || !bytes.HasSuffix(out, escFeedCut) {
+		t.Errorf("stream does not start with init and end with a cut")
+	}
+	if !bytes.Contains(out, append(append([]byte{}, escSizeLarge...), "Caf? Lovelace"...)) {
+		t.Errorf("the restaurant name is not printed large, with non-ASCII replaced")
+	}
+	for _, b := range out {
+		if b > 0x7e {
+			t.Fatalf("stream holds byte %#x, which code page 437 prints differently", b)
+		}
+	}
+}
+
+func TestPDF(t *testing.T) {
+	r := paidReceipt()
+	for i := 0; i < 80; i++ {
+		r.Invoice.Order_details = append(r.Invoice.Order_details, models.InvoiceLine{Order_item_id: fmt.Sprint(i), Food_name: "Bread (with butter)", Unit_price: money.New(300)})
+	}
+	out := PDF(r)
+
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatalf("document is not framed as a PDF")
+	}
+	if !bytes.Contains(out, []byte("/Count 2")) {
+		t.Errorf("a long receipt was not broken over two pages")
+	}
+	if !bytes.Contains(out, []byte(`(Caf\351 Lovelace)`)) || !bytes.Contains(out, []byte(`Bread \(with butter\)`)) {
+		t.Errorf("text is not escaped for WinAnsi string literals")
+	}
+
+	// every object starts where the cross-reference table says it does
+	xref := regexp.MustCompile(`(?m)^(\d{10}) 00000 n $`).FindAllSubmatch(out, -1)
+	if len(xref) != 8 {
+		t.Fatalf("cross-reference table lists %d objects, want 8", len(xref))
+	}
+	for i, entry := range xref {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(out[offset:], []byte(want)) {
+			t.Errorf("object %d is not at offset %d", i+1, offset)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package receipt
+
+import "strings"
+
+// Text renders the receipt as plain UTF-8 text, width characters wide.
+func Text(r Receipt, width int) []byte {
+	var b strings.Builder
+
+	for _, row := range layout(r, width) {
+		text := row.text
+		if row.center {
+			text = center(text, width)
+		}
+		b.WriteString(strings.TrimRight(text, " "))
+		b.WriteByte('\n')
+	}
+
+	return []byte(b.String())
+}
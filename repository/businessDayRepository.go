@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BusinessDayStore keeps the closed business days. A day without a document
+// is still open.
+type BusinessDayStore interface {
+	List(ctx context.Context) ([]models.BusinessDay, error)
+	Get(ctx context.Context, businessDate string) (models.BusinessDay, error)
+	// Close stores the closed day. It returns ErrDuplicate when the day was
+	// already closed.
+	Close(ctx context.Context, day models.BusinessDay) error
+}
+
+type mongoBusinessDayStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoBusinessDayStore) List(ctx context.Context) ([]models.BusinessDay, error) {
+	res, err := s.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"business_date": -1}))
+	if err != nil {
+		return nil, err
+	}
+
+	days := []models.BusinessDay{}
+	err = res.All(ctx, &days)
+	return days, err
+}
+
+func (s *mongoBusinessDayStore) Get(ctx context.Context, businessDate string) (models.BusinessDay, error) {
+	var day models.BusinessDay
+	err := s.collection.FindOne(ctx, bson.M{"business_date": businessDate}).Decode(&day)
+	return day, notFound(err)
+}
+
+func (s *mongoBusinessDayStore) Close(ctx context.Context, day models.BusinessDay) error {
+	// the upsert only inserts when no process closed the day first; the
+	// unique index on business_date turns a racing insert into a duplicate
+	res, err := s.collection.UpdateOne(ctx,
+		bson.M{"business_date": day.Business_date},
+		bson.M{"$setOnInsert": day},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	if err != nil {
+		return err
+	}
+	if res.UpsertedCount == 0 {
+		return ErrDuplicate
+	}
+	return nil
+}
+
+type memoryBusinessDayStore struct {
+	days *memCollection[models.BusinessDay]
+}
+
+func (s *memoryBusinessDayStore) List(ctx context.Context) ([]models.BusinessDay, error) {
+	days, err := s.days.find(nil)
+	sort.Slice(days, func(i, j int) bool { return days[i].Business_date > days[j].Business_date })
+	return days, err
+}
+
+func (s *memoryBusinessDayStore) Get(ctx context.Context, businessDate string) (models.BusinessDay, error) {
+	return s.days.get(businessDate)
+}
+
+func (s *memoryBusinessDayStore) Close(ctx context.Context, day models.BusinessDay) error {
+	return s.days.insert(day.Business_date, day)
+}
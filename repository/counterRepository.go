@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CounterStore hands out sequence numbers, e.g. for invoice numbers.
+type CounterStore interface {
+	// Next atomically advances the named counter by n and returns its new
+	// value; the n values ending there belong to the caller alone. Counters
+	// start at zero, so the first value handed out is 1.
+	Next(ctx context.Context, name string, n int64) (int64, error)
+}
+
+type mongoCounterStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoCounterStore) Next(ctx context.Context, name string, n int64) (int64, error) {
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := s.collection.FindOneAndUpdate(ctx, bson.M{"_id": name}, bson.M{"$inc": bson.M{"seq": n}}, opts).Decode(&counter)
+	return counter.Seq, err
+}
+
+type memoryCounterStore struct {
+	mu       sync.Mutex
+	counters map[string]int64
+}
+
+func (s *memoryCounterStore) Next(ctx context.Context, name string, n int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counters[name] += n
+	return s.counters[name], nil
+}
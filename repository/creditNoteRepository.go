@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type CreditNoteStore interface {
+	ListByInvoice(ctx context.Context, invoiceId string) ([]models.CreditNote, error)
+	// ListBetween returns the credit notes created in [from, to).
+	ListBetween(ctx context.Context, from, to time.Time) ([]models.CreditNote, error)
+	ListByDrawer(ctx context.Context, drawerId string) ([]models.CreditNote, error)
+	Get(ctx context.Context, creditNoteId string) (models.CreditNote, error)
+	Create(ctx context.Context, creditNote models.CreditNote) error
+}
+
+type mongoCreditNoteStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoCreditNoteStore) ListByInvoice(ctx context.Context, invoiceId string) ([]models.CreditNote, error) {
+	return s.find(ctx, bson.M{"invoice_id": invoiceId})
+}
+
+func (s *mongoCreditNoteStore) ListBetween(ctx context.Context, from, to time.Time) ([]models.CreditNote, error) {
+	return s.find(ctx, bson.M{"created_at": bson.M{"$gte": from, "$lt": to}})
+}
+
+func (s *mongoCreditNoteStore) ListByDrawer(ctx context.Context, drawerId string) ([]models.CreditNote, error) {
+	return s.find(ctx, bson.M{"drawer_id": drawerId})
+}
+
+func (s *mongoCreditNoteStore) find(ctx context.Context, filter bson.M) ([]models.CreditNote, error) {
+	res, err := s.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	creditNotes := []models.CreditNote{}
+	err = res.All(ctx, &creditNotes)
+	return creditNotes, err
+}
+
+func (s *mongoCreditNoteStore) Get(ctx context.Context, creditNoteId string) (models.CreditNote, error) {
+	var creditNote models.CreditNote
+	err := s.collection.FindOne(ctx, bson.M{"credit_note_id": creditNoteId}).Decode(&creditNote)
+	return creditNote, notFound(err)
+}
+
+func (s *mongoCreditNoteStore) Create(ctx context.Context, creditNote models.CreditNote) error {
+	_, err := s.collection.InsertOne(ctx, creditNote)
+	return err
+}
+
+type memoryCreditNoteStore struct {
+	creditNotes *memCollection[models.CreditNote]
+}
+
+func (s *memoryCreditNoteStore) ListByInvoice(ctx context.Context, invoiceId string) ([]models.CreditNote, error) {
+	return s.creditNotes.find(func(creditNote models.CreditNote) bool {
+		return creditNote.Invoice_id == invoiceId
+	})
+}
+
+func (s *memoryCreditNoteStore) ListBetween(ctx context.Context, from, to time.Time) ([]models.CreditNote, error) {
+	return s.creditNotes.find(func(creditNote models.CreditNote) bool {
+		return !creditNote.Created_at.Before(from) && creditNote.Created_at.Before(to)
+	})
+}
+
+func (s *memoryCreditNoteStore) ListByDrawer(ctx context.Context, drawerId string) ([]models.CreditNote, error) {
+	return s.creditNotes.find(func(creditNote models.CreditNote) bool {
+		return creditNote.Drawer_id == drawerId
+	})
+}
+
+func (s *memoryCreditNoteStore) Get(ctx context.Context, creditNoteId string) (models.CreditNote, error) {
+	return s.creditNotes.get(creditNoteId)
+}
+
+func (s *memoryCreditNoteStore) Create(ctx context.Context, creditNote models.CreditNote) error {
+	return s.creditNotes.insert(creditNote.Credit_note_id, creditNote)
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type DiscountStore interface {
+	// ListByOrder returns every discount of the order, removed ones included.
+	ListByOrder(ctx context.Context, orderId string) ([]models.Discount, error)
+	// ListBetween returns the discounts applied in [from, to).
+	ListBetween(ctx context.Context, from, to time.Time) ([]models.Discount, error)
+	Get(ctx context.Context, discountId string) (models.Discount, error)
+	Create(ctx context.Context, discount models.Discount) error
+	Update(ctx context.Context, discount models.Discount) error
+}
+
+type mongoDiscountStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoDiscountStore) ListByOrder(ctx context.Context, orderId string) ([]models.Discount, error) {
+	return s.find(ctx, bson.M{"order_id": orderId})
+}
+
+func (s *mongoDiscountStore) ListBetween(ctx context.Context, from, to time.Time) ([]models.Discount, error) {
+	return s.find(ctx, bson.M{"created_at": bson.M{"$gte": from, "$lt": to}})
+}
+
+func (s *mongoDiscountStore) find(ctx context.Context, filter bson.M) ([]models.Discount, error) {
+	res, err := s.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	discounts := []models.Discount{}
+	err = res.All(ctx, &discounts)
+	return discounts, err
+}
+
+func (s *mongoDiscountStore) Get(ctx context.Context, discountId string) (models.Discount, error) {
+	var discount models.Discount
+	err := s.collection.FindOne(ctx, bson.M{"discount_id": discountId}).Decode(&discount)
+	return discount, notFound(err)
+}
+
+func (s *mongoDiscountStore) Create(ctx context.Context, discount models.Discount) error {
+	_, err := s.collection.InsertOne(ctx, discount)
+	return err
+}
+
+func (s *mongoDiscountStore) Update(ctx context.Context, discount models.Discount) error {
+	res, err := s.collection.ReplaceOne(ctx, bson.M{"discount_id": discount.Discount_id}, discount)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryDiscountStore struct {
+	discounts *memCollection[models.Discount]
+}
+
+func (s *memoryDiscountStore) ListByOrder(ctx context.Context, orderId string) ([]models.Discount, error) {
+	return s.discounts.find(func(discount models.Discount) bool {
+		return discount.Order_id == orderId
+	})
+}
+
+func (s *memoryDiscountStore) ListBetween(ctx context.Context, from, to time.Time) ([]models.Discount, error) {
+	return s.discounts.find(func(discount models.Discount) bool {
+		return !discount.Created_at.Before(from) && discount.Created_at.Before(to)
+	})
+}
+
+func (s *memoryDiscountStore) Get(ctx context.Context, discountId string) (models.Discount, error) {
+	return s.discounts.get(discountId)
+}
+
+func (s *memoryDiscountStore) Create(ctx context.Context, discount models.Discount) error {
+	return s.discounts.insert(discount.Discount_id, discount)
+}
+
+func (s *memoryDiscountStore) Update(ctx context.Context, discount models.Discount) error {
+	return s.discounts.replace(discount.Discount_id, discount)
+}
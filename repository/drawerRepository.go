@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DrawerFilter narrows List down; zero fields are ignored. A drawer matches
+// the time range when it was open during it.
+type DrawerFilter struct {
+	Register string
+	From     time.Time
+	To       time.Time
+}
+
+type DrawerStore interface {
+	List(ctx context.Context, filter DrawerFilter) ([]models.Drawer, error)
+	Get(ctx context.Context, drawerId string) (models.Drawer, error)
+	GetOpenByRegister(ctx context.Context, register string) (models.Drawer, error)
+	GetOpenByUser(ctx context.Context, userId string) (models.Drawer, error)
+	Create(ctx context.Context, drawer models.Drawer) error
+	// AddMovement records cash put into or taken out of an open drawer. It
+	// returns ErrConflict when the drawer was closed.
+	AddMovement(ctx context.Context, drawerId string, movement models.CashMovement) (models.Drawer, error)
+	// Close stores the closed drawer. It returns ErrConflict when the drawer
+	// was closed in the meantime.
+	Close(ctx context.Context, drawer models.Drawer) error
+}
+
+type mongoDrawerStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoDrawerStore) List(ctx context.Context, filter DrawerFilter) ([]models.Drawer, error) {
+	query := bson.M{}
+	if filter.Register != "" {
+		query["register"] = filter.Register
+	}
+	if !filter.To.IsZero() {
+		query["opened_at"] = bson.M{"$lt": filter.To}
+	}
+	if !filter.From.IsZero() {
+		query["$or"] = []bson.M{{"closed_at": nil}, {"closed_at": bson.M{"$gt": filter.From}}}
+	}
+
+	res, err := s.collection.Find(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	drawers := []models.Drawer{}
+	err = res.All(ctx, &drawers)
+	return drawers, err
+}
+
+func (s *mongoDrawerStore) Get(ctx context.Context, drawerId string) (models.Drawer, error) {
+	var drawer models.Drawer
+	err := s.collection.FindOne(ctx, bson.M{"drawer_id": drawerId}).Decode(&drawer)
+	return drawer, notFound(err)
+}
+
+func (s *mongoDrawerStore) GetOpenByRegister(ctx context.Context, register string) (models.Drawer, error) {
+	var drawer models.Drawer
+	err := s.collection.FindOne(ctx, bson.M{"register": register, "closed_at": nil}).Decode(&drawer)
+	return drawer, notFound(err)
+}
+
+func (s *mongoDrawerStore) GetOpenByUser(ctx context.Context, userId string) (models.Drawer, error) {
+	var drawer models.Drawer
+	err := s.collection.FindOne(ctx, bson.M{"opened_by": userId, "closed_at": nil}).Decode(&drawer)
+	return drawer, notFound(err)
+}
+
+func (s *mongoDrawerStore) Create(ctx context.Context, drawer models.Drawer) error {
+	_, err := s.collection.InsertOne(ctx, drawer)
+	return err
+}
+
+func (s *mongoDrawerStore) AddMovement(ctx context.Context, drawerId string, movement models.CashMovement) (models.Drawer, error) {
+	res, err := s.collection.UpdateOne(ctx,
+		bson.M{"drawer_id": drawerId, "closed_at": nil},
+		bson.M{"$push": bson.M{"movements": movement}},
+	)
+	if err != nil {
+		return models.Drawer{}, err
+	}
+
+	drawer, err := s.Get(ctx, drawerId)
+	if err == nil && res.MatchedCount == 0 {
+		return drawer, ErrConflict
+	}
+	return drawer, err
+}
+
+func (s *mongoDrawerStore) Close(ctx context.Context, drawer models.Drawer) error {
+	res, err := s.collection.ReplaceOne(ctx, bson.M{"drawer_id": drawer.Drawer_id, "closed_at": nil}, drawer)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		if _, err := s.Get(ctx, drawer.Drawer_id); err != nil {
+			return err
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+type memoryDrawerStore struct {
+	drawers *memCollection[models.Drawer]
+}
+
+func (s *memoryDrawerStore) List(ctx context.Context, filter DrawerFilter) ([]models.Drawer, error) {
+	return s.drawers.find(func(drawer models.Drawer) bool {
+		if filter.Register != "" && drawer.Register != filter.Register {
+			return false
+		}
+		if !filter.To.IsZero() && !drawer.Opened_at.Before(filter.To) {
+			return false
+		}
+		if !filter.From.IsZero() && drawer.Closed_at != nil && !drawer.Closed_at.After(filter.From) {
+			return false
+		}
+		return true
+	})
+}
+
+func (s *memoryDrawerStore) Get(ctx context.Context, drawerId string) (models.Drawer, error) {
+	return s.drawers.get(drawerId)
+}
+
+func (s *memoryDrawerStore) GetOpenByRegister(ctx context.Context, register string) (models.Drawer, error) {
+	return s.first(func(drawer models.Drawer) bool {
+		return drawer.Register == register && drawer.IsOpen()
+	})
+}
+
+func (s *memoryDrawerStore) GetOpenByUser(ctx context.Context, userId string) (models.Drawer, error) {
+	return s.first(func(drawer models.Drawer) bool {
+		return drawer.Opened_by == userId && drawer.IsOpen()
+	})
+}
+
+func (s *memoryDrawerStore) first(match func(drawer models.Drawer) bool) (models.Drawer, error) {
+	drawers, err := s.drawers.find(match)
+	if err != nil {
+		return models.Drawer{}, err
+	}
+	if len(drawers) == 0 {
+		return models.Drawer{}, ErrNotFound
+	}
+	return drawers[0], nil
+}
+
+func (s *memoryDrawerStore) Create(ctx context.Context, drawer models.Drawer) error {
+	return s.drawers.insert(drawer.Drawer_id, drawer)
+}
+
+func (s *memoryDrawerStore) AddMovement(ctx context.Context, drawerId string, movement models.CashMovement) (models.Drawer, error) {
+	return s.drawers.update(drawerId, func(drawer *models.Drawer) error {
+		if !drawer.IsOpen() {
+			return ErrConflict
+		}
+		drawer.Movements = append(drawer.Movements, movement)
+		return nil
+	})
+}
+
+func (s *memoryDrawerStore) Close(ctx context.Context, drawer models.Drawer) error {
+	_, err := s.drawers.update(drawer.Drawer_id, func(stored *models.Drawer) error {
+		if !stored.IsOpen() {
+			return ErrConflict
+		}
+		*stored = drawer
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"slices"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FoodFilter leaves out food containing any of Exclude_allergens, and food
+// not tagged with every one of Dietary_tags.
+type FoodFilter struct {
+	Exclude_allergens []string
+	Dietary_tags      []string
+}
+
+func (filter FoodFilter) Matches(food models.Food) bool {
+	for _, allergen := range filter.Exclude_allergens {
+		if slices.Contains(food.Allergens, allergen) {
+			return false
+		}
+	}
+	for _, tag := range filter.Dietary_tags {
+		if !slices.Contains(food.Dietary_tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func (filter FoodFilter) query() bson.M {
+	query := bson.M{}
+	if len(filter.Exclude_allergens) > 0 {
+		query["allergens"] = bson.M{"$nin": filter.Exclude_allergens}
+	}
+	if len(filter.Dietary_tags) > 0 {
+		query["dietary_tags"] = bson.M{"$all": filter.Dietary_tags}
+	}
+	return query
+}
+
+type FoodStore interface {
+	List(ctx context.Context, filter FoodFilter, skip, limit int) ([]models.Food, int, error)
+	Get(ctx context.Context, foodId string) (models.Food, error)
+	ListByMenus(ctx context.Context, menuIds []string, filter FoodFilter) ([]models.Food, error)
+	Create(ctx context.Context, food models.Food) error
+	Update(ctx context.Context, food models.Food) error
+}
+
+type mongoFoodStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoFoodStore) List(ctx context.Context, filter FoodFilter, skip, limit int) ([]models.Food, int, error) {
+	query := filter.query()
+	total, err := s.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSkip(int64(skip)).SetLimit(int64(limit))
+	res, err := s.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	foods := []models.Food{}
+	if err = res.All(ctx, &foods); err != nil {
+		return nil, 0, err
+	}
+
+	return foods, int(total), nil
+}
+
+func (s *mongoFoodStore) Get(ctx context.Context, foodId string) (models.Food, error) {
+	var food models.Food
+	err := s.collection.FindOne(ctx, bson.M{"food_id": foodId}).Decode(&food)
+	return food, notFound(err)
+}
+
+func (s *mongoFoodStore) ListByMenus(ctx context.Context, menuIds []string, filter FoodFilter) ([]models.Food, error) {
+	query := filter.query()
+	query["menu_id"] = bson.M{"$in": menuIds}
+
+	res, err := s.collection.Find(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	foods := []models.Food{}
+	err = res.All(ctx, &foods)
+	return foods, err
+}
+
+func (s *mongoFoodStore) Create(ctx context.Context, food models.Food) error {
+	_, err := s.collection.InsertOne(ctx, food)
+	return err
+}
+
+func (s *mongoFoodStore) Update(ctx context.Context, food models.Food) error {
+	res, err := s.collection.ReplaceOne(ctx, bson.M{"food_id": food.Food_id}, food)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryFoodStore struct {
+	foods *memCollection[models.Food]
+}
+
+func (s *memoryFoodStore) List(ctx context.Context, filter FoodFilter, skip, limit int) ([]models.Food, int, error) {
+	foods, err := s.foods.find(filter.Matches)
+	if err != nil {
+		return nil, 0, err
+	}
+	return paginate(foods, skip, limit), len(foods), nil
+}
+
+func (s *memoryFoodStore) Get(ctx context.Context, foodId string) (models.Food, error) {
+	return s.foods.get(foodId)
+}
+
+func (s *memoryFoodStore) ListByMenus(ctx context.Context, menuIds []string, filter FoodFilter) ([]models.Food, error) {
+	return s.foods.find(func(food models.Food) bool {
+		return food.Menu_id != nil && slices.Contains(menuIds, *food.Menu_id) && filter.Matches(food)
+	})
+}
+
+func (s *memoryFoodStore) Create(ctx context.Context, food models.Food) error {
+	return s.foods.insert(food.Food_id, food)
+}
+
+func (s *memoryFoodStore) Update(ctx context.Context, food models.Food) error {
+	return s.foods.replace(food.Food_id, food)
+}
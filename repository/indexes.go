@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// collectionIndexes lists the indexes every collection needs, for lookups
+// that would otherwise scan it and for the uniqueness the stores rely on.
+var collectionIndexes = map[string][]mongo.IndexModel{
+	// BusinessDayStore.Close upserts on the date; two processes upserting at
+	// once would otherwise both insert
+	"businessDay": {
+		{Keys: bson.D{{Key: "business_date", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	"revocation": {
+		{Keys: bson.D{{Key: "kind", Value: 1}, {Key: "key", Value: 1}}},
+		// expired revocations are removed by MongoDB itself
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	},
+}
+
+// EnsureIndexes creates the indexes the stores need. Creating an index that
+// already exists does nothing, so it is safe to run on every start.
+func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
+	for collection, indexes := range collectionIndexes {
+		if _, err := db.Collection(collection).Indexes().CreateMany(ctx, indexes); err != nil {
+			return fmt.Errorf("%s: %w", collection, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// overdueStatuses are the statuses of invoices that still wait for a
+// payment and so can fall overdue.
+var overdueStatuses = []string{models.InvoicePending, models.InvoicePartiallyPaid, models.InvoiceFailed}
+
+type InvoiceStore interface {
+	// List returns every invoice, or only those in one of the given statuses.
+	List(ctx context.Context, statuses ...string) ([]models.Invoice, error)
+	// ListOverdue returns the unpaid invoices due before now that are not
+	// marked overdue yet.
+	ListOverdue(ctx context.Context, now time.Time) ([]models.Invoice, error)
+	ListByOrder(ctx context.Context, orderId string) ([]models.Invoice, error)
+	// ListBetween returns the invoices created in [from, to).
+	ListBetween(ctx context.Context, from, to time.Time) ([]models.Invoice, error)
+	Get(ctx context.Context, invoiceId string) (models.Invoice, error)
+	Create(ctx context.Context, invoice models.Invoice) error
+	CreateMany(ctx context.Context, invoices []models.Invoice) error
+	// UpdateSettlement sets the amounts, status and payment method settled
+	// from the invoice's payments and credit notes, if its status is still
+	// from. It returns ErrConflict when the invoice has moved on in the
+	// meantime. Invoices stored without a status are in status "".
+	UpdateSettlement(ctx context.Context, invoice models.Invoice, from string) (models.Invoice, error)
+	// UpdatePaymentMethod sets the invoice's payment method if its status is
+	// still from, and returns ErrConflict otherwise.
+	UpdatePaymentMethod(ctx context.Context, invoiceId, from string, method *string, updatedAt time.Time) (models.Invoice, error)
+	// Void records the void of the invoice if its status is still from, and
+	// returns ErrConflict otherwise.
+	Void(ctx context.Context, invoice models.Invoice, from string) (models.Invoice, error)
+	// MarkOverdue moves an unpaid invoice due before now to OVERDUE. It
+	// returns ErrConflict when the invoice was paid, voided or already
+	// marked in the meantime.
+	MarkOverdue(ctx context.Context, invoiceId string, now time.Time) (models.Invoice, error)
+}
+
+type mongoInvoiceStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoInvoiceStore) List(ctx context.Context, statuses ...string) ([]models.Invoice, error) {
+	if len(statuses) == 0 {
+		return s.find(ctx, bson.M{})
+	}
+	return s.find(ctx, bson.M{"payment_status": bson.M{"$in": statuses}})
+}
+
+func (s *mongoInvoiceStore) ListOverdue(ctx context.Context, now time.Time) ([]models.Invoice, error) {
+	return s.find(ctx, overdueFilter(now))
+}
+
+func overdueFilter(now time.Time) bson.M {
+	return bson.M{
+		"payment_status":   bson.M{"$in": overdueStatuses},
+		"payment_due_date": bson.M{"$lt": now},
+	}
+}
+
+func (s *mongoInvoiceStore) ListByOrder(ctx context.Context, orderId string) ([]models.Invoice, error) {
+	return s.find(ctx, bson.M{"order_id": orderId})
+}
+
+func (s *mongoInvoiceStore) ListBetween(ctx context.Context, from, to time.Time) ([]models.Invoice, error) {
+	return s.find(ctx, bson.M{"created_at": bson.M{"$gte": from, "$lt": to}})
+}
+
+func (s *mongoInvoiceStore) find(ctx context.Context, filter bson.M) ([]models.Invoice, error) {
+	res, err := s.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	invoices := []models.Invoice{}
+	err = res.All(ctx, &invoices)
+	return invoices, err
+}
+
+func (s *mongoInvoiceStore) Get(ctx context.Context, invoiceId string) (models.Invoice, error) {
+	var invoice models.Invoice
+	err := s.collection.FindOne(ctx, bson.M{"invoice_id": invoiceId}).Decode(&invoice)
+	return invoice, notFound(err)
+}
+
+func (s *mongoInvoiceStore) Create(ctx context.Context, invoice models.Invoice) error {
+	_, err := s.collection.InsertOne(ctx, invoice)
+	return err
+}
+
+func (s *mongoInvoiceStore) CreateMany(ctx context.Context, invoices []models.Invoice) error {
+	if len(invoices) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, 0, len(invoices))
+	for _, invoice := range invoices {
+		docs = append(docs, invoice)
+	}
+
+	_, err := s.collection.InsertMany(ctx, docs)
+	return err
+}
+
+func (s *mongoInvoiceStore) UpdateSettlement(ctx context.Context, invoice models.Invoice, from string) (models.Invoice, error) {
+	return s.updateFrom(ctx, invoice.Invoice_id, from, bson.M{
+		"payment_status":  invoice.Payment_status,
+		"payment_method":  invoice.Payment_method,
+		"amount_paid":     invoice.Amount_paid,
+		"amount_refunded": invoice.Amount_refunded,
+		"amount_credited": invoice.Amount_credited,
+		"tip_total":       invoice.Tip_total,
+		"balance":         invoice.Balance,
+		"updated_at":      invoice.Updated_at,
+	})
+}
+
+func (s *mongoInvoiceStore) UpdatePaymentMethod(ctx context.Context, invoiceId, from string, method *string, updatedAt time.Time) (models.Invoice, error) {
+	return s.updateFrom(ctx, invoiceId, from, bson.M{"payment_method": method, "updated_at": updatedAt})
+}
+
+func (s *mongoInvoiceStore) Void(ctx context.Context, invoice models.Invoice, from string) (models.Invoice, error) {
+	return s.updateFrom(ctx, invoice.Invoice_id, from, bson.M{
+		"payment_status": invoice.Payment_status,
+		"voided_at":      invoice.Voided_at,
+		"voided_by":      invoice.Voided_by,
+		"void_reason":    invoice.Void_reason,
+		"updated_at":     invoice.Updated_at,
+	})
+}
+
+// updateFrom sets fields on the invoice if its status is still from.
+func (s *mongoInvoiceStore) updateFrom(ctx context.Context, invoiceId, from string, fields bson.M) (models.Invoice, error) {
+	var invoice models.Invoice
+
+	filter := bson.M{"invoice_id": invoiceId, "payment_status": from}
+	if from == "" {
+		filter["payment_status"] = bson.M{"$in": []interface{}{nil, ""}}
+	}
+	err := s.collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.M{"$set": fields},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&invoice)
+	if err == mongo.ErrNoDocuments {
+		if _, err := s.Get(ctx, invoiceId); err != nil {
+			return invoice, err
+		}
+		return invoice, ErrConflict
+	}
+
+	return invoice, err
+}
+
+func (s *mongoInvoiceStore) MarkOverdue(ctx context.Context, invoiceId string, now time.Time) (models.Invoice, error) {
+	var invoice models.Invoice
+
+	filter := overdueFilter(now)
+	filter["invoice_id"] = invoiceId
+	err := s.collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.M{"$set": bson.M{"payment_status": models.InvoiceOverdue, "updated_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&invoice)
+	if err == mongo.ErrNoDocuments {
+		if _, err := s.Get(ctx, invoiceId); err != nil {
+			return invoice, err
+		}
+		return invoice, ErrConflict
+	}
+
+	return invoice, err
+}
+
+type memoryInvoiceStore struct {
+	invoices *memCollection[models.Invoice]
+}
+
+func (s *memoryInvoiceStore) List(ctx context.Context, statuses ...string) ([]models.Invoice, error) {
+	return s.invoices.find(func(invoice models.Invoice) bool {
+		return len(statuses) == 0 || invoice.Payment_status != nil && slices.Contains(statuses, *invoice.Payment_status)
+	})
+}
+
+func (s *memoryInvoiceStore) ListBetween(ctx context.Context, from, to time.Time) ([]models.Invoice, error) {
+	return s.invoices.find(func(invoice models.Invoice) bool {
+		return !invoice.Created_at.Before(from) && invoice.Created_at.Before(to)
+	})
+}
+
+func (s *memoryInvoiceStore) ListOverdue(ctx context.Context, now time.Time) ([]models.Invoice, error) {
+	return s.invoices.find(func(invoice models.Invoice) bool {
+		return isOverdue(invoice, now)
+	})
+}
+
+func isOverdue(invoice models.Invoice, now time.Time) bool {
+	return invoice.Payment_status != nil && slices.Contains(overdueStatuses, *invoice.Payment_status) && invoice.Payment_due_date.Before(now)
+}
+
+func (s *memoryInvoiceStore) ListByOrder(ctx context.Context, orderId string) ([]models.Invoice, error) {
+	return s.invoices.find(func(invoice models.Invoice) bool {
+		return invoice.Order_id == orderId
+	})
+}
+
+func (s *memoryInvoiceStore) Get(ctx context.Context, invoiceId string) (models.Invoice, error) {
+	return s.invoices.get(invoiceId)
+}
+
+func (s *memoryInvoiceStore) Create(ctx context.Context, invoice models.Invoice) error {
+	return s.invoices.insert(invoice.Invoice_id, invoice)
+}
+
+func (s *memoryInvoiceStore) UpdateSettlement(ctx context.Context, invoice models.Invoice, from string) (models.Invoice, error) {
+	return s.updateFrom(invoice.Invoice_id, from, func(stored *models.Invoice) {
+		stored.Payment_status = invoice.Payment_status
+		stored.Payment_method = invoice.Payment_method
+		stored.Amount_paid = invoice.Amount_paid
+		stored.Amount_refunded = invoice.Amount_refunded
+		stored.Amount_credited = invoice.Amount_credited
+		stored.Tip_total = invoice.Tip_total
+		stored.Balance = invoice.Balance
+		stored.Updated_at = invoice.Updated_at
+	})
+}
+
+func (s *memoryInvoiceStore) UpdatePaymentMethod(ctx context.Context, invoiceId, from string, method *string, updatedAt time.Time) (models.Invoice, error) {
+	return s.updateFrom(invoiceId, from, func(stored *models.Invoice) {
+		stored.Payment_method = method
+		stored.Updated_at = updatedAt
+	})
+}
+
+func (s *memoryInvoiceStore) Void(ctx context.Context, invoice models.Invoice, from string) (models.Invoice, error) {
+	return s.updateFrom(invoice.Invoice_id, from, func(stored *models.Invoice) {
+		stored.Payment_status = invoice.Payment_status
+		stored.Voided_at = invoice.Voided_at
+		stored.Voided_by = invoice.Voided_by
+		stored.Void_reason = invoice.Void_reason
+		stored.Updated_at = invoice.Updated_at
+	})
+}
+
+func (s *memoryInvoiceStore) updateFrom(invoiceId, from string, set func(stored *models.Invoice)) (models.Invoice, error) {
+	return s.invoices.update(invoiceId, func(stored *models.Invoice) error {
+		if stored.StoredStatus() != from {
+			return ErrConflict
+		}
+		set(stored)
+		return nil
+	})
+}
+
+func (s *memoryInvoiceStore) CreateMany(ctx context.Context, invoices []models.Invoice) error {
+	for _, invoice := range invoices {
+		if err := s.invoices.insert(invoice.Invoice_id, invoice); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memoryInvoiceStore) MarkOverdue(ctx context.Context, invoiceId string, now time.Time) (models.Invoice, error) {
+	return s.invoices.update(invoiceId, func(invoice *models.Invoice) error {
+		if !isOverdue(*invoice, now) {
+			return ErrConflict
+		}
+		status := models.InvoiceOverdue
+		invoice.Payment_status = &status
+		invoice.Updated_at = now
+		return nil
+	})
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// memCollection is a tiny thread-safe document collection used by the
+// in-memory stores. Documents are copied through BSON on the way in and out
+// so callers never share pointers with the stored value, which mirrors how
+// the Mongo implementation behaves.
+type memCollection[T any] struct {
+	mu    sync.RWMutex
+	keys  []string
+	items map[string][]byte
+}
+
+func newMemCollection[T any]() *memCollection[T] {
+	return &memCollection[T]{items: map[string][]byte{}}
+}
+
+func (m *memCollection[T]) insert(id string, doc T) error {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.items[id]; ok {
+		return ErrDuplicate
+	}
+	m.items[id] = raw
+	m.keys = append(m.keys, id)
+
+	return nil
+}
+
+func (m *memCollection[T]) get(id string) (T, error) {
+	var doc T
+
+	m.mu.RLock()
+	raw, ok := m.items[id]
+	m.mu.RUnlock()
+	if !ok {
+		return doc, ErrNotFound
+	}
+
+	err := bson.Unmarshal(raw, &doc)
+	return doc, err
+}
+
+func (m *memCollection[T]) replace(id string, doc T) error {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.items[id]; !ok {
+		return ErrNotFound
+	}
+	m.items[id] = raw
+
+	return nil
+}
+
+// upsert inserts the document or replaces the one stored under id.
+func (m *memCollection[T]) upsert(id string, doc T) error {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.items[id]; !ok {
+		m.keys = append(m.keys, id)
+	}
+	m.items[id] = raw
+
+	return nil
+}
+
+// update applies fn to the stored document while holding the write lock, so
+// read-modify-write sequences are atomic.
+func (m *memCollection[T]) update(id string, fn func(doc *T) error) (T, error) {
+	var doc T
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	raw, ok := m.items[id]
+	if !ok {
+		return doc, ErrNotFound
+	}
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return doc, err
+	}
+	if err := fn(&doc); err != nil {
+		return doc, err
+	}
+
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return doc, err
+	}
+	m.items[id] = raw
+
+	return doc, nil
+}
+
+// find returns the documents accepted by match in insertion order. A nil
+// match returns everything.
+func (m *memCollection[T]) find(match func(doc T) bool) ([]T, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	docs := []T{}
+	for _, id := range m.keys {
+		var doc T
+		if err := bson.Unmarshal(m.items[id], &doc); err != nil {
+			return nil, err
+		}
+		if match == nil || match(doc) {
+			docs = append(docs, doc)
+		}
+	}
+
+	return docs, nil
+}
+
+func paginate[T any](docs []T, skip, limit int) []T {
+	if skip >= len(docs) {
+		return []T{}
+	}
+	docs = docs[skip:]
+	if limit > 0 && limit < len(docs) {
+		docs = docs[:limit]
+	}
+	return docs
+}
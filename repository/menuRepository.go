@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MenuStore interface {
+	List(ctx context.Context) ([]models.Menu, error)
+	Get(ctx context.Context, menuId string) (models.Menu, error)
+	Create(ctx context.Context, menu models.Menu) error
+	Update(ctx context.Context, menu models.Menu) error
+}
+
+type mongoMenuStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoMenuStore) List(ctx context.Context) ([]models.Menu, error) {
+	res, err := s.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	menus := []models.Menu{}
+	err = res.All(ctx, &menus)
+	return menus, err
+}
+
+func (s *mongoMenuStore) Get(ctx context.Context, menuId string) (models.Menu, error) {
+	var menu models.Menu
+	err := s.collection.FindOne(ctx, bson.M{"menu_id": menuId}).Decode(&menu)
+	return menu, notFound(err)
+}
+
+func (s *mongoMenuStore) Create(ctx context.Context, menu models.Menu) error {
+	_, err := s.collection.InsertOne(ctx, menu)
+	return err
+}
+
+func (s *mongoMenuStore) Update(ctx context.Context, menu models.Menu) error {
+	res, err := s.collection.ReplaceOne(ctx, bson.M{"menu_id": menu.Menu_id}, menu)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryMenuStore struct {
+	menus *memCollection[models.Menu]
+}
+
+func (s *memoryMenuStore) List(ctx context.Context) ([]models.Menu, error) {
+	return s.menus.find(nil)
+}
+
+func (s *memoryMenuStore) Get(ctx context.Context, menuId string) (models.Menu, error) {
+	return s.menus.get(menuId)
+}
+
+func (s *memoryMenuStore) Create(ctx context.Context, menu models.Menu) error {
+	return s.menus.insert(menu.Menu_id, menu)
+}
+
+func (s *memoryMenuStore) Update(ctx context.Context, menu models.Menu) error {
+	return s.menus.replace(menu.Menu_id, menu)
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MigrateMoney rewrites prices stored as plain floats into the {minor,
+// currency} documents used since amounts moved to minor units. Only the price
+// fields stored before then are touched, and each is set on its own, on the
+// condition that it still holds the float that was read, so nothing written
+// since is overwritten. Migrated documents no longer match, which makes the
+// migration safe to run on every start. It returns how many documents were
+// rewritten.
+func MigrateMoney(ctx context.Context, db *mongo.Database) (int, error) {
+	migrated := 0
+
+	n, err := migrateField(ctx, db.Collection("food"), "price")
+	migrated += n
+	if err != nil {
+		return migrated, fmt.Errorf("food: %w", err)
+	}
+
+	n, err = migrateField(ctx, db.Collection("orderItem"), "unit_price")
+	migrated += n
+	if err != nil {
+		return migrated, fmt.Errorf("orderItem: %w", err)
+	}
+
+	return migrated, nil
+}
+
+func migrateField(ctx context.Context, collection *mongo.Collection, field string) (int, error) {
+	cursor, err := collection.Find(ctx, bson.M{field: bson.M{"$type": "number"}}, options.Find().SetProjection(bson.M{field: 1}))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		id := cursor.Current.Lookup("_id")
+		legacy := cursor.Current.Lookup(field)
+
+		// legacy values are decoded by money.Money itself
+		var amount money.Money
+		if err := legacy.Unmarshal(&amount); err != nil {
+			return migrated, fmt.Errorf("document %s: %w", id, err)
+		}
+
+		res, err := collection.UpdateOne(ctx, bson.M{"_id": id, field: legacy}, bson.M{"$set": bson.M{field: amount}})
+		if err != nil {
+			return migrated, fmt.Errorf("document %s: %w", id, err)
+		}
+		migrated += int(res.ModifiedCount)
+	}
+
+	return migrated, cursor.Err()
+}
+
+// BackfillRoles gives every user saved before roles existed the WAITER role,
+// the one signing up grants. Admins grant anything more. It returns how many
+// users were updated.
+func BackfillRoles(ctx context.Context, db *mongo.Database) (int, error) {
+	res, err := db.Collection("user").UpdateMany(
+		ctx,
+		bson.M{"role": bson.M{"$in": []interface{}{nil, ""}}},
+		bson.M{"$set": bson.M{"role": models.RoleWaiter}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.ModifiedCount), nil
+}
+
+// RemoveStoredTokens drops the access and refresh tokens users were saved
+// with before only a hash of the refresh token was kept on the session. It
+// returns how many users were updated.
+func RemoveStoredTokens(ctx context.Context, db *mongo.Database) (int, error) {
+	res, err := db.Collection("user").UpdateMany(
+		ctx,
+		bson.M{"$or": []bson.M{{"token": bson.M{"$exists": true}}, {"refresh_token": bson.M{"$exists": true}}}},
+		bson.M{"$unset": bson.M{"token": "", "refresh_token": ""}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.ModifiedCount), nil
+}
@@ -0,0 +1,374 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type OrderItemStore interface {
+	List(ctx context.Context) ([]models.OrderItem, error)
+	ListByOrder(ctx context.Context, orderId string) ([]models.OrderItem, error)
+	// ListPending returns the items the kitchen still has to prepare.
+	ListPending(ctx context.Context) ([]models.OrderItem, error)
+	Get(ctx context.Context, orderItemId string) (models.OrderItem, error)
+	CreateMany(ctx context.Context, orderItems []models.OrderItem) error
+	Update(ctx context.Context, orderItem models.OrderItem) error
+	// Bump marks a pending item as prepared by uid, leaving the rest of it as
+	// it is. It returns ErrConflict when the item is no longer pending.
+	Bump(ctx context.Context, orderItemId, uid string, preparedAt time.Time) (models.OrderItem, error)
+	// Cancel takes a pending item off the kitchen's list the way Bump does,
+	// returning ErrConflict when the item is no longer pending.
+	Cancel(ctx context.Context, orderItemId string, cancelledAt time.Time) (models.OrderItem, error)
+	// ItemsByOrder joins the items of an order with their food and table and
+	// totals them up.
+	ItemsByOrder(ctx context.Context, orderId string) (models.OrderSummary, error)
+}
+
+type mongoOrderItemStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoOrderItemStore) List(ctx context.Context) ([]models.OrderItem, error) {
+	return s.find(ctx, bson.M{})
+}
+
+func (s *mongoOrderItemStore) ListByOrder(ctx context.Context, orderId string) ([]models.OrderItem, error) {
+	return s.find(ctx, bson.M{"order_id": orderId})
+}
+
+func (s *mongoOrderItemStore) ListPending(ctx context.Context) ([]models.OrderItem, error) {
+	return s.find(ctx, bson.M{"kitchen_status": bson.M{"$in": []interface{}{models.KitchenPending, "", nil}}})
+}
+
+func (s *mongoOrderItemStore) find(ctx context.Context, filter bson.M) ([]models.OrderItem, error) {
+	res, err := s.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	orderItems := []models.OrderItem{}
+	err = res.All(ctx, &orderItems)
+	return orderItems, err
+}
+
+func (s *mongoOrderItemStore) Get(ctx context.Context, orderItemId string) (models.OrderItem, error) {
+	var orderItem models.OrderItem
+	err := s.collection.FindOne(ctx, bson.M{"order_item_id": orderItemId}).Decode(&orderItem)
+	return orderItem, notFound(err)
+}
+
+func (s *mongoOrderItemStore) CreateMany(ctx context.Context, orderItems []models.OrderItem) error {
+	if len(orderItems) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, 0, len(orderItems))
+	for _, orderItem := range orderItems {
+		docs = append(docs, orderItem)
+	}
+
+	_, err := s.collection.InsertMany(ctx, docs)
+	return err
+}
+
+func (s *mongoOrderItemStore) Update(ctx context.Context, orderItem models.OrderItem) error {
+	res, err := s.collection.ReplaceOne(ctx, bson.M{"order_item_id": orderItem.Order_item_id}, orderItem)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoOrderItemStore) Bump(ctx context.Context, orderItemId, uid string, preparedAt time.Time) (models.OrderItem, error) {
+	var orderItem models.OrderItem
+
+	err := s.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"order_item_id": orderItemId, "kitchen_status": bson.M{"$in": []interface{}{models.KitchenPending, "", nil}}},
+		bson.M{"$set": bson.M{
+			"kitchen_status": models.KitchenPrepared,
+			"prepared_at":    preparedAt,
+			"prepared_by":    uid,
+			"updated_at":     preparedAt,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&orderItem)
+	if err == mongo.ErrNoDocuments {
+		if _, err := s.Get(ctx, orderItemId); err != nil {
+			return orderItem, err
+		}
+		return orderItem, ErrConflict
+	}
+
+	return orderItem, err
+}
+
+func (s *mongoOrderItemStore) Cancel(ctx context.Context, orderItemId string, cancelledAt time.Time) (models.OrderItem, error) {
+	var orderItem models.OrderItem
+
+	err := s.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"order_item_id": orderItemId, "kitchen_status": bson.M{"$in": []interface{}{models.KitchenPending, "", nil}}},
+		bson.M{"$set": bson.M{
+			"kitchen_status": models.KitchenCancelled,
+			"cancelled_at":   cancelledAt,
+			"updated_at":     cancelledAt,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&orderItem)
+	if err == mongo.ErrNoDocuments {
+		if _, err := s.Get(ctx, orderItemId); err != nil {
+			return orderItem, err
+		}
+		return orderItem, ErrConflict
+	}
+
+	return orderItem, err
+}
+
+func (s *mongoOrderItemStore) ItemsByOrder(ctx context.Context, orderId string) (models.OrderSummary, error) {
+	// cancelled items are never billed
+	matchStage := bson.M{"$match": bson.M{"order_id": orderId, "kitchen_status": bson.M{"$ne": models.KitchenCancelled}}}
+	lookupStage := bson.M{"$lookup": bson.M{"from": "food", "localField": "food_id", "foreignField": "food_id", "as": "food"}}
+	unwindStage := bson.M{"$unwind": bson.M{"path": "$food", "preserveNullAndEmptyArrays": true}}
+
+	lookupMenuStage := bson.M{"$lookup": bson.M{"from": "menu", "localField": "food.menu_id", "foreignField": "menu_id", "as": "menu"}}
+	unwindMenuStage := bson.M{"$unwind": bson.M{"path": "$menu", "preserveNullAndEmptyArrays": true}}
+
+	lookupOrderStage := bson.M{"$lookup": bson.M{"from": "order", "localField": "order_id", "foreignField": "order_id", "as": "order"}}
+	unwindOrderStage := bson.M{"$unwind": bson.M{"path": "$order", "preserveNullAndEmptyArrays": true}}
+
+	lookupTableStage := bson.M{"$lookup": bson.M{"from": "table", "localField": "order.table_id", "foreignField": "table_id", "as": "table"}}
+	unwindTableStage := bson.M{"$unwind": bson.M{"path": "$table", "preserveNullAndEmptyArrays": true}}
+
+	// items stored before orders had counts are one each
+	count := bson.M{"$max": []interface{}{bson.M{"$ifNull": []interface{}{"$count", 1}}, 1}}
+
+	projectStage := bson.M{
+		"$project": bson.M{
+			"_id":          0,
+			"order_id":     1,
+			"table_id":     "$table.table_id",
+			"table_number": "$table.table_number",
+			"guests":       "$table.number_of_guests",
+			"line": bson.M{
+				"order_item_id": "$order_item_id",
+				"food_id":       "$food_id",
+				"food_name":     "$food.name",
+				"food_image":    "$food.food_image",
+				"menu_id":       "$food.menu_id",
+				"menu_category": "$menu.category",
+				"quantity":      "$quantity",
+				"variant":       "$variant",
+				"count":         count,
+				"notes":         "$notes",
+				"seat":          "$seat",
+				"modifiers":     "$modifiers",
+				"unit_price":    "$unit_price",
+				"amount": bson.M{
+					"minor":    bson.M{"$multiply": []interface{}{"$unit_price.minor", count}},
+					"currency": "$unit_price.currency",
+				},
+			},
+		},
+	}
+
+	groupStage := bson.M{
+		"$group": bson.M{
+			"_id":          "$order_id",
+			"table_id":     bson.M{"$first": "$table_id"},
+			"table_number": bson.M{"$first": "$table_number"},
+			"guests":       bson.M{"$first": "$guests"},
+			"payment_due":  bson.M{"$sum": "$line.amount.minor"},
+			"currency":     bson.M{"$first": "$line.amount.currency"},
+			"total_count":  bson.M{"$sum": "$line.count"},
+			"order_items":  bson.M{"$push": "$line"},
+		},
+	}
+
+	projectStage2 := bson.M{
+		"$project": bson.M{
+			"_id":          0,
+			"order_id":     "$_id",
+			"table_id":     1,
+			"table_number": 1,
+			"guests":       1,
+			"payment_due":  bson.M{"minor": "$payment_due", "currency": "$currency"},
+			"total_count":  1,
+			"order_items":  1,
+		},
+	}
+
+	res, err := s.collection.Aggregate(ctx, []bson.M{
+		matchStage,
+		lookupStage,
+		unwindStage,
+		lookupMenuStage,
+		unwindMenuStage,
+		lookupOrderStage,
+		unwindOrderStage,
+		lookupTableStage,
+		unwindTableStage,
+		projectStage,
+		groupStage,
+		projectStage2,
+	})
+	if err != nil {
+		return models.OrderSummary{}, err
+	}
+
+	var summaries []models.OrderSummary
+	if err = res.All(ctx, &summaries); err != nil {
+		return models.OrderSummary{}, err
+	}
+
+	if len(summaries) == 0 {
+		return models.OrderSummary{Order_id: orderId, Order_items: []models.OrderLine{}}, nil
+	}
+	return summaries[0], nil
+}
+
+type memoryOrderItemStore struct {
+	orderItems *memCollection[models.OrderItem]
+	foods      *memCollection[models.Food]
+	menus      *memCollection[models.Menu]
+	orders     *memCollection[models.Order]
+	tables     *memCollection[models.Table]
+}
+
+func (s *memoryOrderItemStore) List(ctx context.Context) ([]models.OrderItem, error) {
+	return s.orderItems.find(nil)
+}
+
+func (s *memoryOrderItemStore) ListByOrder(ctx context.Context, orderId string) ([]models.OrderItem, error) {
+	return s.orderItems.find(func(orderItem models.OrderItem) bool {
+		return orderItem.Order_id == orderId
+	})
+}
+
+func (s *memoryOrderItemStore) ListPending(ctx context.Context) ([]models.OrderItem, error) {
+	return s.orderItems.find(func(orderItem models.OrderItem) bool {
+		return orderItem.CurrentKitchenStatus() == models.KitchenPending
+	})
+}
+
+func (s *memoryOrderItemStore) Get(ctx context.Context, orderItemId string) (models.OrderItem, error) {
+	return s.orderItems.get(orderItemId)
+}
+
+func (s *memoryOrderItemStore) CreateMany(ctx context.Context, orderItems []models.OrderItem) error {
+	for _, orderItem := range orderItems {
+		if err := s.orderItems.insert(orderItem.Order_item_id, orderItem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memoryOrderItemStore) Update(ctx context.Context, orderItem models.OrderItem) error {
+	return s.orderItems.replace(orderItem.Order_item_id, orderItem)
+}
+
+func (s *memoryOrderItemStore) Bump(ctx context.Context, orderItemId, uid string, preparedAt time.Time) (models.OrderItem, error) {
+	return s.orderItems.update(orderItemId, func(orderItem *models.OrderItem) error {
+		if orderItem.CurrentKitchenStatus() != models.KitchenPending {
+			return ErrConflict
+		}
+		orderItem.Kitchen_status = models.KitchenPrepared
+		orderItem.Prepared_at = &preparedAt
+		orderItem.Prepared_by = uid
+		orderItem.Updated_at = preparedAt
+		return nil
+	})
+}
+
+func (s *memoryOrderItemStore) Cancel(ctx context.Context, orderItemId string, cancelledAt time.Time) (models.OrderItem, error) {
+	return s.orderItems.update(orderItemId, func(orderItem *models.OrderItem) error {
+		if orderItem.CurrentKitchenStatus() != models.KitchenPending {
+			return ErrConflict
+		}
+		orderItem.Kitchen_status = models.KitchenCancelled
+		orderItem.Cancelled_at = &cancelledAt
+		orderItem.Updated_at = cancelledAt
+		return nil
+	})
+}
+
+func (s *memoryOrderItemStore) ItemsByOrder(ctx context.Context, orderId string) (models.OrderSummary, error) {
+	summary := models.OrderSummary{Order_id: orderId, Payment_due: money.Zero(), Order_items: []models.OrderLine{}}
+
+	orderItems, err := s.ListByOrder(ctx, orderId)
+	if err != nil || len(orderItems) == 0 {
+		return summary, err
+	}
+
+	if order, err := s.orders.get(orderId); err == nil && order.Table_id != nil {
+		if table, err := s.tables.get(*order.Table_id); err == nil {
+			summary.Table_id = table.Table_id
+			if table.Table_number != nil {
+				summary.Table_number = *table.Table_number
+			}
+			if table.Number_of_guests != nil {
+				summary.Guests = *table.Number_of_guests
+			}
+		}
+	}
+
+	for _, orderItem := range orderItems {
+		if orderItem.CurrentKitchenStatus() == models.KitchenCancelled {
+			continue
+		}
+
+		line := models.OrderLine{Order_item_id: orderItem.Order_item_id}
+		if orderItem.Food_id != nil {
+			line.Food_id = *orderItem.Food_id
+			if food, err := s.foods.get(*orderItem.Food_id); err == nil {
+				if food.Name != nil {
+					line.Food_name = *food.Name
+				}
+				if food.Food_image != nil {
+					line.Food_image = *food.Food_image
+				}
+				if food.Menu_id != nil {
+					line.Menu_id = *food.Menu_id
+					if menu, err := s.menus.get(*food.Menu_id); err == nil {
+						line.Menu_category = menu.Category
+					}
+				}
+			}
+		}
+		if orderItem.Quantity != nil {
+			line.Quantity = *orderItem.Quantity
+		}
+		if orderItem.Variant != nil {
+			line.Variant = *orderItem.Variant
+		}
+		line.Count = orderItem.Units()
+		if orderItem.Notes != nil {
+			line.Notes = *orderItem.Notes
+		}
+		if orderItem.Seat != nil {
+			line.Seat = *orderItem.Seat
+		}
+		line.Modifiers = orderItem.Modifiers
+		if orderItem.Unit_price != nil {
+			line.Unit_price = *orderItem.Unit_price
+		}
+		line.Amount = line.Unit_price.Mul(int64(line.Count))
+
+		summary.Payment_due = summary.Payment_due.Add(line.Amount)
+		summary.Total_count += line.Count
+		summary.Order_items = append(summary.Order_items, line)
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type OrderStore interface {
+	// List returns every order, or only the ones in the given statuses.
+	List(ctx context.Context, statuses ...string) ([]models.Order, error)
+	Get(ctx context.Context, orderId string) (models.Order, error)
+	Create(ctx context.Context, order models.Order) error
+	// UpdateDetails sets the order's table, server, allergies and allergen
+	// warnings to those of order, leaving its status and history alone.
+	UpdateDetails(ctx context.Context, order models.Order) (models.Order, error)
+	// Transition moves the order to change.To if it is still in status from,
+	// and appends change to its history. It returns ErrConflict when the
+	// order has moved on in the meantime.
+	Transition(ctx context.Context, orderId, from string, change models.OrderStatusChange) (models.Order, error)
+	// SetInvoiced sets or clears the order's invoiced marker, and returns
+	// ErrConflict when it is already that way.
+	SetInvoiced(ctx context.Context, orderId string, invoiced bool) (models.Order, error)
+	// SetAllergenWarnings replaces the order's allergen warnings, leaving
+	// the rest of it as it is.
+	SetAllergenWarnings(ctx context.Context, orderId string, warnings []models.AllergenWarning) (models.Order, error)
+}
+
+type mongoOrderStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoOrderStore) List(ctx context.Context, statuses ...string) ([]models.Order, error) {
+	filter := bson.M{}
+	if len(statuses) > 0 {
+		filter["status"] = bson.M{"$in": statusValues(statuses)}
+	}
+
+	res, err := s.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	orders := []models.Order{}
+	err = res.All(ctx, &orders)
+	return orders, err
+}
+
+func (s *mongoOrderStore) Get(ctx context.Context, orderId string) (models.Order, error) {
+	var order models.Order
+	err := s.collection.FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order)
+	return order, notFound(err)
+}
+
+func (s *mongoOrderStore) Create(ctx context.Context, order models.Order) error {
+	_, err := s.collection.InsertOne(ctx, order)
+	return err
+}
+
+func (s *mongoOrderStore) UpdateDetails(ctx context.Context, order models.Order) (models.Order, error) {
+	var updated models.Order
+
+	err := s.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"order_id": order.Order_id},
+		bson.M{"$set": bson.M{
+			"table_id":          order.Table_id,
+			"server_id":         order.Server_id,
+			"allergies":         order.Allergies,
+			"seat_allergies":    order.Seat_allergies,
+			"allergen_warning":  order.Allergen_warning,
+			"allergen_warnings": order.Allergen_warnings,
+			"updated_at":        order.Updated_at,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+
+	return updated, notFound(err)
+}
+
+func (s *mongoOrderStore) Transition(ctx context.Context, orderId, from string, change models.OrderStatusChange) (models.Order, error) {
+	var order models.Order
+
+	err := s.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"order_id": orderId, "status": bson.M{"$in": statusValues([]string{from})}},
+		bson.M{
+			"$set":  bson.M{"status": change.To, "updated_at": change.Changed_at},
+			"$push": bson.M{"status_history": change},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&order)
+	if err == mongo.ErrNoDocuments {
+		if _, err := s.Get(ctx, orderId); err != nil {
+			return order, err
+		}
+		return order, ErrConflict
+	}
+
+	return order, err
+}
+
+func (s *mongoOrderStore) SetInvoiced(ctx context.Context, orderId string, invoiced bool) (models.Order, error) {
+	var order models.Order
+	Updated_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	err := s.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"order_id": orderId, "invoiced": bson.M{"$ne": invoiced}},
+		bson.M{"$set": bson.M{"invoiced": invoiced, "updated_at": Updated_at}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&order)
+	if err == mongo.ErrNoDocuments {
+		if _, err := s.Get(ctx, orderId); err != nil {
+			return order, err
+		}
+		return order, ErrConflict
+	}
+
+	return order, err
+}
+
+func (s *mongoOrderStore) SetAllergenWarnings(ctx context.Context, orderId string, warnings []models.AllergenWarning) (models.Order, error) {
+	var order models.Order
+
+	err := s.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"order_id": orderId},
+		bson.M{"$set": bson.M{"allergen_warning": len(warnings) > 0, "allergen_warnings": warnings}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&order)
+
+	return order, notFound(err)
+}
+
+// statusValues widens OPEN to also match orders saved before statuses
+// existed, which have no status field at all.
+func statusValues(statuses []string) []interface{} {
+	values := []interface{}{}
+	for _, status := range statuses {
+		values = append(values, status)
+		if status == models.OrderOpen {
+			values = append(values, "", nil)
+		}
+	}
+	return values
+}
+
+type memoryOrderStore struct {
+	orders *memCollection[models.Order]
+}
+
+func (s *memoryOrderStore) List(ctx context.Context, statuses ...string) ([]models.Order, error) {
+	return s.orders.find(func(order models.Order) bool {
+		if len(statuses) == 0 {
+			return true
+		}
+		for _, status := range statuses {
+			if order.CurrentStatus() == status {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (s *memoryOrderStore) Get(ctx context.Context, orderId string) (models.Order, error) {
+	return s.orders.get(orderId)
+}
+
+func (s *memoryOrderStore) Create(ctx context.Context, order models.Order) error {
+	return s.orders.insert(order.Order_id, order)
+}
+
+func (s *memoryOrderStore) UpdateDetails(ctx context.Context, order models.Order) (models.Order, error) {
+	return s.orders.update(order.Order_id, func(stored *models.Order) error {
+		stored.Table_id = order.Table_id
+		stored.Server_id = order.Server_id
+		stored.Allergies = order.Allergies
+		stored.Seat_allergies = order.Seat_allergies
+		stored.Allergen_warning = order.Allergen_warning
+		stored.Allergen_warnings = order.Allergen_warnings
+		stored.Updated_at = order.Updated_at
+		return nil
+	})
+}
+
+func (s *memoryOrderStore) Transition(ctx context.Context, orderId, from string, change models.OrderStatusChange) (models.Order, error) {
+	return s.orders.update(orderId, func(order *models.Order) error {
+		if order.CurrentStatus() != from {
+			return ErrConflict
+		}
+		order.Status = change.To
+		order.Status_history = append(order.Status_history, change)
+		order.Updated_at = change.Changed_at
+		return nil
+	})
+}
+
+func (s *memoryOrderStore) SetInvoiced(ctx context.Context, orderId string, invoiced bool) (models.Order, error) {
+	return s.orders.update(orderId, func(order *models.Order) error {
+		if order.Invoiced == invoiced {
+			return ErrConflict
+		}
+		order.Invoiced = invoiced
+		order.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		return nil
+	})
+}
+
+func (s *memoryOrderStore) SetAllergenWarnings(ctx context.Context, orderId string, warnings []models.AllergenWarning) (models.Order, error) {
+	return s.orders.update(orderId, func(order *models.Order) error {
+		order.Allergen_warning = len(warnings) > 0
+		order.Allergen_warnings = warnings
+		return nil
+	})
+}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type PaymentStore interface {
+	ListByInvoice(ctx context.Context, invoiceId string) ([]models.Payment, error)
+	ListBetween(ctx context.Context, from, to time.Time) ([]models.Payment, error)
+	ListByDrawer(ctx context.Context, drawerId string) ([]models.Payment, error)
+	Get(ctx context.Context, paymentId string) (models.Payment, error)
+	GetByReference(ctx context.Context, provider, reference string) (models.Payment, error)
+	Create(ctx context.Context, payment models.Payment) error
+	Update(ctx context.Context, payment models.Payment) error
+	// UpdateRefunded replaces the payment only while its status and refunded
+	// amount are still those of previous, and returns ErrConflict otherwise.
+	UpdateRefunded(ctx context.Context, payment, previous models.Payment) error
+	// UpdateStatus replaces the payment only while its status is still from,
+	// and returns ErrConflict otherwise.
+	UpdateStatus(ctx context.Context, payment models.Payment, from string) error
+}
+
+type mongoPaymentStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoPaymentStore) ListByInvoice(ctx context.Context, invoiceId string) ([]models.Payment, error) {
+	return s.find(ctx, bson.M{"invoice_id": invoiceId})
+}
+
+// ListBetween returns the payments created in [from, to).
+func (s *mongoPaymentStore) ListBetween(ctx context.Context, from, to time.Time) ([]models.Payment, error) {
+	return s.find(ctx, bson.M{"created_at": bson.M{"$gte": from, "$lt": to}})
+}
+
+func (s *mongoPaymentStore) ListByDrawer(ctx context.Context, drawerId string) ([]models.Payment, error) {
+	return s.find(ctx, bson.M{"drawer_id": drawerId})
+}
+
+func (s *mongoPaymentStore) find(ctx context.Context, filter bson.M) ([]models.Payment, error) {
+	res, err := s.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	payments := []models.Payment{}
+	err = res.All(ctx, &payments)
+	return payments, err
+}
+
+func (s *mongoPaymentStore) Get(ctx context.Context, paymentId string) (models.Payment, error) {
+	var payment models.Payment
+	err := s.collection.FindOne(ctx, bson.M{"payment_id": paymentId}).Decode(&payment)
+	return payment, notFound(err)
+}
+
+func (s *mongoPaymentStore) GetByReference(ctx context.Context, provider, reference string) (models.Payment, error) {
+	var payment models.Payment
+	err := s.collection.FindOne(ctx, bson.M{"provider": provider, "reference": reference}).Decode(&payment)
+	return payment, notFound(err)
+}
+
+func (s *mongoPaymentStore) Create(ctx context.Context, payment models.Payment) error {
+	_, err := s.collection.InsertOne(ctx, payment)
+	return err
+}
+
+func (s *mongoPaymentStore) Update(ctx context.Context, payment models.Payment) error {
+	res, err := s.collection.ReplaceOne(ctx, bson.M{"payment_id": payment.Payment_id}, payment)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoPaymentStore) UpdateRefunded(ctx context.Context, payment, previous models.Payment) error {
+	res, err := s.collection.ReplaceOne(
+		ctx,
+		bson.M{"payment_id": payment.Payment_id, "status": previous.Status, "refunded.minor": previous.Refunded.Minor},
+		payment,
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		if _, err := s.Get(ctx, payment.Payment_id); err != nil {
+			return err
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+func (s *mongoPaymentStore) UpdateStatus(ctx context.Context, payment models.Payment, from string) error {
+	res, err := s.collection.ReplaceOne(ctx, bson.M{"payment_id": payment.Payment_id, "status": from}, payment)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		if _, err := s.Get(ctx, payment.Payment_id); err != nil {
+			return err
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+type memoryPaymentStore struct {
+	payments *memCollection[models.Payment]
+}
+
+func (s *memoryPaymentStore) ListByInvoice(ctx context.Context, invoiceId string) ([]models.Payment, error) {
+	return s.payments.find(func(payment models.Payment) bool {
+		return payment.Invoice_id == invoiceId
+	})
+}
+
+func (s *memoryPaymentStore) ListBetween(ctx context.Context, from, to time.Time) ([]models.Payment, error) {
+	return s.payments.find(func(payment models.Payment) bool {
+		return !payment.Created_at.Before(from) && payment.Created_at.Before(to)
+	})
+}
+
+func (s *memoryPaymentStore) ListByDrawer(ctx context.Context, drawerId string) ([]models.Payment, error) {
+	return s.payments.find(func(payment models.Payment) bool {
+		return payment.Drawer_id == drawerId
+	})
+}
+
+func (s *memoryPaymentStore) Get(ctx context.Context, paymentId string) (models.Payment, error) {
+	return s.payments.get(paymentId)
+}
+
+func (s *memoryPaymentStore) GetByReference(ctx context.Context, provider, reference string) (models.Payment, error) {
+	payments, err := s.payments.find(func(payment models.Payment) bool {
+		return payment.Provider == provider && payment.Reference == reference
+	})
+	if err != nil {
+		return models.Payment{}, err
+	}
+	if len(payments) == 0 {
+		return models.Payment{}, ErrNotFound
+	}
+	return payments[0], nil
+}
+
+func (s *memoryPaymentStore) Create(ctx context.Context, payment models.Payment) error {
+	return s.payments.insert(payment.Payment_id, payment)
+}
+
+func (s *memoryPaymentStore) Update(ctx context.Context, payment models.Payment) error {
+	return s.payments.replace(payment.Payment_id, payment)
+}
+
+func (s *memoryPaymentStore) UpdateRefunded(ctx context.Context, payment, previous models.Payment) error {
+	_, err := s.payments.update(payment.Payment_id, func(stored *models.Payment) error {
+		if stored.Status != previous.Status || stored.Refunded.Minor != previous.Refunded.Minor {
+			return ErrConflict
+		}
+		*stored = payment
+		return nil
+	})
+	return err
+}
+
+func (s *memoryPaymentStore) UpdateStatus(ctx context.Context, payment models.Payment, from string) error {
+	_, err := s.payments.update(payment.Payment_id, func(stored *models.Payment) error {
+		if stored.Status != from {
+			return ErrConflict
+		}
+		*stored = payment
+		return nil
+	})
+	return err
+}
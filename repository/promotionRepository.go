@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrUsageLimit is returned when a promotion has been redeemed as many times
+// as it may be.
+var ErrUsageLimit = errors.New("usage limit reached")
+
+type PromotionStore interface {
+	List(ctx context.Context) ([]models.Promotion, error)
+	Get(ctx context.Context, promotionId string) (models.Promotion, error)
+	GetByCode(ctx context.Context, code string) (models.Promotion, error)
+	Create(ctx context.Context, promotion models.Promotion) error
+	Update(ctx context.Context, promotion models.Promotion) error
+	// Redeem counts one more use of the promotion, atomically checking it
+	// against the usage limit.
+	Redeem(ctx context.Context, promotionId string) error
+	// Release gives back a use, when a discount is removed.
+	Release(ctx context.Context, promotionId string) error
+}
+
+type mongoPromotionStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoPromotionStore) List(ctx context.Context) ([]models.Promotion, error) {
+	res, err := s.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	promotions := []models.Promotion{}
+	err = res.All(ctx, &promotions)
+	return promotions, err
+}
+
+func (s *mongoPromotionStore) Get(ctx context.Context, promotionId string) (models.Promotion, error) {
+	var promotion models.Promotion
+	err := s.collection.FindOne(ctx, bson.M{"promotion_id": promotionId}).Decode(&promotion)
+	return promotion, notFound(err)
+}
+
+func (s *mongoPromotionStore) GetByCode(ctx context.Context, code string) (models.Promotion, error) {
+	var promotion models.Promotion
+	err := s.collection.FindOne(ctx, bson.M{"code": code}).Decode(&promotion)
+	return promotion, notFound(err)
+}
+
+func (s *mongoPromotionStore) Create(ctx context.Context, promotion models.Promotion) error {
+	_, err := s.collection.InsertOne(ctx, promotion)
+	return err
+}
+
+func (s *mongoPromotionStore) Update(ctx context.Context, promotion models.Promotion) error {
+	res, err := s.collection.ReplaceOne(ctx, bson.M{"promotion_id": promotion.Promotion_id}, promotion)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoPromotionStore) Redeem(ctx context.Context, promotionId string) error {
+	filter := bson.M{
+		"promotion_id": promotionId,
+		"$or": []bson.M{
+			{"max_uses": nil},
+			{"$expr": bson.M{"$lt": []interface{}{"$uses", "$max_uses"}}},
+		},
+	}
+
+	res, err := s.collection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"uses": 1}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		if _, err := s.Get(ctx, promotionId); err != nil {
+			return err
+		}
+		return ErrUsageLimit
+	}
+	return nil
+}
+
+func (s *mongoPromotionStore) Release(ctx context.Context, promotionId string) error {
+	res, err := s.collection.UpdateOne(ctx, bson.M{"promotion_id": promotionId, "uses": bson.M{"$gt": 0}}, bson.M{"$inc": bson.M{"uses": -1}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		_, err := s.Get(ctx, promotionId)
+		return err
+	}
+	return nil
+}
+
+type memoryPromotionStore struct {
+	promotions *memCollection[models.Promotion]
+}
+
+func (s *memoryPromotionStore) List(ctx context.Context) ([]models.Promotion, error) {
+	return s.promotions.find(nil)
+}
+
+func (s *memoryPromotionStore) Get(ctx context.Context, promotionId string) (models.Promotion, error) {
+	return s.promotions.get(promotionId)
+}
+
+func (s *memoryPromotionStore) GetByCode(ctx context.Context, code string) (models.Promotion, error) {
+	promotions, err := s.promotions.find(func(promotion models.Promotion) bool {
+		return promotion.Code != nil && *promotion.Code == code
+	})
+	if err != nil {
+		return models.Promotion{}, err
+	}
+	if len(promotions) == 0 {
+		return models.Promotion{}, ErrNotFound
+	}
+	return promotions[0], nil
+}
+
+func (s *memoryPromotionStore) Create(ctx context.Context, promotion models.Promotion) error {
+	return s.promotions.insert(promotion.Promotion_id, promotion)
+}
+
+func (s *memoryPromotionStore) Update(ctx context.Context, promotion models.Promotion) error {
+	return s.promotions.replace(promotion.Promotion_id, promotion)
+}
+
+func (s *memoryPromotionStore) Redeem(ctx context.Context, promotionId string) error {
+	_, err := s.promotions.update(promotionId, func(promotion *models.Promotion) error {
+		if promotion.Max_uses != nil && promotion.Uses >= *promotion.Max_uses {
+			return ErrUsageLimit
+		}
+		promotion.Uses++
+		return nil
+	})
+	return err
+}
+
+func (s *memoryPromotionStore) Release(ctx context.Context, promotionId string) error {
+	_, err := s.promotions.update(promotionId, func(promotion *models.Promotion) error {
+		if promotion.Uses > 0 {
+			promotion.Uses--
+		}
+		return nil
+	})
+	return err
+}
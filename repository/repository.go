@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrNotFound  = errors.New("document was not found")
+	ErrDuplicate = errors.New("document already exists")
+	// ErrConflict means the document changed between reading and writing it.
+	ErrConflict = errors.New("document was modified concurrently")
+)
+
+// Stores groups every repository the handlers depend on, so a whole backend
+// can be swapped in one place.
+type Stores struct {
+	Foods          FoodStore
+	Menus          MenuStore
+	Tables         TableStore
+	Orders         OrderStore
+	OrderItems     OrderItemStore
+	Invoices       InvoiceStore
+	Users          UserStore
+	Sessions       SessionStore
+	Revocations    RevocationStore
+	Reservations   ReservationStore
+	TaxRates       TaxRateStore
+	Payments       PaymentStore
+	CreditNotes    CreditNoteStore
+	ServiceCharges ServiceChargeStore
+	Shifts         ShiftStore
+	Promotions     PromotionStore
+	Discounts      DiscountStore
+	Counters       CounterStore
+	Sales          SalesStore
+	Drawers        DrawerStore
+	BusinessDays   BusinessDayStore
+}
+
+func NewMongoStores(db *mongo.Database) *Stores {
+	return &Stores{
+		Foods:          &mongoFoodStore{collection: db.Collection("food")},
+		Menus:          &mongoMenuStore{collection: db.Collection("menu")},
+		Tables:         &mongoTableStore{collection: db.Collection("table")},
+		Orders:         &mongoOrderStore{collection: db.Collection("order")},
+		OrderItems:     &mongoOrderItemStore{collection: db.Collection("orderItem")},
+		Invoices:       &mongoInvoiceStore{collection: db.Collection("invoice")},
+		Users:          &mongoUserStore{collection: db.Collection("user")},
+		Sessions:       &mongoSessionStore{collection: db.Collection("session")},
+		Revocations:    &mongoRevocationStore{collection: db.Collection("revocation")},
+		Reservations:   &mongoReservationStore{collection: db.Collection("reservation")},
+		TaxRates:       &mongoTaxRateStore{collection: db.Collection("taxRate")},
+		Payments:       &mongoPaymentStore{collection: db.Collection("payment")},
+		CreditNotes:    &mongoCreditNoteStore{collection: db.Collection("creditNote")},
+		ServiceCharges: &mongoServiceChargeStore{collection: db.Collection("serviceCharge")},
+		Shifts:         &mongoShiftStore{collection: db.Collection("shift")},
+		Promotions:     &mongoPromotionStore{collection: db.Collection("promotion")},
+		Discounts:      &mongoDiscountStore{collection: db.Collection("discount")},
+		Counters:       &mongoCounterStore{collection: db.Collection("counter")},
+		Sales:          &mongoSalesStore{collection: db.Collection("invoice")},
+		Drawers:        &mongoDrawerStore{collection: db.Collection("drawer")},
+		BusinessDays:   &mongoBusinessDayStore{collection: db.Collection("businessDay")},
+	}
+}
+
+func NewMemoryStores() *Stores {
+	foods := newMemCollection[models.Food]()
+	menus := newMemCollection[models.Menu]()
+	tables := newMemCollection[models.Table]()
+	orders := newMemCollection[models.Order]()
+	orderItems := newMemCollection[models.OrderItem]()
+	invoices := newMemCollection[models.Invoice]()
+	users := newMemCollection[models.User]()
+
+	return &Stores{
+		Foods:          &memoryFoodStore{foods: foods},
+		Menus:          &memoryMenuStore{menus: menus},
+		Tables:         &memoryTableStore{tables: tables},
+		Orders:         &memoryOrderStore{orders: orders},
+		OrderItems:     &memoryOrderItemStore{orderItems: orderItems, foods: foods, menus: menus, orders: orders, tables: tables},
+		Invoices:       &memoryInvoiceStore{invoices: invoices},
+		Users:          &memoryUserStore{users: users},
+		Sessions:       &memorySessionStore{sessions: newMemCollection[models.Session]()},
+		Revocations:    &memoryRevocationStore{revocations: newMemCollection[models.Revocation]()},
+		Reservations:   &memoryReservationStore{reservations: newMemCollection[models.Reservation]()},
+		TaxRates:       &memoryTaxRateStore{taxRates: newMemCollection[models.TaxRate]()},
+		Payments:       &memoryPaymentStore{payments: newMemCollection[models.Payment]()},
+		CreditNotes:    &memoryCreditNoteStore{creditNotes: newMemCollection[models.CreditNote]()},
+		ServiceCharges: &memoryServiceChargeStore{serviceCharges: newMemCollection[models.ServiceCharge]()},
+		Shifts:         &memoryShiftStore{shifts: newMemCollection[models.Shift]()},
+		Promotions:     &memoryPromotionStore{promotions: newMemCollection[models.Promotion]()},
+		Discounts:      &memoryDiscountStore{discounts: newMemCollection[models.Discount]()},
+		Counters:       &memoryCounterStore{counters: map[string]int64{}},
+		Sales:          &memorySalesStore{invoices: invoices, orders: orders, tables: tables, foods: foods, menus: menus, users: users},
+		Drawers:        &memoryDrawerStore{drawers: newMemCollection[models.Drawer]()},
+		BusinessDays:   &memoryBusinessDayStore{days: newMemCollection[models.BusinessDay]()},
+	}
+}
+
+// notFound maps the driver's "no documents" error onto ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	return err
+}
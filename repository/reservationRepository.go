@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ReservationFilter struct {
+	Table_id string
+	From     *time.Time
+	To       *time.Time
+}
+
+type ReservationStore interface {
+	// List returns reservations overlapping [From, To) on the given table;
+	// every field of the filter is optional.
+	List(ctx context.Context, filter ReservationFilter) ([]models.Reservation, error)
+	Get(ctx context.Context, reservationId string) (models.Reservation, error)
+	Create(ctx context.Context, reservation models.Reservation) error
+	Update(ctx context.Context, reservation models.Reservation) error
+	// ListOverlapping returns the live bookings of a table that overlap
+	// [start, end), leaving out the reservation excludeId.
+	ListOverlapping(ctx context.Context, tableId string, start, end time.Time, excludeId string) ([]models.Reservation, error)
+}
+
+type mongoReservationStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoReservationStore) List(ctx context.Context, filter ReservationFilter) ([]models.Reservation, error) {
+	query := bson.M{}
+	if filter.Table_id != "" {
+		query["table_id"] = filter.Table_id
+	}
+	if filter.To != nil {
+		query["start_time"] = bson.M{"$lt": *filter.To}
+	}
+	if filter.From != nil {
+		query["end_time"] = bson.M{"$gt": *filter.From}
+	}
+
+	return s.find(ctx, query)
+}
+
+func (s *mongoReservationStore) find(ctx context.Context, query bson.M) ([]models.Reservation, error) {
+	res, err := s.collection.Find(ctx, query, options.Find().SetSort(bson.M{"start_time": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	reservations := []models.Reservation{}
+	err = res.All(ctx, &reservations)
+	return reservations, err
+}
+
+func (s *mongoReservationStore) Get(ctx context.Context, reservationId string) (models.Reservation, error) {
+	var reservation models.Reservation
+	err := s.collection.FindOne(ctx, bson.M{"reservation_id": reservationId}).Decode(&reservation)
+	return reservation, notFound(err)
+}
+
+func (s *mongoReservationStore) Create(ctx context.Context, reservation models.Reservation) error {
+	_, err := s.collection.InsertOne(ctx, reservation)
+	return err
+}
+
+func (s *mongoReservationStore) Update(ctx context.Context, reservation models.Reservation) error {
+	res, err := s.collection.ReplaceOne(ctx, bson.M{"reservation_id": reservation.Reservation_id}, reservation)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoReservationStore) ListOverlapping(ctx context.Context, tableId string, start, end time.Time, excludeId string) ([]models.Reservation, error) {
+	return s.find(ctx, bson.M{
+		"table_id":       tableId,
+		"reservation_id": bson.M{"$ne": excludeId},
+		"status":         bson.M{"$ne": models.ReservationCancelled},
+		"start_time":     bson.M{"$lt": end},
+		"end_time":       bson.M{"$gt": start},
+	})
+}
+
+type memoryReservationStore struct {
+	reservations *memCollection[models.Reservation]
+}
+
+func (s *memoryReservationStore) List(ctx context.Context, filter ReservationFilter) ([]models.Reservation, error) {
+	return s.reservations.find(func(reservation models.Reservation) bool {
+		if filter.Table_id != "" && (reservation.Table_id == nil || *reservation.Table_id != filter.Table_id) {
+			return false
+		}
+		if filter.To != nil && !reservation.Start_time.Before(*filter.To) {
+			return false
+		}
+		if filter.From != nil && !reservation.End_time.After(*filter.From) {
+			return false
+		}
+		return true
+	})
+}
+
+func (s *memoryReservationStore) Get(ctx context.Context, reservationId string) (models.Reservation, error) {
+	return s.reservations.get(reservationId)
+}
+
+func (s *memoryReservationStore) Create(ctx context.Context, reservation models.Reservation) error {
+	return s.reservations.insert(reservation.Reservation_id, reservation)
+}
+
+func (s *memoryReservationStore) Update(ctx context.Context, reservation models.Reservation) error {
+	return s.reservations.replace(reservation.Reservation_id, reservation)
+}
+
+func (s *memoryReservationStore) ListOverlapping(ctx context.Context, tableId string, start, end time.Time, excludeId string) ([]models.Reservation, error) {
+	return s.reservations.find(func(reservation models.Reservation) bool {
+		return reservation.Table_id != nil && *reservation.Table_id == tableId &&
+			reservation.Reservation_id != excludeId &&
+			reservation.Status != models.ReservationCancelled &&
+			reservation.Start_time.Before(end) && reservation.End_time.After(start)
+	})
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RevocationStore interface {
+	// RevokeSession blocks every token of the session until expiresAt.
+	RevokeSession(ctx context.Context, sessionId string, expiresAt time.Time) error
+	// RevokeUser blocks every token of the user issued before the given time.
+	// Tokens carry their issue time in whole seconds, so tokens issued in the
+	// same second are blocked too rather than let through.
+	RevokeUser(ctx context.Context, userId string, issuedBefore time.Time, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, sessionId, userId string, issuedAt int64) (bool, error)
+}
+
+type mongoRevocationStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoRevocationStore) RevokeSession(ctx context.Context, sessionId string, expiresAt time.Time) error {
+	return s.upsert(ctx, models.RevokedSession, sessionId, bson.M{"expires_at": expiresAt})
+}
+
+func (s *mongoRevocationStore) RevokeUser(ctx context.Context, userId string, issuedBefore time.Time, expiresAt time.Time) error {
+	return s.upsert(ctx, models.RevokedUser, userId, bson.M{"issued_before": issuedBefore.Unix(), "expires_at": expiresAt})
+}
+
+func (s *mongoRevocationStore) upsert(ctx context.Context, kind, key string, set bson.M) error {
+	Created_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	_, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{"kind": kind, "key": key},
+		bson.M{
+			"$set":         set,
+			"$setOnInsert": bson.M{"_id": primitive.NewObjectID(), "created_at": Created_at},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (s *mongoRevocationStore) IsRevoked(ctx context.Context, sessionId, userId string, issuedAt int64) (bool, error) {
+	filter := bson.M{"$or": []bson.M{
+		{"kind": models.RevokedSession, "key": sessionId},
+		{"kind": models.RevokedUser, "key": userId, "issued_before": bson.M{"$gte": issuedAt}},
+	}}
+
+	count, err := s.collection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	return count > 0, err
+}
+
+type memoryRevocationStore struct {
+	revocations *memCollection[models.Revocation]
+}
+
+func (s *memoryRevocationStore) RevokeSession(ctx context.Context, sessionId string, expiresAt time.Time) error {
+	return s.upsert(models.Revocation{Kind: models.RevokedSession, Key: sessionId, Expires_at: expiresAt})
+}
+
+func (s *memoryRevocationStore) RevokeUser(ctx context.Context, userId string, issuedBefore time.Time, expiresAt time.Time) error {
+	return s.upsert(models.Revocation{Kind: models.RevokedUser, Key: userId, Issued_before: issuedBefore.Unix(), Expires_at: expiresAt})
+}
+
+func (s *memoryRevocationStore) upsert(revocation models.Revocation) error {
+	revocation.ID = primitive.NewObjectID()
+	revocation.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	return s.revocations.upsert(revocation.Kind+":"+revocation.Key, revocation)
+}
+
+func (s *memoryRevocationStore) IsRevoked(ctx context.Context, sessionId, userId string, issuedAt int64) (bool, error) {
+	if _, err := s.revocations.get(models.RevokedSession + ":" + sessionId); err == nil {
+		return true, nil
+	}
+
+	revocation, err := s.revocations.get(models.RevokedUser + ":" + userId)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return issuedAt <= revocation.Issued_before, nil
+}
@@ -0,0 +1,341 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"github.com/tokha04/go-restautant-management/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SalesFilter selects the invoices created in [From, To) and how their lines
+// are grouped, one of models.SalesGroups. An empty Group_by totals every line
+// in a single row. Location is the time zone days and hours are taken in.
+type SalesFilter struct {
+	From     time.Time
+	To       time.Time
+	Group_by string
+	Location *time.Location
+}
+
+// SalesStore totals invoiced sales for reports.
+type SalesStore interface {
+	Sales(ctx context.Context, filter SalesFilter) ([]models.SalesRow, error)
+}
+
+// mongoSalesStore aggregates the invoice collection, joining the orders,
+// tables, food, menus and users the groupings need.
+type mongoSalesStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoSalesStore) Sales(ctx context.Context, filter SalesFilter) ([]models.SalesRow, error) {
+	timezone := mongoTimezone(filter.Location, filter.From)
+
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"created_at":     bson.M{"$gte": filter.From, "$lt": filter.To},
+			"payment_status": bson.M{"$ne": models.InvoiceVoided},
+		}},
+		{"$lookup": bson.M{"from": "order", "localField": "order_id", "foreignField": "order_id", "as": "order"}},
+		{"$unwind": bson.M{"path": "$order", "preserveNullAndEmptyArrays": true}},
+		{"$lookup": bson.M{"from": "table", "localField": "order.table_id", "foreignField": "table_id", "as": "table"}},
+		{"$unwind": bson.M{"path": "$table", "preserveNullAndEmptyArrays": true}},
+		// shares of an evenly or custom split order have no lines
+		{"$unwind": bson.M{"path": "$lines", "preserveNullAndEmptyArrays": true}},
+	}
+
+	var key, label interface{}
+	switch filter.Group_by {
+	case "":
+		key, label = "", "Total"
+	case models.SalesByDay:
+		key = bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$created_at", "timezone": timezone}}
+		label = key
+	case models.SalesByHour:
+		key = bson.M{"$dateToString": bson.M{"format": "%H:00", "date": "$created_at", "timezone": timezone}}
+		label = key
+	case models.SalesByMenu:
+		pipeline = append(pipeline,
+			bson.M{"$lookup": bson.M{"from": "food", "localField": "lines.food_id", "foreignField": "food_id", "as": "food"}},
+			bson.M{"$unwind": bson.M{"path": "$food", "preserveNullAndEmptyArrays": true}},
+			bson.M{"$lookup": bson.M{"from": "menu", "localField": "food.menu_id", "foreignField": "menu_id", "as": "menu"}},
+			bson.M{"$unwind": bson.M{"path": "$menu", "preserveNullAndEmptyArrays": true}},
+		)
+		key, label = "$food.menu_id", "$menu.name"
+	case models.SalesByFood:
+		key, label = "$lines.food_id", "$lines.food_name"
+	case models.SalesByTable:
+		key, label = "$table.table_id", bson.M{"$toString": "$table.table_number"}
+	case models.SalesByUser:
+		pipeline = append(pipeline,
+			bson.M{"$lookup": bson.M{"from": "user", "localField": "order.server_id", "foreignField": "user_id", "as": "user"}},
+			bson.M{"$unwind": bson.M{"path": "$user", "preserveNullAndEmptyArrays": true}},
+		)
+		key, label = "$order.server_id", bson.M{"$concat": []interface{}{"$user.first_name", " ", "$user.last_name"}}
+	case models.SalesByPayment:
+		key = bson.M{"$ifNull": []interface{}{"$payment_method", models.PaymentNone}}
+		label = key
+	default:
+		return nil, fmt.Errorf("unknown sales grouping %q", filter.Group_by)
+	}
+
+	// lines billed before orders had counts are one each
+	units := bson.M{"$max": []interface{}{bson.M{"$ifNull": []interface{}{"$lines.count", 1}}, 1}}
+
+	// a share without lines is one sale of no items with the invoice's
+	// totals, as the memory store's invoiceSales has it
+	inclusiveTax := bson.M{"$sum": bson.M{"$map": bson.M{
+		"input": bson.M{"$filter": bson.M{
+			"input": bson.M{"$ifNull": []interface{}{"$tax_breakdown", []interface{}{}}},
+			"cond":  "$$this.inclusive",
+		}},
+		"in": "$$this.tax_amount.minor",
+	}}}
+	pipeline = append(pipeline, bson.M{"$addFields": bson.M{"sale": bson.M{"$cond": []interface{}{
+		bson.M{"$eq": []interface{}{bson.M{"$type": "$lines"}, "object"}},
+		bson.M{
+			"items":     units,
+			"gross":     bson.M{"$multiply": []interface{}{"$lines.unit_price.minor", units}},
+			"discounts": "$lines.discount.minor",
+			"tax":       "$lines.tax_amount.minor",
+			"net":       "$lines.net_amount.minor",
+			"currency":  "$lines.unit_price.currency",
+		},
+		bson.M{
+			"items":     0,
+			"gross":     bson.M{"$add": []interface{}{"$subtotal.minor", "$discount_total.minor", inclusiveTax}},
+			"discounts": "$discount_total.minor",
+			"tax":       "$tax_total.minor",
+			"net":       "$subtotal.minor",
+			"currency":  "$total.currency",
+		},
+	}}}})
+
+	pipeline = append(pipeline,
+		bson.M{"$group": bson.M{
+			"_id":      key,
+			"label":    bson.M{"$first": label},
+			"invoices": bson.M{"$addToSet": "$invoice_id"},
+			"orders": bson.M{"$addToSet": bson.M{
+				"order_id": "$order_id",
+				"guests":   bson.M{"$ifNull": []interface{}{"$table.number_of_guests", 0}},
+			}},
+			"items":     bson.M{"$sum": "$sale.items"},
+			"gross":     bson.M{"$sum": "$sale.gross"},
+			"discounts": bson.M{"$sum": "$sale.discounts"},
+			"tax":       bson.M{"$sum": "$sale.tax"},
+			"net":       bson.M{"$sum": "$sale.net"},
+			"currency":  bson.M{"$first": "$sale.currency"},
+		}},
+		bson.M{"$project": bson.M{
+			"_id":       0,
+			"key":       "$_id",
+			"label":     1,
+			"invoices":  bson.M{"$size": "$invoices"},
+			"covers":    bson.M{"$sum": "$orders.guests"},
+			"items":     1,
+			"gross":     bson.M{"minor": "$gross", "currency": "$currency"},
+			"discounts": bson.M{"minor": "$discounts", "currency": "$currency"},
+			"tax":       bson.M{"minor": "$tax", "currency": "$currency"},
+			"net":       bson.M{"minor": "$net", "currency": "$currency"},
+		}},
+	)
+
+	res, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []models.SalesRow{}
+	err = res.All(ctx, &rows)
+	return rows, err
+}
+
+// mongoTimezone names loc the way $dateToString accepts it. The process's
+// local zone has no name MongoDB knows, so it is given as its offset at t.
+func mongoTimezone(loc *time.Location, t time.Time) string {
+	if loc == nil {
+		loc = time.Local
+	}
+	if name := loc.String(); name != "Local" {
+		return name
+	}
+	return t.In(loc).Format("-07:00")
+}
+
+type memorySalesStore struct {
+	invoices *memCollection[models.Invoice]
+	orders   *memCollection[models.Order]
+	tables   *memCollection[models.Table]
+	foods    *memCollection[models.Food]
+	menus    *memCollection[models.Menu]
+	users    *memCollection[models.User]
+}
+
+// salesGroup accumulates a row along with the invoices and orders already
+// counted in it.
+type salesGroup struct {
+	row      models.SalesRow
+	invoices map[string]bool
+	orders   map[string]bool
+}
+
+func (s *memorySalesStore) Sales(ctx context.Context, filter SalesFilter) ([]models.SalesRow, error) {
+	loc := filter.Location
+	if loc == nil {
+		loc = time.Local
+	}
+
+	invoices, err := s.invoices.find(func(invoice models.Invoice) bool {
+		return !invoice.IsVoided() && !invoice.Created_at.Before(filter.From) && invoice.Created_at.Before(filter.To)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	groups := map[string]*salesGroup{}
+	keys := []string{}
+
+	for _, invoice := range invoices {
+		order, _ := s.orders.get(invoice.Order_id)
+		var table models.Table
+		if order.Table_id != nil {
+			table, _ = s.tables.get(*order.Table_id)
+		}
+		guests := 0
+		if table.Number_of_guests != nil {
+			guests = *table.Number_of_guests
+		}
+
+		for _, sale := range invoiceSales(invoice) {
+			key, label, err := s.groupKey(filter.Group_by, invoice, order, table, sale.line, loc)
+			if err != nil {
+				return nil, err
+			}
+
+			group, ok := groups[key]
+			if !ok {
+				zero := money.Zero()
+				zero.Currency = sale.gross.Currency
+				group = &salesGroup{
+					row:      models.SalesRow{Key: key, Label: label, Gross: zero, Discounts: zero, Tax: zero, Net: zero},
+					invoices: map[string]bool{},
+					orders:   map[string]bool{},
+				}
+				groups[key] = group
+				keys = append(keys, key)
+			}
+
+			row := &group.row
+			row.Items += sale.items
+			row.Gross = row.Gross.Add(sale.gross)
+			row.Discounts = row.Discounts.Add(sale.discount)
+			row.Tax = row.Tax.Add(sale.tax)
+			row.Net = row.Net.Add(sale.net)
+			if !group.invoices[invoice.Invoice_id] {
+				group.invoices[invoice.Invoice_id] = true
+				row.Invoices++
+			}
+			if !group.orders[invoice.Order_id] {
+				group.orders[invoice.Order_id] = true
+				row.Covers += guests
+			}
+		}
+	}
+
+	rows := []models.SalesRow{}
+	for _, key := range keys {
+		rows = append(rows, groups[key].row)
+	}
+	return rows, nil
+}
+
+// lineSale is what one invoice line sold.
+type lineSale struct {
+	line     models.InvoiceLine
+	items    int
+	gross    money.Money
+	discount money.Money
+	tax      money.Money
+	net      money.Money
+}
+
+// invoiceSales lists the sales of invoice line by line. Even and custom
+// splits bill shares of the order rather than its lines, so a share is one
+// sale of no items and no food, with the totals of the invoice.
+func invoiceSales(invoice models.Invoice) []lineSale {
+	if len(invoice.Lines) == 0 {
+		inclusiveTax := money.Zero()
+		for _, summary := range invoice.Tax_breakdown {
+			if summary.Inclusive {
+				inclusiveTax = inclusiveTax.Add(summary.Tax_amount)
+			}
+		}
+		return []lineSale{{
+			gross:    invoice.Subtotal.Add(inclusiveTax).Add(invoice.Discount_total),
+			discount: invoice.Discount_total,
+			tax:      invoice.Tax_total,
+			net:      invoice.Subtotal,
+		}}
+	}
+
+	sales := []lineSale{}
+	for _, line := range invoice.Lines {
+		sales = append(sales, lineSale{
+			line:     line,
+			items:    line.Units(),
+			gross:    line.Unit_price.Mul(int64(line.Units())),
+			discount: line.Discount,
+			tax:      line.Tax_amount,
+			net:      line.Net_amount,
+		})
+	}
+	return sales
+}
+
+func (s *memorySalesStore) groupKey(groupBy string, invoice models.Invoice, order models.Order, table models.Table, line models.InvoiceLine, loc *time.Location) (string, string, error) {
+	switch groupBy {
+	case "":
+		return "", "Total", nil
+	case models.SalesByDay:
+		day := invoice.Created_at.In(loc).Format("2006-01-02")
+		return day, day, nil
+	case models.SalesByHour:
+		hour := invoice.Created_at.In(loc).Format("15") + ":00"
+		return hour, hour, nil
+	case models.SalesByMenu:
+		food, err := s.foods.get(line.Food_id)
+		if err != nil || food.Menu_id == nil {
+			return "", "", nil
+		}
+		menu, _ := s.menus.get(*food.Menu_id)
+		return *food.Menu_id, menu.Name, nil
+	case models.SalesByFood:
+		return line.Food_id, line.Food_name, nil
+	case models.SalesByTable:
+		if table.Table_number == nil {
+			return table.Table_id, "", nil
+		}
+		return table.Table_id, strconv.Itoa(*table.Table_number), nil
+	case models.SalesByUser:
+		if order.Server_id == nil {
+			return "", "", nil
+		}
+		user, err := s.users.get(*order.Server_id)
+		if err != nil || user.First_name == nil || user.Last_name == nil {
+			return *order.Server_id, "", nil
+		}
+		return *order.Server_id, *user.First_name + " " + *user.Last_name, nil
+	case models.SalesByPayment:
+		if invoice.Payment_method == nil {
+			return models.PaymentNone, models.PaymentNone, nil
+		}
+		return *invoice.Payment_method, *invoice.Payment_method, nil
+	}
+	return "", "", fmt.Errorf("unknown sales grouping %q", groupBy)
+}
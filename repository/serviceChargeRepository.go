@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ServiceChargeStore interface {
+	List(ctx context.Context) ([]models.ServiceCharge, error)
+	Get(ctx context.Context, serviceChargeId string) (models.ServiceCharge, error)
+	Create(ctx context.Context, serviceCharge models.ServiceCharge) error
+	Update(ctx context.Context, serviceCharge models.ServiceCharge) error
+}
+
+type mongoServiceChargeStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoServiceChargeStore) List(ctx context.Context) ([]models.ServiceCharge, error) {
+	res, err := s.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	serviceCharges := []models.ServiceCharge{}
+	err = res.All(ctx, &serviceCharges)
+	return serviceCharges, err
+}
+
+func (s *mongoServiceChargeStore) Get(ctx context.Context, serviceChargeId string) (models.ServiceCharge, error) {
+	var serviceCharge models.ServiceCharge
+	err := s.collection.FindOne(ctx, bson.M{"service_charge_id": serviceChargeId}).Decode(&serviceCharge)
+	return serviceCharge, notFound(err)
+}
+
+func (s *mongoServiceChargeStore) Create(ctx context.Context, serviceCharge models.ServiceCharge) error {
+	_, err := s.collection.InsertOne(ctx, serviceCharge)
+	return err
+}
+
+func (s *mongoServiceChargeStore) Update(ctx context.Context, serviceCharge models.ServiceCharge) error {
+	res, err := s.collection.ReplaceOne(ctx, bson.M{"service_charge_id": serviceCharge.Service_charge_id}, serviceCharge)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryServiceChargeStore struct {
+	serviceCharges *memCollection[models.ServiceCharge]
+}
+
+func (s *memoryServiceChargeStore) List(ctx context.Context) ([]models.ServiceCharge, error) {
+	return s.serviceCharges.find(nil)
+}
+
+func (s *memoryServiceChargeStore) Get(ctx context.Context, serviceChargeId string) (models.ServiceCharge, error) {
+	return s.serviceCharges.get(serviceChargeId)
+}
+
+func (s *memoryServiceChargeStore) Create(ctx context.Context, serviceCharge models.ServiceCharge) error {
+	return s.serviceCharges.insert(serviceCharge.Service_charge_id, serviceCharge)
+}
+
+func (s *memoryServiceChargeStore) Update(ctx context.Context, serviceCharge models.ServiceCharge) error {
+	return s.serviceCharges.replace(serviceCharge.Service_charge_id, serviceCharge)
+}
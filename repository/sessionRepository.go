@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrStaleRefreshToken is returned by Rotate when the presented refresh token
+// is no longer the current one of its session, i.e. it was already used.
+var ErrStaleRefreshToken = errors.New("refresh token was already rotated")
+
+type SessionStore interface {
+	Create(ctx context.Context, session models.Session) error
+	Get(ctx context.Context, sessionId string) (models.Session, error)
+	ListActiveByUser(ctx context.Context, userId string) ([]models.Session, error)
+	// Rotate swaps the current refresh token hash for a new one, but only if
+	// the session is live and still holds currentHash.
+	Rotate(ctx context.Context, sessionId, currentHash, nextHash string, expiresAt time.Time) error
+	Revoke(ctx context.Context, sessionId, reason string) error
+}
+
+type mongoSessionStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoSessionStore) Create(ctx context.Context, session models.Session) error {
+	_, err := s.collection.InsertOne(ctx, session)
+	return err
+}
+
+func (s *mongoSessionStore) Get(ctx context.Context, sessionId string) (models.Session, error) {
+	var session models.Session
+	err := s.collection.FindOne(ctx, bson.M{"session_id": sessionId}).Decode(&session)
+	return session, notFound(err)
+}
+
+func (s *mongoSessionStore) ListActiveByUser(ctx context.Context, userId string) ([]models.Session, error) {
+	res, err := s.collection.Find(ctx, bson.M{"user_id": userId, "revoked": false})
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []models.Session{}
+	err = res.All(ctx, &sessions)
+	return sessions, err
+}
+
+func (s *mongoSessionStore) Rotate(ctx context.Context, sessionId, currentHash, nextHash string, expiresAt time.Time) error {
+	Updated_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	res, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{"session_id": sessionId, "refresh_token_hash": currentHash, "revoked": false},
+		bson.M{"$set": bson.M{"refresh_token_hash": nextHash, "expires_at": expiresAt, "updated_at": Updated_at}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrStaleRefreshToken
+	}
+	return nil
+}
+
+func (s *mongoSessionStore) Revoke(ctx context.Context, sessionId, reason string) error {
+	Revoked_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	res, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{"session_id": sessionId},
+		bson.M{"$set": bson.M{"revoked": true, "revoked_reason": reason, "revoked_at": Revoked_at, "updated_at": Revoked_at}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memorySessionStore struct {
+	sessions *memCollection[models.Session]
+}
+
+func (s *memorySessionStore) Create(ctx context.Context, session models.Session) error {
+	return s.sessions.insert(session.Session_id, session)
+}
+
+func (s *memorySessionStore) Get(ctx context.Context, sessionId string) (models.Session, error) {
+	return s.sessions.get(sessionId)
+}
+
+func (s *memorySessionStore) ListActiveByUser(ctx context.Context, userId string) ([]models.Session, error) {
+	return s.sessions.find(func(session models.Session) bool {
+		return session.User_id == userId && !session.Revoked
+	})
+}
+
+func (s *memorySessionStore) Rotate(ctx context.Context, sessionId, currentHash, nextHash string, expiresAt time.Time) error {
+	_, err := s.sessions.update(sessionId, func(session *models.Session) error {
+		if session.Revoked || session.Refresh_token_hash != currentHash {
+			return ErrStaleRefreshToken
+		}
+		session.Refresh_token_hash = nextHash
+		session.Expires_at = expiresAt
+		session.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		return nil
+	})
+	return err
+}
+
+func (s *memorySessionStore) Revoke(ctx context.Context, sessionId, reason string) error {
+	_, err := s.sessions.update(sessionId, func(session *models.Session) error {
+		Revoked_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		session.Revoked = true
+		session.Revoked_reason = reason
+		session.Revoked_at = &Revoked_at
+		session.Updated_at = Revoked_at
+		return nil
+	})
+	return err
+}
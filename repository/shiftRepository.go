@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ShiftFilter narrows List down; zero fields are ignored. A shift matches the
+// time range when it overlaps it.
+type ShiftFilter struct {
+	User_id string
+	From    time.Time
+	To      time.Time
+}
+
+type ShiftStore interface {
+	List(ctx context.Context, filter ShiftFilter) ([]models.Shift, error)
+	Get(ctx context.Context, shiftId string) (models.Shift, error)
+	GetOpenByUser(ctx context.Context, userId string) (models.Shift, error)
+	Create(ctx context.Context, shift models.Shift) error
+	Update(ctx context.Context, shift models.Shift) error
+}
+
+type mongoShiftStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoShiftStore) List(ctx context.Context, filter ShiftFilter) ([]models.Shift, error) {
+	query := bson.M{}
+	if filter.User_id != "" {
+		query["user_id"] = filter.User_id
+	}
+	if !filter.To.IsZero() {
+		query["started_at"] = bson.M{"$lt": filter.To}
+	}
+	if !filter.From.IsZero() {
+		query["$or"] = []bson.M{{"ended_at": nil}, {"ended_at": bson.M{"$gt": filter.From}}}
+	}
+
+	res, err := s.collection.Find(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	shifts := []models.Shift{}
+	err = res.All(ctx, &shifts)
+	return shifts, err
+}
+
+func (s *mongoShiftStore) Get(ctx context.Context, shiftId string) (models.Shift, error) {
+	var shift models.Shift
+	err := s.collection.FindOne(ctx, bson.M{"shift_id": shiftId}).Decode(&shift)
+	return shift, notFound(err)
+}
+
+func (s *mongoShiftStore) GetOpenByUser(ctx context.Context, userId string) (models.Shift, error) {
+	var shift models.Shift
+	err := s.collection.FindOne(ctx, bson.M{"user_id": userId, "ended_at": nil}).Decode(&shift)
+	return shift, notFound(err)
+}
+
+func (s *mongoShiftStore) Create(ctx context.Context, shift models.Shift) error {
+	_, err := s.collection.InsertOne(ctx, shift)
+	return err
+}
+
+func (s *mongoShiftStore) Update(ctx context.Context, shift models.Shift) error {
+	res, err := s.collection.ReplaceOne(ctx, bson.M{"shift_id": shift.Shift_id}, shift)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryShiftStore struct {
+	shifts *memCollection[models.Shift]
+}
+
+func (s *memoryShiftStore) List(ctx context.Context, filter ShiftFilter) ([]models.Shift, error) {
+	return s.shifts.find(func(shift models.Shift) bool {
+		if filter.User_id != "" && shift.User_id != filter.User_id {
+			return false
+		}
+		if !filter.To.IsZero() && !shift.Started_at.Before(filter.To) {
+			return false
+		}
+		if !filter.From.IsZero() && shift.Ended_at != nil && !shift.Ended_at.After(filter.From) {
+			return false
+		}
+		return true
+	})
+}
+
+func (s *memoryShiftStore) Get(ctx context.Context, shiftId string) (models.Shift, error) {
+	return s.shifts.get(shiftId)
+}
+
+func (s *memoryShiftStore) GetOpenByUser(ctx context.Context, userId string) (models.Shift, error) {
+	shifts, err := s.shifts.find(func(shift models.Shift) bool {
+		return shift.User_id == userId && shift.IsOpen()
+	})
+	if err != nil {
+		return models.Shift{}, err
+	}
+	if len(shifts) == 0 {
+		return models.Shift{}, ErrNotFound
+	}
+	return shifts[0], nil
+}
+
+func (s *memoryShiftStore) Create(ctx context.Context, shift models.Shift) error {
+	return s.shifts.insert(shift.Shift_id, shift)
+}
+
+func (s *memoryShiftStore) Update(ctx context.Context, shift models.Shift) error {
+	return s.shifts.replace(shift.Shift_id, shift)
+}
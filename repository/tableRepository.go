@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type TableStore interface {
+	List(ctx context.Context) ([]models.Table, error)
+	Get(ctx context.Context, tableId string) (models.Table, error)
+	Create(ctx context.Context, table models.Table) error
+	Update(ctx context.Context, table models.Table) error
+}
+
+type mongoTableStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoTableStore) List(ctx context.Context) ([]models.Table, error) {
+	res, err := s.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	tables := []models.Table{}
+	err = res.All(ctx, &tables)
+	return tables, err
+}
+
+func (s *mongoTableStore) Get(ctx context.Context, tableId string) (models.Table, error) {
+	var table models.Table
+	err := s.collection.FindOne(ctx, bson.M{"table_id": tableId}).Decode(&table)
+	return table, notFound(err)
+}
+
+func (s *mongoTableStore) Create(ctx context.Context, table models.Table) error {
+	_, err := s.collection.InsertOne(ctx, table)
+	return err
+}
+
+func (s *mongoTableStore) Update(ctx context.Context, table models.Table) error {
+	res, err := s.collection.ReplaceOne(ctx, bson.M{"table_id": table.Table_id}, table)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryTableStore struct {
+	tables *memCollection[models.Table]
+}
+
+func (s *memoryTableStore) List(ctx context.Context) ([]models.Table, error) {
+	return s.tables.find(nil)
+}
+
+func (s *memoryTableStore) Get(ctx context.Context, tableId string) (models.Table, error) {
+	return s.tables.get(tableId)
+}
+
+func (s *memoryTableStore) Create(ctx context.Context, table models.Table) error {
+	return s.tables.insert(table.Table_id, table)
+}
+
+func (s *memoryTableStore) Update(ctx context.Context, table models.Table) error {
+	return s.tables.replace(table.Table_id, table)
+}
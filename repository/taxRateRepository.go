@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type TaxRateStore interface {
+	List(ctx context.Context) ([]models.TaxRate, error)
+	Get(ctx context.Context, taxRateId string) (models.TaxRate, error)
+	Create(ctx context.Context, taxRate models.TaxRate) error
+	Update(ctx context.Context, taxRate models.TaxRate) error
+}
+
+type mongoTaxRateStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoTaxRateStore) List(ctx context.Context) ([]models.TaxRate, error) {
+	res, err := s.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	taxRates := []models.TaxRate{}
+	err = res.All(ctx, &taxRates)
+	return taxRates, err
+}
+
+func (s *mongoTaxRateStore) Get(ctx context.Context, taxRateId string) (models.TaxRate, error) {
+	var taxRate models.TaxRate
+	err := s.collection.FindOne(ctx, bson.M{"tax_rate_id": taxRateId}).Decode(&taxRate)
+	return taxRate, notFound(err)
+}
+
+func (s *mongoTaxRateStore) Create(ctx context.Context, taxRate models.TaxRate) error {
+	_, err := s.collection.InsertOne(ctx, taxRate)
+	return err
+}
+
+func (s *mongoTaxRateStore) Update(ctx context.Context, taxRate models.TaxRate) error {
+	res, err := s.collection.ReplaceOne(ctx, bson.M{"tax_rate_id": taxRate.Tax_rate_id}, taxRate)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryTaxRateStore struct {
+	taxRates *memCollection[models.TaxRate]
+}
+
+func (s *memoryTaxRateStore) List(ctx context.Context) ([]models.TaxRate, error) {
+	return s.taxRates.find(nil)
+}
+
+func (s *memoryTaxRateStore) Get(ctx context.Context, taxRateId string) (models.TaxRate, error) {
+	return s.taxRates.get(taxRateId)
+}
+
+func (s *memoryTaxRateStore) Create(ctx context.Context, taxRate models.TaxRate) error {
+	return s.taxRates.insert(taxRate.Tax_rate_id, taxRate)
+}
+
+func (s *memoryTaxRateStore) Update(ctx context.Context, taxRate models.TaxRate) error {
+	return s.taxRates.replace(taxRate.Tax_rate_id, taxRate)
+}
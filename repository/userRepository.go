@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/tokha04/go-restautant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type UserStore interface {
+	List(ctx context.Context, skip, limit int) ([]models.User, int, error)
+	Get(ctx context.Context, userId string) (models.User, error)
+	GetByEmail(ctx context.Context, email string) (models.User, error)
+	// ExistsByEmailOrPhone reports whether any user already owns the email or
+	// the phone number.
+	ExistsByEmailOrPhone(ctx context.Context, email, phone string) (bool, error)
+	CountByRole(ctx context.Context, role string) (int, error)
+	Create(ctx context.Context, user models.User) error
+	UpdateRole(ctx context.Context, userId, role string) error
+	// EnsureAdmin gives the user with admin's email the admin role, or
+	// inserts admin when nobody has that email. It reports whether admin was
+	// inserted.
+	EnsureAdmin(ctx context.Context, admin models.User) (bool, error)
+}
+
+type mongoUserStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoUserStore) List(ctx context.Context, skip, limit int) ([]models.User, int, error) {
+	total, err := s.collection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSkip(int64(skip)).SetLimit(int64(limit))
+	res, err := s.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	users := []models.User{}
+	if err = res.All(ctx, &users); err != nil {
+		return nil, 0, err
+	}
+
+	return users, int(total), nil
+}
+
+func (s *mongoUserStore) Get(ctx context.Context, userId string) (models.User, error) {
+	var user models.User
+	err := s.collection.FindOne(ctx, bson.M{"user_id": userId}).Decode(&user)
+	return user, notFound(err)
+}
+
+func (s *mongoUserStore) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	var user models.User
+	err := s.collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	return user, notFound(err)
+}
+
+func (s *mongoUserStore) ExistsByEmailOrPhone(ctx context.Context, email, phone string) (bool, error) {
+	count, err := s.collection.CountDocuments(ctx, bson.M{"$or": []bson.M{{"email": email}, {"phone": phone}}})
+	return count > 0, err
+}
+
+func (s *mongoUserStore) CountByRole(ctx context.Context, role string) (int, error) {
+	count, err := s.collection.CountDocuments(ctx, bson.M{"role": role})
+	return int(count), err
+}
+
+func (s *mongoUserStore) Create(ctx context.Context, user models.User) error {
+	_, err := s.collection.InsertOne(ctx, user)
+	return err
+}
+
+func (s *mongoUserStore) UpdateRole(ctx context.Context, userId, role string) error {
+	Updated_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	res, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{"user_id": userId},
+		bson.M{"$set": bson.M{"role": role, "updated_at": Updated_at}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoUserStore) EnsureAdmin(ctx context.Context, admin models.User) (bool, error) {
+	insert, err := bson.Marshal(admin)
+	if err != nil {
+		return false, err
+	}
+	var onInsert bson.M
+	if err := bson.Unmarshal(insert, &onInsert); err != nil {
+		return false, err
+	}
+	delete(onInsert, "role")
+	delete(onInsert, "updated_at")
+
+	res, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{"email": *admin.Email},
+		bson.M{
+			"$set":         bson.M{"role": models.RoleAdmin, "updated_at": admin.Updated_at},
+			"$setOnInsert": onInsert,
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.UpsertedCount > 0, nil
+}
+
+type memoryUserStore struct {
+	users *memCollection[models.User]
+}
+
+func (s *memoryUserStore) List(ctx context.Context, skip, limit int) ([]models.User, int, error) {
+	users, err := s.users.find(nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	return paginate(users, skip, limit), len(users), nil
+}
+
+func (s *memoryUserStore) Get(ctx context.Context, userId string) (models.User, error) {
+	return s.users.get(userId)
+}
+
+func (s *memoryUserStore) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	users, err := s.users.find(func(user models.User) bool {
+		return user.Email != nil && *user.Email == email
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+	if len(users) == 0 {
+		return models.User{}, ErrNotFound
+	}
+	return users[0], nil
+}
+
+func (s *memoryUserStore) ExistsByEmailOrPhone(ctx context.Context, email, phone string) (bool, error) {
+	users, err := s.users.find(func(user models.User) bool {
+		return (user.Email != nil && *user.Email == email) || (user.Phone != nil && *user.Phone == phone)
+	})
+	return len(users) > 0, err
+}
+
+func (s *memoryUserStore) CountByRole(ctx context.Context, role string) (int, error) {
+	users, err := s.users.find(func(user models.User) bool {
+		return user.Role != nil && *user.Role == role
+	})
+	return len(users), err
+}
+
+func (s *memoryUserStore) Create(ctx context.Context, user models.User) error {
+	return s.users.insert(user.User_id, user)
+}
+
+func (s *memoryUserStore) UpdateRole(ctx context.Context, userId, role string) error {
+	_, err := s.users.update(userId, func(user *models.User) error {
+		user.Role = &role
+		user.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		return nil
+	})
+	return err
+}
+
+func (s *memoryUserStore) EnsureAdmin(ctx context.Context, admin models.User) (bool, error) {
+	user, err := s.GetByEmail(ctx, *admin.Email)
+	if err == ErrNotFound {
+		return true, s.users.insert(admin.User_id, admin)
+	}
+	if err != nil {
+		return false, err
+	}
+	return false, s.UpdateRole(ctx, user.User_id, models.RoleAdmin)
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/controllers"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/models"
+)
+
+func BusinessDayRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/businessDays", middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.GetBusinessDays())
+	incomingRoutes.GET("/businessDays/:business_date", middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.GetBusinessDay())
+	incomingRoutes.POST("/businessDays/:business_date/close", middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.CloseBusinessDay())
+}
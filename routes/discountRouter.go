@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/controllers"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/models"
+)
+
+func DiscountRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/orders/:order_id/discounts", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier, models.RoleWaiter), controllers.GetOrderDiscounts())
+	incomingRoutes.POST("/orders/:order_id/discounts", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier, models.RoleWaiter), controllers.ApplyDiscount())
+	incomingRoutes.POST("/discounts/:discount_id/remove", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier, models.RoleWaiter), controllers.RemoveDiscount())
+	incomingRoutes.GET("/discounts", middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.GetDiscounts())
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/controllers"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/models"
+)
+
+func DrawerRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/drawers", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier), controllers.GetDrawers())
+	incomingRoutes.GET("/drawers/:drawer_id", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier), controllers.GetDrawer())
+	incomingRoutes.GET("/drawers/:drawer_id/report", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier), controllers.GetDrawerReport())
+	incomingRoutes.POST("/drawers/open", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier), controllers.OpenDrawer())
+	incomingRoutes.POST("/drawers/:drawer_id/movements", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier), controllers.AddCashMovement())
+	incomingRoutes.POST("/drawers/:drawer_id/close", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier), controllers.CloseDrawer())
+}
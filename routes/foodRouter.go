@@ -3,11 +3,13 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/tokha04/go-restautant-management/controllers"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/models"
 )
 
 func FoodRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/foods", controllers.GetFoods())
 	incomingRoutes.GET("/foods/:food_id", controllers.GetFood())
-	incomingRoutes.POST("/foods", controllers.CreateFood())
-	incomingRoutes.PATCH("/foods/:food_id", controllers.UpdateFood())
+	incomingRoutes.POST("/foods", middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.CreateFood())
+	incomingRoutes.PATCH("/foods/:food_id", middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.UpdateFood())
 }
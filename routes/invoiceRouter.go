@@ -3,11 +3,16 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/tokha04/go-restautant-management/controllers"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/models"
 )
 
 func InvoiceRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.GET("/invoices", controllers.GetInvoices())
-	incomingRoutes.GET("/invoices/:invoice_id", controllers.GetInvoice())
-	incomingRoutes.POST("/invoices", controllers.CreateInvoice())
-	incomingRoutes.PATCH("/invoices/:invooice_id", controllers.UpdateInvoice())
+	incomingRoutes.GET("/invoices", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier, models.RoleWaiter), controllers.GetInvoices())
+	incomingRoutes.GET("/invoices/:invoice_id", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier, models.RoleWaiter), controllers.GetInvoice())
+	incomingRoutes.GET("/invoices/:invoice_id/receipt", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier, models.RoleWaiter), controllers.GetInvoiceReceipt())
+	incomingRoutes.POST("/invoices", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier, models.RoleWaiter), controllers.CreateInvoice())
+	incomingRoutes.POST("/invoices/split", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier, models.RoleWaiter), controllers.SplitInvoice())
+	incomingRoutes.POST("/invoices/:invoice_id/void", middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.VoidInvoice())
+	incomingRoutes.PATCH("/invoices/:invoice_id", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier), controllers.UpdateInvoice())
 }
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/controllers"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/models"
+)
+
+func KitchenRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/kitchen/items", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleChef), controllers.GetKitchenItems())
+	incomingRoutes.GET("/kitchen/stream", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleChef), controllers.KitchenStream())
+	incomingRoutes.POST("/kitchen/items/:order_item_id/bump", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleChef), controllers.BumpOrderItem())
+}
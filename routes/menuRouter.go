@@ -3,11 +3,14 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/tokha04/go-restautant-management/controllers"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/models"
 )
 
 func MenuRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/menus", controllers.GetMenus())
+	incomingRoutes.GET("/menus/current", controllers.GetCurrentMenus())
 	incomingRoutes.GET("/menus/:menu_id", controllers.GetMenu())
-	incomingRoutes.POST("/menus", controllers.CreateMenu())
-	incomingRoutes.PATCH("/menus/:menu_id", controllers.UpdateMenu())
+	incomingRoutes.POST("/menus", middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.CreateMenu())
+	incomingRoutes.PATCH("/menus/:menu_id", middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.UpdateMenu())
 }
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/controllers"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/models"
+)
+
+func NotificationRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/notifications/stream", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier), controllers.NotificationStream())
+}
@@ -3,12 +3,15 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/tokha04/go-restautant-management/controllers"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/models"
 )
 
 func OrderItemRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/orderItems", controllers.GetOrderItems())
-	incomingRoutes.GET("/orderItems/:orderItem_id", controllers.GetOrderItem())
+	incomingRoutes.GET("/orderItems/:order_item_id", controllers.GetOrderItem())
 	incomingRoutes.GET("/orderItems-order/:order_id", controllers.GetOrderItemsByOrder())
-	incomingRoutes.POST("/orderItems", controllers.CreateOrderItem())
-	incomingRoutes.PATCH("/orderItems/:orderItem_id", controllers.UpdateOrderItem())
+	incomingRoutes.POST("/orderItems", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleWaiter), controllers.CreateOrderItem())
+	incomingRoutes.PATCH("/orderItems/:order_item_id", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleWaiter), controllers.UpdateOrderItem())
+	incomingRoutes.POST("/orderItems/:order_item_id/cancel", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleWaiter), controllers.CancelOrderItem())
 }
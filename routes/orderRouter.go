@@ -3,11 +3,20 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/tokha04/go-restautant-management/controllers"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/models"
 )
 
 func OrderRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/orders", controllers.GetOrders())
 	incomingRoutes.GET("/orders/:order_id", controllers.GetOrder())
-	incomingRoutes.POST("/orders", controllers.CreateOrder())
-	incomingRoutes.PATCH("/orders/:order_id", controllers.UpdateOrder())
+	incomingRoutes.POST("/orders", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleWaiter), controllers.CreateOrder())
+	incomingRoutes.PATCH("/orders/:order_id", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleWaiter), controllers.UpdateOrder())
+
+	incomingRoutes.POST("/orders/:order_id/send-to-kitchen", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleWaiter), controllers.TransitionOrder(models.OrderSentToKitchen))
+	incomingRoutes.POST("/orders/:order_id/ready", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleChef), controllers.TransitionOrder(models.OrderReady))
+	incomingRoutes.POST("/orders/:order_id/serve", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleWaiter), controllers.TransitionOrder(models.OrderServed))
+	incomingRoutes.POST("/orders/:order_id/pay", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier), controllers.TransitionOrder(models.OrderPaid))
+	incomingRoutes.POST("/orders/:order_id/close", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier), controllers.TransitionOrder(models.OrderClosed))
+	incomingRoutes.POST("/orders/:order_id/cancel", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleWaiter), controllers.TransitionOrder(models.OrderCancelled))
 }
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/controllers"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/models"
+)
+
+// PaymentWebhookRoutes must be registered before the Authentication
+// middleware; webhooks are authenticated by their signature.
+func PaymentWebhookRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.POST("/payments/webhook", controllers.PaymentWebhook())
+}
+
+func PaymentRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/invoices/:invoice_id/payments", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier, models.RoleWaiter), controllers.GetInvoicePayments())
+	incomingRoutes.POST("/invoices/:invoice_id/payments", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier, models.RoleWaiter), controllers.CreatePayment())
+	incomingRoutes.GET("/invoices/:invoice_id/creditNotes", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier), controllers.GetInvoiceCreditNotes())
+	incomingRoutes.GET("/creditNotes/:credit_note_id", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier), controllers.GetCreditNote())
+	incomingRoutes.POST("/payments/:payment_id/refund", middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.RefundPayment())
+	incomingRoutes.POST("/payments/:payment_id/void", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleCashier), controllers.VoidPayment())
+}
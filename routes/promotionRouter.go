@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/controllers"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/models"
+)
+
+func PromotionRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/promotions", middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.GetPromotions())
+	incomingRoutes.GET("/promotions/:promotion_id", middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.GetPromotion())
+	incomingRoutes.POST("/promotions", middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.CreatePromotion())
+	incomingRoutes.PATCH("/promotions/:promotion_id", middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.UpdatePromotion())
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/controllers"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/models"
+)
+
+func ReportRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/reports/tips", controllers.GetTipReport())
+	incomingRoutes.GET("/reports/sales", middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.GetSalesReport())
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/controllers"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/models"
+)
+
+func ReservationRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/reservations", controllers.GetReservations())
+	incomingRoutes.GET("/reservations/availability", controllers.GetAvailability())
+	incomingRoutes.GET("/reservations/:reservation_id", controllers.GetReservation())
+	incomingRoutes.POST("/reservations", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleWaiter), controllers.CreateReservation())
+	incomingRoutes.PATCH("/reservations/:reservation_id", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleWaiter), controllers.UpdateReservation())
+	incomingRoutes.DELETE("/reservations/:reservation_id", middleware.Authorize(models.RoleAdmin, models.RoleManager, models.RoleWaiter), controllers.CancelReservation())
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/controllers"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/models"
+)
+
+func ServiceChargeRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/serviceCharges", controllers.GetServiceCharges())
+	incomingRoutes.GET("/serviceCharges/:service_charge_id", controllers.GetServiceCharge())
+	incomingRoutes.POST("/serviceCharges", middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.CreateServiceCharge())
+	incomingRoutes.PATCH("/serviceCharges/:service_charge_id", middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.UpdateServiceCharge())
+}
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/controllers"
+)
+
+func ShiftRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/shifts", controllers.GetShifts())
+	incomingRoutes.POST("/shifts/start", controllers.StartShift())
+	incomingRoutes.POST("/shifts/:shift_id/end", controllers.EndShift())
+}
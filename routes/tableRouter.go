@@ -3,11 +3,13 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/tokha04/go-restautant-management/controllers"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/models"
 )
 
 func TableRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/tables", controllers.GetTables())
 	incomingRoutes.GET("/tables/:table_id", controllers.GetTable())
-	incomingRoutes.POST("/tables", controllers.CreateTable())
-	incomingRoutes.PATCH("/tables/:table_id", controllers.UpdateTable())
+	incomingRoutes.POST("/tables", middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.CreateTable())
+	incomingRoutes.PATCH("/tables/:table_id", middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.UpdateTable())
 }
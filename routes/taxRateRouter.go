@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tokha04/go-restautant-management/controllers"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/models"
+)
+
+func TaxRateRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/taxRates", controllers.GetTaxRates())
+	incomingRoutes.GET("/taxRates/:tax_rate_id", controllers.GetTaxRate())
+	incomingRoutes.POST("/taxRates", middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.CreateTaxRate())
+	incomingRoutes.PATCH("/taxRates/:tax_rate_id", middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.UpdateTaxRate())
+}
@@ -3,11 +3,20 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/tokha04/go-restautant-management/controllers"
+	"github.com/tokha04/go-restautant-management/middleware"
+	"github.com/tokha04/go-restautant-management/models"
 )
 
 func UserRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.GET("/users", controllers.GetUsers())
-	incomingRoutes.GET("/users/:user_id", controllers.GetUser())
 	incomingRoutes.POST("/users/signup", controllers.SignUp())
 	incomingRoutes.GET("/users/login", controllers.Login())
+	incomingRoutes.POST("/users/refresh", controllers.RefreshToken())
+
+	incomingRoutes.POST("/users/logout", middleware.Authentication(), controllers.Logout())
+	incomingRoutes.POST("/users/logout-all", middleware.Authentication(), controllers.LogoutAll())
+
+	incomingRoutes.GET("/users", middleware.Authentication(), middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.GetUsers())
+	incomingRoutes.GET("/users/:user_id", middleware.Authentication(), middleware.Authorize(models.RoleAdmin, models.RoleManager), controllers.GetUser())
+	incomingRoutes.PATCH("/users/:user_id/role", middleware.Authentication(), middleware.Authorize(models.RoleAdmin), controllers.UpdateUserRole())
+	incomingRoutes.DELETE("/users/:user_id/sessions", middleware.Authentication(), middleware.Authorize(models.RoleAdmin), controllers.KillUserSessions())
 }
@@ -0,0 +1,91 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+// 2024-06-03 is a Monday.
+func at(value string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04:05", value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseNext(t *testing.T) {
+	tests := []struct {
+		spec string
+		from string
+		want string
+	}{
+		{"*/5 * * * *", "2024-06-03 10:02:00", "2024-06-03 10:05:00"},
+		{"*/5 * * * *", "2024-06-03 10:05:30", "2024-06-03 10:10:00"},
+		{"5/15 * * * *", "2024-06-03 10:36:00", "2024-06-03 10:50:00"},
+		{"15,45 8-10 * * *", "2024-06-03 10:50:00", "2024-06-04 08:15:00"},
+		{"0 9 * * 1-5", "2024-06-07 10:00:00", "2024-06-10 09:00:00"},
+		{"30 2 1 * *", "2024-06-15 00:00:00", "2024-07-01 02:30:00"},
+		{"0 0 1 1 *", "2024-06-15 00:00:00", "2025-01-01 00:00:00"},
+		{"0 12 29 2 *", "2024-03-01 00:00:00", "2028-02-29 12:00:00"},
+		// 7 is Sunday as well as 0
+		{"0 0 * * 7", "2024-06-03 00:00:00", "2024-06-09 00:00:00"},
+		{"0 0 * * 0", "2024-06-03 00:00:00", "2024-06-09 00:00:00"},
+		// with both day fields restricted, either one matching is enough
+		{"0 0 13 * 5", "2024-06-03 00:00:00", "2024-06-07 00:00:00"},
+		{"0 0 13 * 5", "2024-06-07 00:00:00", "2024-06-13 00:00:00"},
+		{"@hourly", "2024-06-03 10:00:00", "2024-06-03 11:00:00"},
+		{"@daily", "2024-06-03 10:00:00", "2024-06-04 00:00:00"},
+		{"@weekly", "2024-06-03 10:00:00", "2024-06-09 00:00:00"},
+		{"@monthly", "2024-06-03 10:00:00", "2024-07-01 00:00:00"},
+		{"@every 90s", "2024-06-03 10:00:10", "2024-06-03 10:01:40"},
+		{" @every 1m ", "2024-06-03 10:00:10", "2024-06-03 10:01:10"},
+	}
+
+	for _, test := range tests {
+		schedule, err := Parse(test.spec)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.spec, err)
+			continue
+		}
+
+		if got := schedule.Next(at(test.from)); !got.Equal(at(test.want)) {
+			t.Errorf("%q after %s runs at %s, want %s", test.spec, test.from, got.Format(time.DateTime), test.want)
+		}
+	}
+}
+
+func TestNextNeverMatches(t *testing.T) {
+	schedule, err := Parse("0 0 31 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := schedule.Next(at("2024-06-03 00:00:00")); !got.IsZero() {
+		t.Errorf("February 31st runs at %s, want never", got)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-b * * * *",
+		"@yearly",
+		"@every",
+		"@every x",
+		"@every -1m",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", spec)
+		}
+	}
+}
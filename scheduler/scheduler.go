@@ -0,0 +1,109 @@
+// Package scheduler runs background jobs inside the API process on cron-like
+// schedules.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job is the work a scheduled entry does. It should return once ctx is done.
+type Job func(ctx context.Context) error
+
+type entry struct {
+	name     string
+	schedule Schedule
+	job      Job
+}
+
+// Scheduler runs every job on its schedule. A job never overlaps itself: a
+// run that takes longer than the interval delays the next one.
+type Scheduler struct {
+	mu      sync.Mutex
+	entries []entry
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func New() *Scheduler {
+	return &Scheduler{}
+}
+
+// Add registers a job under name on the schedule spec, as understood by
+// Parse. Jobs added after Start do not run.
+func (s *Scheduler) Add(name, spec string, job Job) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entry{name: name, schedule: schedule, job: job})
+
+	return nil
+}
+
+// Start runs the jobs in the background until ctx is done or Stop is called.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	for _, e := range s.entries {
+		s.wg.Add(1)
+		go s.run(ctx, e)
+	}
+}
+
+// Stop cancels the running jobs and waits for them to return.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	s.wg.Wait()
+}
+
+func (s *Scheduler) run(ctx context.Context, e entry) {
+	defer s.wg.Done()
+
+	for {
+		next := e.schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("scheduler: job %s has no next run, stopping it", e.name)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.runOnce(ctx, e)
+	}
+}
+
+// runOnce runs the job, logging its failure; a panicking job must not take
+// the API down with it.
+func (s *Scheduler) runOnce(ctx context.Context, e entry) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("scheduler: job %s panicked: %v", e.name, r)
+		}
+	}()
+
+	started := time.Now()
+	if err := e.job(ctx); err != nil {
+		log.Printf("scheduler: job %s failed after %s: %v", e.name, time.Since(started).Round(time.Millisecond), err)
+	}
+}